@@ -0,0 +1,203 @@
+package alert
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval  = 5 * time.Minute
+	DefaultCooldown  = time.Hour
+	DefaultMaxAlerts = 50
+
+	maxMessageLength = 1000
+	sinkTimeout      = 30 * time.Second
+)
+
+// Alert is one distinct error, with how often and when it was reported.
+type Alert struct {
+	Message   string    `json:"message"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Digest is the batch of alerts handed to every sink at the end of an interval.
+type Digest struct {
+	From   time.Time `json:"from"`
+	Until  time.Time `json:"until"`
+	Alerts []Alert   `json:"alerts"`
+	// Dropped counts reports of further distinct errors that did not fit in the digest.
+	Dropped int `json:"dropped"`
+}
+
+// Sink delivers digests over one channel, such as email or a webhook.
+type Sink interface {
+	Send(ctx context.Context, digest Digest) error
+}
+
+// Alerter collects reported errors and sends them to its sinks as a digest every Interval.
+//
+// Identical errors are deduplicated: repeats are counted instead of reported again, and an error
+// that was in a digest is held back, still counting, until Cooldown has passed. At most MaxAlerts
+// distinct errors are kept per digest. Sink failures and panics are logged and never stop the
+// process; Report never blocks on delivery.
+type Alerter struct {
+	Sinks     []Sink
+	Interval  time.Duration
+	Cooldown  time.Duration
+	MaxAlerts int
+
+	mu       sync.Mutex
+	pending  map[string]*Alert
+	lastSent map[string]time.Time
+	dropped  int
+
+	// now is the clock, time.Now unless a test fixes it.
+	now func() time.Time
+}
+
+// Report records err for the next digest. It is safe to call on a nil Alerter.
+func (a *Alerter) Report(err error) {
+	if a == nil || err == nil {
+		return
+	}
+
+	message := err.Error()
+	if len(message) > maxMessageLength {
+		message = message[:maxMessageLength] + "..."
+	}
+	now := a.clock()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.pending == nil {
+		a.pending = map[string]*Alert{}
+	}
+	if pending, ok := a.pending[message]; ok {
+		pending.Count++
+		pending.LastSeen = now
+		return
+	}
+	if len(a.pending) >= a.maxAlerts() {
+		a.dropped++
+		return
+	}
+	a.pending[message] = &Alert{Message: message, Count: 1, FirstSeen: now, LastSeen: now}
+}
+
+// Start sends a digest every Interval until ctx is cancelled, and a last one after that.
+func (a *Alerter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(a.interval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				a.Flush(context.Background())
+				return
+			case <-ticker.C:
+				a.Flush(ctx)
+			}
+		}
+	}()
+}
+
+// Flush sends the errors reported so far, minus those still in their cooldown, to every sink.
+func (a *Alerter) Flush(ctx context.Context) {
+	digest, ok := a.takeDigest(a.clock())
+	if !ok {
+		return
+	}
+
+	for _, sink := range a.Sinks {
+		a.send(ctx, sink, digest)
+	}
+}
+
+func (a *Alerter) takeDigest(now time.Time) (Digest, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.lastSent == nil {
+		a.lastSent = map[string]time.Time{}
+	}
+	for message, sentAt := range a.lastSent {
+		if now.Sub(sentAt) >= a.cooldown() {
+			delete(a.lastSent, message)
+		}
+	}
+
+	digest := Digest{Until: now, Dropped: a.dropped}
+	for message, pending := range a.pending {
+		if _, cooling := a.lastSent[message]; cooling {
+			continue
+		}
+		digest.Alerts = append(digest.Alerts, *pending)
+		a.lastSent[message] = now
+		delete(a.pending, message)
+	}
+	a.dropped = 0
+
+	if len(digest.Alerts) == 0 && digest.Dropped == 0 {
+		return digest, false
+	}
+
+	sort.Slice(digest.Alerts, func(i, j int) bool {
+		return digest.Alerts[i].FirstSeen.Before(digest.Alerts[j].FirstSeen)
+	})
+	digest.From = now
+	if len(digest.Alerts) > 0 {
+		digest.From = digest.Alerts[0].FirstSeen
+	}
+
+	return digest, true
+}
+
+func (a *Alerter) send(ctx context.Context, sink Sink, digest Digest) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("alert: %T panicked: %v\n", sink, recovered)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, sinkTimeout)
+	defer cancel()
+
+	if err := sink.Send(ctx, digest); err != nil {
+		log.Printf("alert: %T failed: %v\n", sink, err)
+	}
+}
+
+func (a *Alerter) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+func (a *Alerter) interval() time.Duration {
+	if a.Interval > 0 {
+		return a.Interval
+	}
+	return DefaultInterval
+}
+
+func (a *Alerter) cooldown() time.Duration {
+	if a.Cooldown > 0 {
+		return a.Cooldown
+	}
+	return DefaultCooldown
+}
+
+func (a *Alerter) maxAlerts() int {
+	if a.MaxAlerts > 0 {
+		return a.MaxAlerts
+	}
+	return DefaultMaxAlerts
+}
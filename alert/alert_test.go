@@ -0,0 +1,169 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// step reports the errors in report at start+at, then takes a digest there when flush is set.
+type step struct {
+	at     time.Duration
+	report []string
+	flush  bool
+	// want maps each message expected in the digest to its count; nil expects no digest.
+	want        map[string]int
+	wantDropped int
+}
+
+func TestAlerter(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		maxAlerts int
+		steps     []step
+	}{
+		{name: "nothing reported", steps: []step{
+			{at: DefaultInterval, flush: true},
+		}},
+		{name: "repeats are counted", steps: []step{
+			{at: 0, report: []string{"a", "b"}},
+			{at: time.Second, report: []string{"a", "a"}},
+			{at: DefaultInterval, flush: true, want: map[string]int{"a": 3, "b": 1}},
+			{at: 2 * DefaultInterval, flush: true},
+		}},
+		{name: "sent errors wait out the cooldown", steps: []step{
+			{at: 0, report: []string{"a"}},
+			{at: DefaultInterval, flush: true, want: map[string]int{"a": 1}},
+			{at: DefaultInterval + time.Minute, report: []string{"a", "b"}},
+			{at: 2 * DefaultInterval, flush: true, want: map[string]int{"b": 1}},
+			{at: 3 * DefaultInterval, report: []string{"a"}},
+			{at: DefaultInterval + DefaultCooldown - time.Second, flush: true},
+			{at: DefaultInterval + DefaultCooldown, flush: true, want: map[string]int{"a": 2}},
+		}},
+		{name: "errors past MaxAlerts are dropped", maxAlerts: 2, steps: []step{
+			{at: 0, report: []string{"a", "b", "c", "d", "a"}},
+			{at: DefaultInterval, flush: true, want: map[string]int{"a": 2, "b": 1}, wantDropped: 2},
+			{at: DefaultInterval, report: []string{"c"}},
+			{at: 2 * DefaultInterval, flush: true, want: map[string]int{"c": 1}},
+		}},
+		{name: "only dropped errors", maxAlerts: 1, steps: []step{
+			{at: 0, report: []string{"a"}},
+			{at: DefaultInterval, flush: true, want: map[string]int{"a": 1}},
+			{at: DefaultInterval, report: []string{"a", "b"}},
+			{at: 2 * DefaultInterval, flush: true, want: map[string]int{}, wantDropped: 1},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var now time.Time
+			alerter := &Alerter{MaxAlerts: tc.maxAlerts, now: func() time.Time { return now }}
+
+			for i, s := range tc.steps {
+				now = start.Add(s.at)
+				for _, message := range s.report {
+					alerter.Report(errors.New(message))
+				}
+				if !s.flush {
+					continue
+				}
+
+				digest, ok := alerter.takeDigest(now)
+				if s.want == nil {
+					if ok {
+						t.Fatalf("step %d: unexpected digest %+v", i, digest)
+					}
+					continue
+				}
+				if !ok {
+					t.Fatalf("step %d: no digest, want %v", i, s.want)
+				}
+				got := map[string]int{}
+				for _, alert := range digest.Alerts {
+					got[alert.Message] = alert.Count
+				}
+				if !reflect.DeepEqual(got, s.want) || digest.Dropped != s.wantDropped {
+					t.Fatalf("step %d: digest has %v and %d dropped, want %v and %d", i, got, digest.Dropped, s.want, s.wantDropped)
+				}
+				if !digest.Until.Equal(now) {
+					t.Fatalf("step %d: digest until %v, want %v", i, digest.Until, now)
+				}
+			}
+		})
+	}
+}
+
+func TestAlerterDigestTimes(t *testing.T) {
+	var now time.Time
+	alerter := &Alerter{now: func() time.Time { return now }}
+
+	now = start.Add(time.Minute)
+	alerter.Report(errors.New("b"))
+	now = start
+	alerter.Report(errors.New("a"))
+	now = start.Add(2 * time.Minute)
+	alerter.Report(errors.New("a"))
+
+	digest, _ := alerter.takeDigest(start.Add(DefaultInterval))
+	if !digest.From.Equal(start) {
+		t.Fatalf("digest from %v, want the first report at %v", digest.From, start)
+	}
+	want := []Alert{
+		{Message: "a", Count: 2, FirstSeen: start, LastSeen: start.Add(2 * time.Minute)},
+		{Message: "b", Count: 1, FirstSeen: start.Add(time.Minute), LastSeen: start.Add(time.Minute)},
+	}
+	if !reflect.DeepEqual(digest.Alerts, want) {
+		t.Fatalf("alerts = %+v, want %+v", digest.Alerts, want)
+	}
+}
+
+func TestReportTruncatesLongMessages(t *testing.T) {
+	alerter := &Alerter{}
+	alerter.Report(errors.New(strings.Repeat("x", 2*maxMessageLength)))
+
+	digest, _ := alerter.takeDigest(time.Now())
+	if len(digest.Alerts) != 1 || len(digest.Alerts[0].Message) != maxMessageLength+len("...") {
+		t.Fatalf("alerts = %d, want one truncated to %d bytes", len(digest.Alerts), maxMessageLength)
+	}
+}
+
+func TestReportOnNilAlerter(t *testing.T) {
+	var alerter *Alerter
+	alerter.Report(errors.New("ignored"))
+}
+
+type panicSink struct{}
+
+func (panicSink) Send(context.Context, Digest) error { panic("sink broke") }
+
+type failingSink struct{}
+
+func (failingSink) Send(context.Context, Digest) error { return errors.New("sink failed") }
+
+type recordingSink struct{ digests []Digest }
+
+func (s *recordingSink) Send(_ context.Context, digest Digest) error {
+	s.digests = append(s.digests, digest)
+	return nil
+}
+
+func TestFlushSurvivesBrokenSinks(t *testing.T) {
+	recorder := &recordingSink{}
+	alerter := &Alerter{Sinks: []Sink{panicSink{}, failingSink{}, recorder}}
+
+	alerter.Report(errors.New("a"))
+	alerter.Flush(context.Background())
+
+	if len(recorder.digests) != 1 || len(recorder.digests[0].Alerts) != 1 {
+		t.Fatalf("the sink after the broken ones got %+v", recorder.digests)
+	}
+
+	//* Nothing new was reported, so no further digest goes out
+	alerter.Flush(context.Background())
+	if len(recorder.digests) != 1 {
+		t.Fatalf("an empty digest was sent: %+v", recorder.digests[1:])
+	}
+}
@@ -0,0 +1,94 @@
+package alert
+
+import (
+	"Gin/Basics/mailer"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// LogSink writes digests to Logger, or to the standard logger when it is nil.
+type LogSink struct {
+	Logger *log.Logger
+}
+
+func (s *LogSink) Send(ctx context.Context, digest Digest) error {
+	logf := log.Printf
+	if s.Logger != nil {
+		logf = s.Logger.Printf
+	}
+
+	for _, entry := range digest.Alerts {
+		logf("ALERT (%d times since %s): %s\n", entry.Count, entry.FirstSeen.Format(time.RFC3339), entry.Message)
+	}
+	if digest.Dropped > 0 {
+		logf("ALERT: %d more errors were dropped\n", digest.Dropped)
+	}
+
+	return nil
+}
+
+// WebhookSink POSTs each digest as JSON to URL, with Token sent as a bearer token when set.
+// Any 2xx response counts as delivered.
+type WebhookSink struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func (s *WebhookSink) Send(ctx context.Context, digest Digest) error {
+	payload, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("alert webhook responded %d: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+// EmailSink emails each digest to To, rendered from the "admin_alert_digest" template. It sends
+// directly rather than through the outbox, since the errors it reports may well be database errors.
+type EmailSink struct {
+	Mailer    mailer.Mailer
+	Templates *mailer.Templates
+	To        []string
+}
+
+func (s *EmailSink) Send(ctx context.Context, digest Digest) error {
+	msg, err := s.Templates.Render("admin_alert_digest", mailer.DefaultLocale, digest)
+	if err != nil {
+		return err
+	}
+	msg.To = s.To
+
+	return s.Mailer.Send(msg)
+}
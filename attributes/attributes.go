@@ -0,0 +1,242 @@
+// Package attributes validates the custom data products attach to their users, such as a company
+// name or marketing consent, against a schema defined by the operator.
+package attributes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+// Attribute types.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+// reservedClaims are set by the service itself and cannot be filled from attributes.
+var reservedClaims = []string{"amr", "aud", "auth_time", "authorized", "exp", "iat", "iss", "jti", "nbf", "sub", "typ"}
+
+// Field describes one attribute. Length, pattern and enum apply to strings, Min and Max to numbers.
+type Field struct {
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// Editable lets users change the attribute through /me after registration.
+	Editable bool `json:"editable"`
+	// Claim copies the attribute into access tokens under this name.
+	Claim     string   `json:"claim"`
+	MinLength *int     `json:"min_length"`
+	MaxLength *int     `json:"max_length"`
+	Pattern   string   `json:"pattern"`
+	Enum      []string `json:"enum"`
+	Min       *float64 `json:"min"`
+	Max       *float64 `json:"max"`
+
+	pattern *regexp.Regexp
+}
+
+// Schema maps attribute names to their fields. The zero value accepts no attributes.
+type Schema struct {
+	Fields map[string]*Field
+}
+
+// FieldError explains why an attribute was rejected.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+// Load reads a schema from the JSON file at path: an object from attribute names to fields, e.g.
+//
+//	{"company": {"type": "string", "max_length": 100, "editable": true, "claim": "org"}}
+//
+// An empty path gives an empty schema.
+func Load(path string) (*Schema, error) {
+	if path == "" {
+		return &Schema{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse reads a schema in the format described at Load.
+func Parse(data []byte) (*Schema, error) {
+	schema := &Schema{}
+	if err := json.Unmarshal(data, &schema.Fields); err != nil {
+		return nil, fmt.Errorf("attributes: %w", err)
+	}
+
+	claims := map[string]string{}
+	for name, field := range schema.Fields {
+		if field == nil {
+			return nil, fmt.Errorf("attributes: %s: missing definition", name)
+		}
+		switch field.Type {
+		case TypeString, TypeNumber, TypeInteger, TypeBoolean:
+		default:
+			return nil, fmt.Errorf("attributes: %s: unknown type %q", name, field.Type)
+		}
+		if field.Pattern != "" {
+			pattern, err := regexp.Compile(field.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("attributes: %s: %w", name, err)
+			}
+			field.pattern = pattern
+		}
+		if field.Claim != "" {
+			if slices.Contains(reservedClaims, field.Claim) {
+				return nil, fmt.Errorf("attributes: %s: claim %q is reserved", name, field.Claim)
+			}
+			if other, taken := claims[field.Claim]; taken {
+				return nil, fmt.Errorf("attributes: %s: claim %q is already used by %s", name, field.Claim, other)
+			}
+			claims[field.Claim] = name
+		}
+	}
+
+	return schema, nil
+}
+
+// Validate checks the attributes given at registration: every name must be in the schema, every
+// required attribute present and every value valid. Null values are left out of the result.
+func (s *Schema) Validate(input map[string]interface{}) (map[string]interface{}, error) {
+	attrs := map[string]interface{}{}
+	for _, name := range sortedKeys(input) {
+		field, ok := s.Fields[name]
+		if !ok {
+			return nil, &FieldError{Field: name, Reason: "unknown attribute"}
+		}
+		if input[name] == nil {
+			continue
+		}
+		if err := field.check(name, input[name]); err != nil {
+			return nil, err
+		}
+		attrs[name] = input[name]
+	}
+
+	for _, name := range sortedKeys(s.Fields) {
+		if _, ok := attrs[name]; !ok && s.Fields[name].Required {
+			return nil, &FieldError{Field: name, Reason: "required"}
+		}
+	}
+
+	return attrs, nil
+}
+
+// Update applies changes made by the user to current. Only editable attributes may change, and a
+// null value removes an attribute unless it is required. Attributes that are not changed are kept
+// as they are, even if the schema has become stricter since they were stored.
+func (s *Schema) Update(current map[string]interface{}, changes map[string]interface{}) (map[string]interface{}, error) {
+	attrs := make(map[string]interface{}, len(current)+len(changes))
+	for name, value := range current {
+		attrs[name] = value
+	}
+
+	for _, name := range sortedKeys(changes) {
+		field, ok := s.Fields[name]
+		if !ok {
+			return nil, &FieldError{Field: name, Reason: "unknown attribute"}
+		}
+		if !field.Editable {
+			return nil, &FieldError{Field: name, Reason: "not editable"}
+		}
+		if changes[name] == nil {
+			if field.Required {
+				return nil, &FieldError{Field: name, Reason: "required"}
+			}
+			delete(attrs, name)
+			continue
+		}
+		if err := field.check(name, changes[name]); err != nil {
+			return nil, err
+		}
+		attrs[name] = changes[name]
+	}
+
+	return attrs, nil
+}
+
+// Claims returns the attributes that the schema copies into access tokens, keyed by claim name.
+func (s *Schema) Claims(attrs map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{}
+	for name, field := range s.Fields {
+		if value, ok := attrs[name]; ok && field.Claim != "" {
+			claims[field.Claim] = value
+		}
+	}
+
+	return claims
+}
+
+// check validates a non-null value of the attribute name. Values come from encoding/json, so
+// numbers are float64.
+func (f *Field) check(name string, value interface{}) error {
+	switch f.Type {
+	case TypeString:
+		str, ok := value.(string)
+		if !ok {
+			return &FieldError{Field: name, Reason: "must be a string"}
+		}
+		length := utf8.RuneCountInString(str)
+		if f.MinLength != nil && length < *f.MinLength {
+			return &FieldError{Field: name, Reason: fmt.Sprintf("must be at least %d characters", *f.MinLength)}
+		}
+		if f.MaxLength != nil && length > *f.MaxLength {
+			return &FieldError{Field: name, Reason: fmt.Sprintf("must be at most %d characters", *f.MaxLength)}
+		}
+		if f.pattern != nil && !f.pattern.MatchString(str) {
+			return &FieldError{Field: name, Reason: "has an invalid format"}
+		}
+		if len(f.Enum) > 0 && !slices.Contains(f.Enum, str) {
+			return &FieldError{Field: name, Reason: "is not one of the allowed values"}
+		}
+
+	case TypeNumber, TypeInteger:
+		number, ok := value.(float64)
+		if !ok {
+			return &FieldError{Field: name, Reason: "must be a number"}
+		}
+		if f.Type == TypeInteger && number != math.Trunc(number) {
+			return &FieldError{Field: name, Reason: "must be an integer"}
+		}
+		if f.Min != nil && number < *f.Min {
+			return &FieldError{Field: name, Reason: fmt.Sprintf("must be at least %g", *f.Min)}
+		}
+		if f.Max != nil && number > *f.Max {
+			return &FieldError{Field: name, Reason: fmt.Sprintf("must be at most %g", *f.Max)}
+		}
+
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return &FieldError{Field: name, Reason: "must be true or false"}
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
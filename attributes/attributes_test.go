@@ -0,0 +1,185 @@
+package attributes
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"company":   {"type": "string", "required": true, "editable": true, "min_length": 2, "max_length": 5, "claim": "org"},
+	"plan":      {"type": "string", "enum": ["free", "pro"]},
+	"code":      {"type": "string", "pattern": "^[A-Z]{3}$", "editable": true},
+	"score":     {"type": "number", "min": 0, "max": 1, "editable": true},
+	"seats":     {"type": "integer", "min": 1},
+	"marketing": {"type": "boolean", "editable": true}
+}`
+
+func mustParse(t *testing.T, data string) *Schema {
+	t.Helper()
+
+	schema, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return schema
+}
+
+// input decodes JSON as the controllers do, so that numbers are float64.
+func input(t *testing.T, data string) map[string]interface{} {
+	t.Helper()
+
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{name: "not an object", schema: `[]`, want: "cannot unmarshal"},
+		{name: "missing definition", schema: `{"company": null}`, want: "missing definition"},
+		{name: "unknown type", schema: `{"company": {"type": "date"}}`, want: `unknown type "date"`},
+		{name: "no type", schema: `{"company": {}}`, want: `unknown type ""`},
+		{name: "invalid pattern", schema: `{"code": {"type": "string", "pattern": "("}}`, want: "code:"},
+		{name: "reserved claim", schema: `{"company": {"type": "string", "claim": "sub"}}`, want: `claim "sub" is reserved`},
+		{name: "claim used twice", schema: `{"a": {"type": "string", "claim": "org"}, "b": {"type": "string", "claim": "org"}}`, want: `claim "org" is already used`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tc.schema)); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Parse error = %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseReservedClaims(t *testing.T) {
+	for _, claim := range reservedClaims {
+		if _, err := Parse([]byte(`{"a": {"type": "string", "claim": "` + claim + `"}}`)); err == nil {
+			t.Errorf("claim %q was accepted", claim)
+		}
+	}
+}
+
+func TestLoadEmptyPath(t *testing.T) {
+	schema, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := schema.Validate(map[string]interface{}{"company": "Acme"}); err == nil {
+		t.Fatal("an empty schema accepted an attribute")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := mustParse(t, testSchema)
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+		// field and reason are those of the expected FieldError.
+		field, reason string
+	}{
+		{name: "valid", input: `{"company": "Acme", "plan": "pro", "code": "ABC", "score": 0.5, "seats": 3, "marketing": true}`,
+			want: `{"company": "Acme", "plan": "pro", "code": "ABC", "score": 0.5, "seats": 3, "marketing": true}`},
+		{name: "nulls are left out", input: `{"company": "Acme", "plan": null}`, want: `{"company": "Acme"}`},
+		{name: "length counts characters", input: `{"company": "Ünïçø"}`, want: `{"company": "Ünïçø"}`},
+		{name: "unknown attribute", input: `{"company": "Acme", "color": "red"}`, field: "color", reason: "unknown attribute"},
+		{name: "required missing", input: `{"plan": "pro"}`, field: "company", reason: "required"},
+		{name: "required null", input: `{"company": null}`, field: "company", reason: "required"},
+		{name: "string type", input: `{"company": 42}`, field: "company", reason: "must be a string"},
+		{name: "too short", input: `{"company": "A"}`, field: "company", reason: "must be at least 2 characters"},
+		{name: "too long", input: `{"company": "Acme Inc"}`, field: "company", reason: "must be at most 5 characters"},
+		{name: "pattern", input: `{"company": "Acme", "code": "abc"}`, field: "code", reason: "has an invalid format"},
+		{name: "enum", input: `{"company": "Acme", "plan": "gold"}`, field: "plan", reason: "is not one of the allowed values"},
+		{name: "number type", input: `{"company": "Acme", "score": "high"}`, field: "score", reason: "must be a number"},
+		{name: "below min", input: `{"company": "Acme", "score": -0.1}`, field: "score", reason: "must be at least 0"},
+		{name: "above max", input: `{"company": "Acme", "score": 1.5}`, field: "score", reason: "must be at most 1"},
+		{name: "integer", input: `{"company": "Acme", "seats": 2.5}`, field: "seats", reason: "must be an integer"},
+		{name: "integer below min", input: `{"company": "Acme", "seats": 0}`, field: "seats", reason: "must be at least 1"},
+		{name: "boolean type", input: `{"company": "Acme", "marketing": "yes"}`, field: "marketing", reason: "must be true or false"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			attrs, err := schema.Validate(input(t, tc.input))
+			if tc.field != "" {
+				var fieldErr *FieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Field != tc.field || fieldErr.Reason != tc.reason {
+					t.Fatalf("Validate error = %v, want %s: %s", err, tc.field, tc.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if want := input(t, tc.want); !reflect.DeepEqual(attrs, want) {
+				t.Fatalf("Validate = %v, want %v", attrs, want)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	schema := mustParse(t, testSchema)
+	//* Stored before the schema limited plan and seats; untouched values are kept as they are
+	current := `{"company": "Acme", "plan": "legacy", "seats": 0, "score": 0.5}`
+
+	for _, tc := range []struct {
+		name          string
+		changes       string
+		want          string
+		field, reason string
+	}{
+		{name: "no changes", changes: `{}`, want: current},
+		{name: "editable attributes", changes: `{"company": "Beta", "marketing": false}`,
+			want: `{"company": "Beta", "plan": "legacy", "seats": 0, "score": 0.5, "marketing": false}`},
+		{name: "null removes", changes: `{"score": null}`, want: `{"company": "Acme", "plan": "legacy", "seats": 0}`},
+		{name: "not editable", changes: `{"plan": "pro"}`, field: "plan", reason: "not editable"},
+		{name: "not editable null", changes: `{"seats": null}`, field: "seats", reason: "not editable"},
+		{name: "required cannot be removed", changes: `{"company": null}`, field: "company", reason: "required"},
+		{name: "unknown attribute", changes: `{"color": "red"}`, field: "color", reason: "unknown attribute"},
+		{name: "changes are checked", changes: `{"code": "abc"}`, field: "code", reason: "has an invalid format"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stored := input(t, current)
+			attrs, err := schema.Update(stored, input(t, tc.changes))
+			if tc.field != "" {
+				var fieldErr *FieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Field != tc.field || fieldErr.Reason != tc.reason {
+					t.Fatalf("Update error = %v, want %s: %s", err, tc.field, tc.reason)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			if want := input(t, tc.want); !reflect.DeepEqual(attrs, want) {
+				t.Fatalf("Update = %v, want %v", attrs, want)
+			}
+			if !reflect.DeepEqual(stored, input(t, current)) {
+				t.Fatalf("Update changed the stored attributes to %v", stored)
+			}
+		})
+	}
+}
+
+func TestClaims(t *testing.T) {
+	schema := mustParse(t, testSchema)
+
+	claims := schema.Claims(input(t, `{"company": "Acme", "plan": "pro"}`))
+	if !reflect.DeepEqual(claims, map[string]interface{}{"org": "Acme"}) {
+		t.Fatalf("Claims = %v", claims)
+	}
+	if claims := schema.Claims(map[string]interface{}{}); len(claims) != 0 {
+		t.Fatalf("Claims without attributes = %v", claims)
+	}
+}
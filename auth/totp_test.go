@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of RFC 6238 Appendix B, "12345678901234567890", base32 encoded.
+var rfc6238Secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	//* Appendix B lists 8-digit codes; the 6-digit code is their last six digits
+	for _, tc := range []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	} {
+		code, err := TOTPCode(rfc6238Secret, tc.unix/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tc.want {
+			t.Errorf("TOTPCode at %d = %s, want %s", tc.unix, code, tc.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	codeAt := func(step int64) string {
+		code, err := TOTPCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	for _, tc := range []struct {
+		name        string
+		step        int64
+		lastCounter int64
+		wantOK      bool
+	}{
+		{name: "current step", step: current, wantOK: true},
+		{name: "one step behind", step: current - 1, wantOK: true},
+		{name: "one step ahead", step: current + 1, wantOK: true},
+		{name: "two steps behind", step: current - 2},
+		{name: "two steps ahead", step: current + 2},
+		{name: "replayed", step: current, lastCounter: current},
+		{name: "older than the last used", step: current - 1, lastCounter: current},
+		{name: "newer than the last used", step: current + 1, lastCounter: current, wantOK: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			counter, ok := ValidateTOTP(rfc6238Secret, codeAt(tc.step), now, tc.lastCounter)
+			if ok != tc.wantOK {
+				t.Fatalf("ValidateTOTP = %v, want %v", ok, tc.wantOK)
+			}
+			if ok && counter != tc.step {
+				t.Fatalf("matched counter %d, want %d", counter, tc.step)
+			}
+		})
+	}
+
+	//* The counter returned by one use rejects the same code the next time
+	counter, ok := ValidateTOTP(rfc6238Secret, codeAt(current), now, 0)
+	if !ok {
+		t.Fatal("the current code was rejected")
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, codeAt(current), now, counter); ok {
+		t.Fatal("a code was accepted twice")
+	}
+}
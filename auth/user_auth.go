@@ -2,7 +2,9 @@ package auth
 
 import (
 	"Gin/Basics/configs"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"time"
 
@@ -11,15 +13,67 @@ import (
 
 var Key = []byte(configs.JWT_SECRET())
 
-func GenerateJWT() (tokenStr string, err error) {
+const (
+	TokenTypeAccess       = "access"
+	TokenTypeMFAChallenge = "mfa_challenge"
+	TokenTypeMagicLink    = "magic_link"
+)
+
+// Authentication method references recorded in the "amr" claim. Values follow RFC 8176 where it has one.
+const (
+	AMRPassword     = "pwd"
+	AMROTP          = "otp"
+	AMRSMS          = "sms"
+	AMRHardwareKey  = "hwk"
+	AMRMultiFactor  = "mfa"
+	AMREmail        = "email"
+	AMRRecoveryCode = "recovery_code"
+	// AMRFederated marks a sign-in through an external identity provider.
+	AMRFederated = "fed"
+)
+
+var ErrInvalidTokenType = errors.New("invalid token type")
+
+// GenerateJWT issues an access token for a user who has just authenticated with the methods in amr.
+// The token records that moment in "auth_time" so that sensitive routes can ask for a recent sign-in.
+// extra adds claims such as the user's custom attributes; it cannot override the claims set here.
+func GenerateJWT(userID int64, amr []string, extra map[string]interface{}) (tokenStr string, err error) {
+	return generateJWT(userID, amr, time.Now(), extra)
+}
+
+// ReissueJWT issues a new access token in place of the one holding claims, e.g. after the old one was
+// revoked. It keeps the "amr" and "auth_time" of claims, as the user did not authenticate again.
+func ReissueJWT(userID int64, claims jwt.MapClaims, extra map[string]interface{}) (string, error) {
+	authTime, ok := AuthTimeFromClaims(claims)
+	if !ok {
+		return "", errors.New("token has no auth_time")
+	}
+
+	return generateJWT(userID, AMRFromClaims(claims), authTime, extra)
+}
+
+func generateJWT(userID int64, amr []string, authTime time.Time, extra map[string]interface{}) (tokenStr string, err error) {
 	expirationTime, err := strconv.ParseInt(configs.JWT_LIFETIME(), 10, 64)
 	if err != nil {
 		return "", err
 	}
+	if len(amr) > 1 && !slices.Contains(amr, AMRMultiFactor) {
+		amr = append(append([]string{}, amr...), AMRMultiFactor)
+	}
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"exp":        time.Now().Add(time.Duration(expirationTime) * time.Hour).Unix(),
+		"exp":        now.Add(time.Duration(expirationTime) * time.Hour).Unix(),
+		"iat":        now.Unix(),
 		"authorized": true,
-		"user":       tokenStr,
+		"sub":        strconv.FormatInt(userID, 10),
+		"typ":        TokenTypeAccess,
+		"auth_time":  authTime.Unix(),
+		"amr":        amr,
+	}
+	for name, value := range extra {
+		if _, reserved := claims[name]; !reserved {
+			claims[name] = value
+		}
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -30,8 +84,126 @@ func GenerateJWT() (tokenStr string, err error) {
 
 }
 
+// GenerateMFAChallenge issues the short-lived token returned by Login when the user still has to
+// present a second factor. amr holds the methods of the first factor and jti identifies the
+// server-side record that makes the challenge single use. It is not accepted as an access token.
+func GenerateMFAChallenge(userID int64, jti string, amr []string, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"exp":        expiresAt.Unix(),
+		"authorized": false,
+		"sub":        strconv.FormatInt(userID, 10),
+		"jti":        jti,
+		"typ":        TokenTypeMFAChallenge,
+		"amr":        amr,
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(Key)
+}
+
+// ValidateMFAChallenge returns the user ID, jti and first-factor methods of a valid MFA challenge token.
+func ValidateMFAChallenge(tokenStr string) (int64, string, []string, error) {
+	claims, err := ValidateJWT(tokenStr)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	if claims["typ"] != TokenTypeMFAChallenge {
+		return 0, "", nil, ErrInvalidTokenType
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return 0, "", nil, errors.New("token has no id")
+	}
+
+	userID, err := UserIDFromClaims(claims)
+
+	return userID, jti, AMRFromClaims(claims), err
+}
+
+// GenerateMagicLinkToken signs the token embedded in a magic login link. jti identifies the
+// server-side record that makes the link single use.
+func GenerateMagicLinkToken(userID int64, jti string, expiresAt time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"exp":        expiresAt.Unix(),
+		"authorized": false,
+		"sub":        strconv.FormatInt(userID, 10),
+		"jti":        jti,
+		"typ":        TokenTypeMagicLink,
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(Key)
+}
+
+// ValidateMagicLinkToken returns the user ID and jti of a valid magic link token.
+func ValidateMagicLinkToken(tokenStr string) (int64, string, error) {
+	claims, err := ValidateJWT(tokenStr)
+	if err != nil {
+		return 0, "", err
+	}
+	if claims["typ"] != TokenTypeMagicLink {
+		return 0, "", ErrInvalidTokenType
+	}
+
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return 0, "", errors.New("token has no id")
+	}
+
+	userID, err := UserIDFromClaims(claims)
+
+	return userID, jti, err
+}
+
+// AMRFromClaims returns the authentication methods stored in the "amr" claim.
+func AMRFromClaims(claims jwt.MapClaims) []string {
+	values, _ := claims["amr"].([]interface{})
+
+	amr := make([]string, 0, len(values))
+	for _, value := range values {
+		if method, ok := value.(string); ok {
+			amr = append(amr, method)
+		}
+	}
+
+	return amr
+}
+
+// AuthTimeFromClaims returns when the user last actively authenticated, per the "auth_time" claim.
+func AuthTimeFromClaims(claims jwt.MapClaims) (time.Time, bool) {
+	authTime, ok := claims["auth_time"].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(authTime), 0), true
+}
+
+// IssuedAtFromClaims returns when the token was issued, per the "iat" claim. Tokens issued before
+// the claim was added report the zero time.
+func IssuedAtFromClaims(claims jwt.MapClaims) time.Time {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(iat), 0)
+}
+
+// UserIDFromClaims returns the user ID stored in the "sub" claim.
+func UserIDFromClaims(claims jwt.MapClaims) (int64, error) {
+	sub, ok := claims["sub"].(string)
+	if !ok {
+		return 0, errors.New("token has no subject")
+	}
+
+	return strconv.ParseInt(sub, 10, 64)
+}
+
 func ValidateJWT(tokenStr string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
 		return Key, nil
 	})
 
@@ -42,7 +214,7 @@ func ValidateJWT(tokenStr string) (jwt.MapClaims, error) {
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		return claims, nil
 	} else {
-		return nil, err
+		return nil, errors.New("invalid token")
 	}
 }
 
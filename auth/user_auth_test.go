@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReissueJWTKeepsAuthTime(t *testing.T) {
+	t.Setenv("JWT_LIFETIME", "1")
+
+	original, err := GenerateJWT(1, []string{AMRPassword, AMROTP}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateJWT(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//* As if the user signed in an hour ago
+	authTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	claims["auth_time"] = float64(authTime.Unix())
+
+	reissued, err := ReissueJWT(1, claims, map[string]interface{}{"auth_time": time.Now().Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reissuedClaims, err := ValidateJWT(reissued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := AuthTimeFromClaims(reissuedClaims); !got.Equal(authTime) {
+		t.Fatalf("auth_time = %v, want %v", got, authTime)
+	}
+	if amr := AMRFromClaims(reissuedClaims); !slices.Equal(amr, []string{AMRPassword, AMROTP, AMRMultiFactor}) {
+		t.Fatalf("amr = %v", amr)
+	}
+
+	delete(claims, "auth_time")
+	if _, err := ReissueJWT(1, claims, nil); err == nil {
+		t.Fatal("a token without auth_time was reissued")
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const defaultRPName = "Auth API"
+
+// WebAuthnUser adapts a user and their stored credentials to webauthn.User.
+type WebAuthnUser struct {
+	User        db.User
+	Credentials []webauthn.Credential
+}
+
+var _ webauthn.User = (*WebAuthnUser)(nil)
+
+// NewWebAuthn returns a relying party configured from WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME and WEBAUTHN_RP_ORIGINS.
+func NewWebAuthn() (*webauthn.WebAuthn, error) {
+	name := configs.WEBAUTHN_RP_NAME()
+	if name == "" {
+		name = defaultRPName
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(configs.WEBAUTHN_RP_ORIGINS(), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          configs.WEBAUTHN_RP_ID(),
+		RPDisplayName: name,
+		RPOrigins:     origins,
+	})
+}
+
+// NewWebAuthnUser decodes the stored credentials of user.
+func NewWebAuthnUser(user db.User, stored []db.WebauthnCredential) (*WebAuthnUser, error) {
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, row := range stored {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(row.Credential, &credential); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return &WebAuthnUser{User: user, Credentials: credentials}, nil
+}
+
+// WebAuthnUserHandle is the opaque user handle given to authenticators: the big-endian user ID.
+func WebAuthnUserHandle(userID int64) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+
+	return handle
+}
+
+// UserIDFromWebAuthnHandle reverses WebAuthnUserHandle.
+func UserIDFromWebAuthnHandle(handle []byte) (int64, error) {
+	if len(handle) != 8 {
+		return 0, errors.New("invalid user handle")
+	}
+
+	return int64(binary.BigEndian.Uint64(handle)), nil
+}
+
+func (u *WebAuthnUser) WebAuthnID() []byte {
+	return WebAuthnUserHandle(u.User.ID)
+}
+
+func (u *WebAuthnUser) WebAuthnName() string {
+	return u.User.Email
+}
+
+func (u *WebAuthnUser) WebAuthnDisplayName() string {
+	return u.User.Name
+}
+
+func (u *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
+
+func (u *WebAuthnUser) WebAuthnIcon() string {
+	return ""
+}
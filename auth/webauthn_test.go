@@ -0,0 +1,312 @@
+package auth
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	testRPID     = "auth.example.com"
+	testRPOrigin = "https://auth.example.com"
+
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// softAuthenticator is a passkey held in memory: it answers ceremonies the way a browser and a
+// platform authenticator would, with "none" attestation.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *softAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony string, challenge string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": testRPOrigin})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// create answers navigator.credentials.create() for options.
+func (a *softAuthenticator) create(t *testing.T, options *protocol.CredentialCreation) []byte {
+	t.Helper()
+	a.userHandle = options.Response.User.ID.(protocol.URLEncodedBase64)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1,
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(flagUserPresent|flagUserVerified|flagAttestedData, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(a.clientData(t, "webauthn.create", options.Response.Challenge.String())),
+		"attestationObject": encode(attestation),
+	})
+}
+
+// get answers navigator.credentials.get() for options, with the user verified when verified is set.
+func (a *softAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion, verified bool) []byte {
+	t.Helper()
+
+	flags := byte(flagUserPresent)
+	if verified {
+		flags |= flagUserVerified
+	}
+	authData := a.authenticatorData(flags, nil)
+	clientData := a.clientData(t, "webauthn.get", options.Response.Challenge.String())
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]string) []byte {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func request(body []byte) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+}
+
+func newTestRelyingParty(t *testing.T) *webauthn.WebAuthn {
+	t.Helper()
+	t.Setenv("WEBAUTHN_RP_ID", testRPID)
+	t.Setenv("WEBAUTHN_RP_NAME", "")
+	t.Setenv("WEBAUTHN_RP_ORIGINS", " "+testRPOrigin+", ")
+
+	relyingParty, err := NewWebAuthn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return relyingParty
+}
+
+// register runs a registration ceremony for user and returns the stored credential.
+func register(t *testing.T, relyingParty *webauthn.WebAuthn, authenticator *softAuthenticator, user *WebAuthnUser) webauthn.Credential {
+	t.Helper()
+
+	options, session, err := relyingParty.BeginRegistration(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential, err := relyingParty.FinishRegistration(user, *session, request(authenticator.create(t, options)))
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	return *credential
+}
+
+func TestWebAuthnRegistrationAndAssertion(t *testing.T) {
+	relyingParty := newTestRelyingParty(t)
+	authenticator := newSoftAuthenticator(t)
+	user := &WebAuthnUser{User: db.User{ID: 42, Email: "ada@example.com", Name: "Ada"}}
+
+	credential := register(t, relyingParty, authenticator, user)
+	if !bytes.Equal(credential.ID, authenticator.credentialID) {
+		t.Fatalf("credential ID = %x, want %x", credential.ID, authenticator.credentialID)
+	}
+	if !bytes.Equal(authenticator.userHandle, WebAuthnUserHandle(42)) {
+		t.Fatalf("user handle = %x, want the big-endian user ID", authenticator.userHandle)
+	}
+
+	//* The stored form must survive the round trip through the database
+	stored, err := json.Marshal(credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err = NewWebAuthnUser(user.User, []db.WebauthnCredential{{Credential: stored}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator.signCount = 1
+	options, session, err := relyingParty.BeginLogin(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used, err := relyingParty.FinishLogin(user, *session, request(authenticator.get(t, options, false)))
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if used.Authenticator.SignCount != 1 || used.Authenticator.CloneWarning {
+		t.Fatalf("sign count = %d, clone warning = %v", used.Authenticator.SignCount, used.Authenticator.CloneWarning)
+	}
+}
+
+func TestWebAuthnSignCountRegression(t *testing.T) {
+	relyingParty := newTestRelyingParty(t)
+	authenticator := newSoftAuthenticator(t)
+	user := &WebAuthnUser{User: db.User{ID: 7, Email: "grace@example.com", Name: "Grace"}}
+
+	credential := register(t, relyingParty, authenticator, user)
+	credential.Authenticator.SignCount = 10
+	user.Credentials = []webauthn.Credential{credential}
+
+	//* A copy of the key that has signed fewer times than the stored counter
+	authenticator.signCount = 9
+	options, session, err := relyingParty.BeginLogin(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used, err := relyingParty.FinishLogin(user, *session, request(authenticator.get(t, options, false)))
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if !used.Authenticator.CloneWarning {
+		t.Fatal("expected a clone warning for a sign counter that went backwards")
+	}
+}
+
+func TestWebAuthnDiscoverableLoginRequiresUserVerification(t *testing.T) {
+	relyingParty := newTestRelyingParty(t)
+	authenticator := newSoftAuthenticator(t)
+	user := &WebAuthnUser{User: db.User{ID: 99, Email: "alan@example.com", Name: "Alan"}}
+	user.Credentials = []webauthn.Credential{register(t, relyingParty, authenticator, user)}
+
+	findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := UserIDFromWebAuthnHandle(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		if userID != user.User.ID {
+			t.Fatalf("user handle resolved to %d, want %d", userID, user.User.ID)
+		}
+		return user, nil
+	}
+
+	for _, tc := range []struct {
+		name     string
+		verified bool
+		wantErr  bool
+	}{
+		{name: "presence only", verified: false, wantErr: true},
+		{name: "user verified", verified: true, wantErr: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			authenticator.signCount++
+			options, session, err := relyingParty.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := protocol.ParseCredentialRequestResponse(request(authenticator.get(t, options, tc.verified)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = relyingParty.ValidateDiscoverableLogin(findUser, *session, parsed)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateDiscoverableLogin error = %v, want error %v", err, tc.wantErr)
+			}
+			var protocolErr *protocol.Error
+			if tc.wantErr && !(errors.As(err, &protocolErr) && strings.Contains(protocolErr.DevInfo, "User verification required")) {
+				t.Fatalf("ValidateDiscoverableLogin error = %v, want a user verification failure", err)
+			}
+		})
+	}
+}
+
+func TestUserIDFromWebAuthnHandle(t *testing.T) {
+	if _, err := UserIDFromWebAuthnHandle([]byte{1, 2, 3}); err == nil {
+		t.Fatal("expected an error for a short handle")
+	}
+	userID, err := UserIDFromWebAuthnHandle(WebAuthnUserHandle(1 << 40))
+	if err != nil || userID != 1<<40 {
+		t.Fatalf("got %d, %v", userID, err)
+	}
+}
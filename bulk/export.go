@@ -0,0 +1,60 @@
+package bulk
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	model "Gin/Basics/models"
+	"context"
+)
+
+const exportPageSize = 1000
+
+// Export writes every user to writer in id order, reading them a page at a time so that the table
+// never has to fit in memory. Accounts awaiting deletion are left out. It returns the number of
+// users written.
+func Export(ctx context.Context, queries *db.Queries, writer Writer) (int, error) {
+	exported := 0
+	var after int64
+	for {
+		users, err := queries.ListUsersAfter(ctx, db.ListUsersAfterParams{ID: after, Limit: exportPageSize})
+		if err != nil {
+			return exported, err
+		}
+
+		for _, user := range users {
+			if user.Status == db.AccountStatusDeleted {
+				continue
+			}
+			if err := writer.Write(recordFromUser(user)); err != nil {
+				return exported, err
+			}
+			exported++
+		}
+
+		if len(users) < exportPageSize {
+			return exported, writer.Flush()
+		}
+		after = users[len(users)-1].ID
+	}
+}
+
+func recordFromUser(user db.User) Record {
+	record := Record{
+		Email:        user.Email,
+		Name:         user.Name,
+		DisplayName:  user.DisplayName,
+		Username:     user.Username.String,
+		PasswordHash: user.Password,
+		Verified:     user.Isverified,
+		Status:       string(user.Status),
+		Phone:        user.Phone,
+		Locale:       user.Locale,
+		Timezone:     user.Timezone,
+		Attributes:   model.DecodeAttributes(user.Attributes),
+	}
+	if user.CreatedAt.Valid {
+		createdAt := user.CreatedAt.Time
+		record.CreatedAt = &createdAt
+	}
+
+	return record
+}
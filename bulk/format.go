@@ -0,0 +1,265 @@
+// Package bulk moves users in and out of the database in CSV or JSON Lines files, e.g. when
+// migrating from another system.
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File formats.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Columns are the CSV header and the JSON Lines keys, in export order. Only email and name are
+// needed on import.
+var Columns = []string{
+	"email", "name", "display_name", "username", "password_hash", "verified", "status",
+	"phone", "locale", "timezone", "attributes", "created_at",
+}
+
+// Record is one user as it appears in an import or export file. PasswordHash is a bcrypt hash, or
+// empty for a user without a password. Status is the account status; only "disabled" and "locked"
+// are imported as such, as "pending" and "active" follow from Verified.
+type Record struct {
+	Email        string                 `json:"email"`
+	Name         string                 `json:"name"`
+	DisplayName  string                 `json:"display_name,omitempty"`
+	Username     string                 `json:"username,omitempty"`
+	PasswordHash string                 `json:"password_hash"`
+	Verified     bool                   `json:"verified"`
+	Status       string                 `json:"status,omitempty"`
+	Phone        string                 `json:"phone,omitempty"`
+	Locale       string                 `json:"locale,omitempty"`
+	Timezone     string                 `json:"timezone,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	CreatedAt    *time.Time             `json:"created_at,omitempty"`
+}
+
+// FormatFromPath guesses the format from a file name, defaulting to CSV.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	default:
+		return FormatCSV
+	}
+}
+
+// RecordError reports a record that could not be read or imported.
+type RecordError struct {
+	Line  int
+	Email string
+	Err   error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads records one at a time. Next returns io.EOF after the last record. A *RecordError
+// means that only the record at that line is unusable and reading can go on; any other error is fatal.
+type Reader interface {
+	Next() (record Record, line int, err error)
+}
+
+// NewReader returns a Reader for the given format.
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("bulk: reading CSV header: %w", err)
+		}
+		for i, column := range header {
+			header[i] = strings.TrimSpace(column)
+			if !slices.Contains(Columns, header[i]) {
+				return nil, fmt.Errorf("bulk: unknown CSV column %q", column)
+			}
+		}
+		return &csvReader{reader: reader, header: header}, nil
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		return &jsonlReader{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("bulk: unknown format %q", format)
+	}
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func (c *csvReader) Next() (Record, int, error) {
+	var record Record
+	fields, err := c.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return record, parseErr.StartLine, &RecordError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return record, 0, err
+	}
+	line, _ := c.reader.FieldPos(0)
+	if len(fields) != len(c.header) {
+		return record, line, &RecordError{Line: line, Err: fmt.Errorf("expected %d fields, got %d", len(c.header), len(fields))}
+	}
+
+	for i, value := range fields {
+		switch c.header[i] {
+		case "email":
+			record.Email = value
+		case "name":
+			record.Name = value
+		case "display_name":
+			record.DisplayName = value
+		case "username":
+			record.Username = value
+		case "password_hash":
+			record.PasswordHash = value
+		case "verified":
+			if value != "" {
+				if record.Verified, err = strconv.ParseBool(value); err != nil {
+					return record, line, &RecordError{Line: line, Email: record.Email, Err: fmt.Errorf("verified: %w", err)}
+				}
+			}
+		case "status":
+			record.Status = value
+		case "phone":
+			record.Phone = value
+		case "locale":
+			record.Locale = value
+		case "timezone":
+			record.Timezone = value
+		case "attributes":
+			if value != "" {
+				if err := json.Unmarshal([]byte(value), &record.Attributes); err != nil {
+					return record, line, &RecordError{Line: line, Email: record.Email, Err: fmt.Errorf("attributes: %w", err)}
+				}
+			}
+		case "created_at":
+			if value != "" {
+				createdAt, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					return record, line, &RecordError{Line: line, Email: record.Email, Err: fmt.Errorf("created_at: %w", err)}
+				}
+				record.CreatedAt = &createdAt
+			}
+		}
+	}
+
+	return record, line, nil
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (j *jsonlReader) Next() (Record, int, error) {
+	var record Record
+	for j.scanner.Scan() {
+		j.line++
+		text := strings.TrimSpace(j.scanner.Text())
+		if text == "" {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			return Record{}, j.line, &RecordError{Line: j.line, Err: err}
+		}
+		return record, j.line, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return record, j.line, err
+	}
+
+	return record, j.line, io.EOF
+}
+
+// Writer writes records in one of the formats. Call Flush when done.
+type Writer interface {
+	Write(record Record) error
+	Flush() error
+}
+
+// NewWriter returns a Writer for the given format. CSV output starts with the header.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(Columns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{writer: writer}, nil
+	case FormatJSONL:
+		buffered := bufio.NewWriter(w)
+		return &jsonlWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	default:
+		return nil, fmt.Errorf("bulk: unknown format %q", format)
+	}
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvWriter) Write(record Record) error {
+	attributes := ""
+	if len(record.Attributes) > 0 {
+		encoded, err := json.Marshal(record.Attributes)
+		if err != nil {
+			return err
+		}
+		attributes = string(encoded)
+	}
+	createdAt := ""
+	if record.CreatedAt != nil {
+		createdAt = record.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return c.writer.Write([]string{
+		record.Email, record.Name, record.DisplayName, record.Username, record.PasswordHash,
+		strconv.FormatBool(record.Verified), record.Status, record.Phone, record.Locale, record.Timezone,
+		attributes, createdAt,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.writer.Flush()
+
+	return c.writer.Error()
+}
+
+type jsonlWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (j *jsonlWriter) Write(record Record) error {
+	return j.encoder.Encode(record)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.buffered.Flush()
+}
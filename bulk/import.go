@@ -0,0 +1,205 @@
+package bulk
+
+import (
+	"Gin/Basics/attributes"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/mailer"
+	model "Gin/Basics/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const defaultBatchSize = 500
+
+// Importer creates users from a Reader, keeping their bcrypt password hashes. Users without one are
+// imported without a password.
+type Importer struct {
+	Pool *pgxpool.Pool
+	// BatchSize is the number of records committed per transaction. Defaults to 500.
+	BatchSize int
+	// DryRun checks every record against the database but rolls every batch back.
+	DryRun bool
+	// MarkVerified marks all imported users verified; otherwise the verified field of each record is used.
+	MarkVerified bool
+	// Schema validates the records' attributes. Nil accepts none.
+	Schema *attributes.Schema
+	// OnError, when set, is told about every record that was not imported.
+	OnError func(*RecordError)
+}
+
+// Summary counts the outcome of an import. In a dry run, Imported counts the records that would
+// have been imported.
+type Summary struct {
+	Imported int
+	Failed   int
+}
+
+// Import reads every record from reader. A bad record is reported to OnError and skipped without
+// affecting the rest of its batch; an error is only returned when the import cannot go on.
+func (im *Importer) Import(ctx context.Context, reader Reader) (Summary, error) {
+	var summary Summary
+	batchSize := im.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	for done := false; !done; {
+		tx, err := im.Pool.Begin(ctx)
+		if err != nil {
+			return summary, err
+		}
+
+		imported := 0
+		for imported < batchSize {
+			record, line, readErr := reader.Next()
+			if readErr == io.EOF {
+				done = true
+				break
+			}
+			var recordErr *RecordError
+			if errors.As(readErr, &recordErr) {
+				im.fail(&summary, recordErr)
+				continue
+			}
+			if readErr != nil {
+				tx.Rollback(ctx)
+				return summary, readErr
+			}
+
+			if importErr := im.importRecord(ctx, tx, record); importErr != nil {
+				im.fail(&summary, &RecordError{Line: line, Email: record.Email, Err: importErr})
+				continue
+			}
+			imported++
+		}
+
+		if im.DryRun {
+			tx.Rollback(ctx)
+		} else if err := tx.Commit(ctx); err != nil {
+			return summary, fmt.Errorf("bulk: committing batch: %w", err)
+		}
+		summary.Imported += imported
+	}
+
+	return summary, nil
+}
+
+// importRecord inserts one record inside a savepoint, so that a failed insert does not abort tx.
+func (im *Importer) importRecord(ctx context.Context, tx pgx.Tx, record Record) error {
+	params, err := im.prepare(record)
+	if err != nil {
+		return err
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := db.New(savepoint).ImportUser(ctx, params); err != nil {
+		savepoint.Rollback(ctx)
+		return err
+	}
+
+	return savepoint.Commit(ctx)
+}
+
+// prepare checks record the way registration would and turns it into insert parameters.
+func (im *Importer) prepare(record Record) (db.ImportUserParams, error) {
+	var params db.ImportUserParams
+
+	email, err := model.NormalizeEmail(record.Email)
+	if err != nil {
+		return params, err
+	}
+	if record.Name == "" {
+		return params, errors.New("name is required")
+	}
+	//* An empty hash is a passwordless account, which signs in with a magic link, passkey or external identity
+	if record.PasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(record.PasswordHash)); err != nil {
+			return params, fmt.Errorf("password_hash: %w", err)
+		}
+	}
+
+	username := pgtype.Text{}
+	if record.Username != "" {
+		normalized, err := model.NormalizeUsername(record.Username)
+		if err != nil {
+			return params, fmt.Errorf("username: %w", err)
+		}
+		username = pgtype.Text{String: normalized, Valid: true}
+	}
+
+	locale := mailer.DefaultLocale
+	if record.Locale != "" {
+		canonical, ok := model.CanonicalLocale(record.Locale)
+		if !ok {
+			return params, fmt.Errorf("invalid locale %q", record.Locale)
+		}
+		locale = canonical
+	}
+	timezone := "UTC"
+	if record.Timezone != "" {
+		if !model.ValidTimezone(record.Timezone) {
+			return params, fmt.Errorf("invalid timezone %q", record.Timezone)
+		}
+		timezone = record.Timezone
+	}
+
+	//* Pending and active follow from verified; accounts awaiting deletion are not imported
+	status := db.NullAccountStatus{}
+	switch db.AccountStatus(record.Status) {
+	case "", db.AccountStatusPending, db.AccountStatusActive:
+	case db.AccountStatusDisabled, db.AccountStatusLocked:
+		status = db.NullAccountStatus{AccountStatus: db.AccountStatus(record.Status), Valid: true}
+	default:
+		return params, fmt.Errorf("invalid status %q", record.Status)
+	}
+
+	schema := im.Schema
+	if schema == nil {
+		schema = &attributes.Schema{}
+	}
+	attrs, err := schema.Validate(record.Attributes)
+	if err != nil {
+		return params, err
+	}
+	encodedAttrs, err := json.Marshal(attrs)
+	if err != nil {
+		return params, err
+	}
+
+	params = db.ImportUserParams{
+		Name:        record.Name,
+		Email:       email,
+		Password:    record.PasswordHash,
+		Isverified:  record.Verified || im.MarkVerified,
+		Phone:       record.Phone,
+		Locale:      locale,
+		Timezone:    timezone,
+		DisplayName: record.DisplayName,
+		Username:    username,
+		Attributes:  encodedAttrs,
+		Status:      status,
+	}
+	if record.CreatedAt != nil {
+		params.CreatedAt = pgtype.Timestamptz{Time: *record.CreatedAt, Valid: true}
+	}
+
+	return params, nil
+}
+
+func (im *Importer) fail(summary *Summary, err *RecordError) {
+	summary.Failed++
+	if im.OnError != nil {
+		im.OnError(err)
+	}
+}
@@ -0,0 +1,188 @@
+package bulk
+
+import (
+	"Gin/Basics/attributes"
+	db "Gin/Basics/db/sqlconfig"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestExportImportRoundTrip writes users the way Export does and checks that importing the file
+// gives back the same accounts, including ones without a password.
+func TestExportImportRoundTrip(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := attributes.Parse([]byte(`{"company": {"type": "string"}, "seats": {"type": "integer"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	createdAt := time.Date(2024, 3, 1, 12, 30, 15, 250000000, time.UTC)
+
+	users := []db.User{
+		{
+			Email:       "ada@example.com",
+			Name:        "Ada Lovelace",
+			DisplayName: "Ada",
+			Username:    pgtype.Text{String: "ada", Valid: true},
+			Password:    string(hash),
+			Isverified:  true,
+			Phone:       "+15551234567",
+			Locale:      "es",
+			Timezone:    "Europe/London",
+			Attributes:  []byte(`{"company": "Analytical Engines", "seats": 3}`),
+			CreatedAt:   pgtype.Timestamptz{Time: createdAt, Valid: true},
+			Status:      db.AccountStatusActive,
+		},
+		{
+			//* Signed up through an identity provider or a magic link
+			Email:      "grace@example.com",
+			Name:       "Grace Hopper",
+			Locale:     "en",
+			Timezone:   "UTC",
+			Attributes: []byte(`{}`),
+			CreatedAt:  pgtype.Timestamptz{Time: createdAt, Valid: true},
+			Status:     db.AccountStatusPending,
+		},
+		{
+			//* Disabled by an admin, which must survive the move
+			Email:      "charles@example.com",
+			Name:       "Charles Babbage",
+			Password:   string(hash),
+			Isverified: true,
+			Locale:     "en",
+			Timezone:   "UTC",
+			Attributes: []byte(`{}`),
+			CreatedAt:  pgtype.Timestamptz{Time: createdAt, Valid: true},
+			Status:     db.AccountStatusDisabled,
+		},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSONL} {
+		t.Run(format, func(t *testing.T) {
+			var file bytes.Buffer
+			writer, err := NewWriter(&file, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, user := range users {
+				if err := writer.Write(recordFromUser(user)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			reader, err := NewReader(&file, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			importer := &Importer{Schema: schema}
+			for _, user := range users {
+				record, line, err := reader.Next()
+				if err != nil {
+					t.Fatalf("line %d: %v", line, err)
+				}
+				params, err := importer.prepare(record)
+				if err != nil {
+					t.Fatalf("line %d: %v", line, err)
+				}
+
+				if params.Email != user.Email || params.Name != user.Name || params.DisplayName != user.DisplayName ||
+					params.Username != user.Username || params.Password != user.Password || params.Isverified != user.Isverified ||
+					params.Phone != user.Phone || params.Locale != user.Locale || params.Timezone != user.Timezone {
+					t.Fatalf("line %d: imported %+v from %+v", line, params, user)
+				}
+				if status := importedStatus(params); status != user.Status {
+					t.Fatalf("line %d: status = %s, want %s", line, status, user.Status)
+				}
+				if !params.CreatedAt.Time.Equal(user.CreatedAt.Time) {
+					t.Fatalf("line %d: created_at = %v, want %v", line, params.CreatedAt.Time, user.CreatedAt.Time)
+				}
+				if !sameJSON(t, params.Attributes, user.Attributes) {
+					t.Fatalf("line %d: attributes = %s, want %s", line, params.Attributes, user.Attributes)
+				}
+			}
+			if _, _, err := reader.Next(); err != io.EOF {
+				t.Fatalf("expected the end of the file, got %v", err)
+			}
+		})
+	}
+}
+
+func TestImportRejectsMalformedPasswordHash(t *testing.T) {
+	importer := &Importer{}
+	if _, err := importer.prepare(Record{Email: "ada@example.com", Name: "Ada", PasswordHash: "plaintext"}); err == nil {
+		t.Fatal("expected an error for a password_hash that is not a bcrypt hash")
+	}
+}
+
+func TestImportStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status   string
+		verified bool
+		want     db.AccountStatus
+		wantErr  bool
+	}{
+		{status: "", verified: true, want: db.AccountStatusActive},
+		{status: "", want: db.AccountStatusPending},
+		{status: "active", verified: true, want: db.AccountStatusActive},
+		//* Pending and active follow from verified, so they cannot disagree with it
+		{status: "active", want: db.AccountStatusPending},
+		{status: "pending", verified: true, want: db.AccountStatusActive},
+		{status: "disabled", want: db.AccountStatusDisabled},
+		{status: "locked", verified: true, want: db.AccountStatusLocked},
+		{status: "deleted", wantErr: true},
+		{status: "banned", wantErr: true},
+	} {
+		importer := &Importer{}
+		params, err := importer.prepare(Record{Email: "ada@example.com", Name: "Ada", Verified: tc.verified, Status: tc.status})
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("status %q was accepted", tc.status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("status %q: %v", tc.status, err)
+		}
+		if status := importedStatus(params); status != tc.want {
+			t.Errorf("status %q, verified %v: imported as %s, want %s", tc.status, tc.verified, status, tc.want)
+		}
+	}
+}
+
+// importedStatus is the status ImportUser stores for params.
+func importedStatus(params db.ImportUserParams) db.AccountStatus {
+	if params.Status.Valid {
+		return params.Status.AccountStatus
+	}
+	if params.Isverified {
+		return db.AccountStatusActive
+	}
+	return db.AccountStatusPending
+}
+
+func sameJSON(t *testing.T, a []byte, b []byte) bool {
+	t.Helper()
+
+	var decodedA, decodedB interface{}
+	if err := json.Unmarshal(a, &decodedA); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &decodedB); err != nil {
+		t.Fatal(err)
+	}
+	encodedA, _ := json.Marshal(decodedA)
+	encodedB, _ := json.Marshal(decodedB)
+
+	return bytes.Equal(encodedA, encodedB)
+}
@@ -0,0 +1,52 @@
+package configs
+
+import (
+	"Gin/Basics/alert"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	alerts     *alert.Alerter
+	alertsOnce sync.Once
+)
+
+// Alerts returns the alerter that server errors are reported to. Digests always go to the log, to
+// ALERT_EMAIL (or ADMIN) by email when set, and to ALERT_WEBHOOK_URL when set.
+func Alerts() *alert.Alerter {
+	alertsOnce.Do(func() {
+		sinks := []alert.Sink{&alert.LogSink{}}
+
+		list := ALERT_EMAIL()
+		if list == "" {
+			list = ADMIN()
+		}
+		var recipients []string
+		for _, recipient := range strings.Split(list, ",") {
+			if recipient = strings.TrimSpace(recipient); recipient != "" {
+				recipients = append(recipients, recipient)
+			}
+		}
+		if len(recipients) > 0 {
+			sinks = append(sinks, &alert.EmailSink{
+				Mailer:    Mailer(),
+				Templates: Templates(),
+				To:        recipients,
+			})
+		}
+
+		if url := ALERT_WEBHOOK_URL(); url != "" {
+			sinks = append(sinks, &alert.WebhookSink{URL: url, Token: ALERT_WEBHOOK_TOKEN()})
+		}
+
+		interval, err := time.ParseDuration(ALERT_INTERVAL())
+		if err != nil {
+			interval = alert.DefaultInterval
+		}
+
+		alerts = &alert.Alerter{Sinks: sinks, Interval: interval}
+	})
+
+	return alerts
+}
@@ -0,0 +1,32 @@
+package configs
+
+import (
+	"Gin/Basics/attributes"
+	"log"
+	"sync"
+)
+
+var (
+	attributeSchema     *attributes.Schema
+	attributeSchemaOnce sync.Once
+)
+
+// AttributeSchema returns the schema of custom user attributes loaded from USER_ATTRIBUTES_SCHEMA.
+// An unreadable or invalid schema stops the server.
+func AttributeSchema() *attributes.Schema {
+	attributeSchemaOnce.Do(func() {
+		schema, err := attributes.Load(USER_ATTRIBUTES_SCHEMA())
+		if err != nil {
+			log.Fatal(err)
+		}
+		attributeSchema = schema
+	})
+
+	return attributeSchema
+}
+
+// SetAttributeSchema replaces the schema returned by AttributeSchema, e.g. in tests.
+func SetAttributeSchema(schema *attributes.Schema) {
+	attributeSchemaOnce.Do(func() {})
+	attributeSchema = schema
+}
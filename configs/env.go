@@ -1,14 +1,25 @@
 package configs
 
 import (
+	"errors"
+	"io/fs"
 	"log"
 	"os"
 
 	"github.com/joho/godotenv"
 )
 
+// loadEnv reads the .env file into the environment. Without one the settings come from the
+// environment alone, as in containers and tests.
+func loadEnv() error {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
 func SQLURI() string {
-	err := godotenv.Load()
+	err := loadEnv()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -17,7 +28,7 @@ func SQLURI() string {
 }
 
 func JWT_SECRET() string {
-	err := godotenv.Load()
+	err := loadEnv()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -26,7 +37,7 @@ func JWT_SECRET() string {
 }
 
 func JWT_LIFETIME() string {
-	err := godotenv.Load()
+	err := loadEnv()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -35,7 +46,7 @@ func JWT_LIFETIME() string {
 }
 
 func EMAIL() string {
-	err := godotenv.Load()
+	err := loadEnv()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -44,7 +55,7 @@ func EMAIL() string {
 }
 
 func PASSWORD() string {
-	err := godotenv.Load()
+	err := loadEnv()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -53,7 +64,7 @@ func PASSWORD() string {
 }
 
 func RELEASE_MODE() string {
-	err := godotenv.Load()
+	err := loadEnv()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -62,7 +73,7 @@ func RELEASE_MODE() string {
 }
 
 func PORT() string {
-	err := godotenv.Load()
+	err := loadEnv()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -70,10 +81,450 @@ func PORT() string {
 	return os.Getenv("PORT")
 }
 func ADMIN() string {
-	err := godotenv.Load()
+	err := loadEnv()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
 	return os.Getenv("ADMIN")
 }
+
+// OTP_PEPPER is the secret key OTPs are hashed with. It is required and must be at least 32
+// characters; changing it invalidates every code already sent.
+func OTP_PEPPER() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("OTP_PEPPER")
+}
+
+// OTP_LENGTH is the number of characters in OTPs, at least 6, the default.
+func OTP_LENGTH() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("OTP_LENGTH")
+}
+
+func OTP_ALPHABET() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("OTP_ALPHABET")
+}
+
+func MFA_ENCRYPTION_KEY() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MFA_ENCRYPTION_KEY")
+}
+
+func MFA_ISSUER() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MFA_ISSUER")
+}
+
+func WEBAUTHN_RP_ID() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("WEBAUTHN_RP_ID")
+}
+
+func WEBAUTHN_RP_NAME() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("WEBAUTHN_RP_NAME")
+}
+
+// WEBAUTHN_RP_ORIGINS is a comma separated list of allowed origins, e.g. "https://app.example.com".
+func WEBAUTHN_RP_ORIGINS() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("WEBAUTHN_RP_ORIGINS")
+}
+
+func MAGIC_LINK_ENABLED() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MAGIC_LINK_ENABLED")
+}
+
+// MAGIC_LINK_URL is the page the emailed link points to; the token is appended as ?token=.
+func MAGIC_LINK_URL() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MAGIC_LINK_URL")
+}
+
+// MAGIC_LINK_LIFETIME is in minutes.
+func MAGIC_LINK_LIFETIME() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MAGIC_LINK_LIFETIME")
+}
+
+// SMS_PROVIDER_URL is the endpoint messages are POSTed to. Without it, and without SMS_FAKE, SMS is turned off.
+func SMS_PROVIDER_URL() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMS_PROVIDER_URL")
+}
+
+// SMS_FAKE set to "true" logs text messages instead of sending them, for local development.
+func SMS_FAKE() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMS_FAKE")
+}
+
+func SMS_PROVIDER_TOKEN() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMS_PROVIDER_TOKEN")
+}
+
+func SMS_FROM() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMS_FROM")
+}
+
+// MAIL_TRANSPORT is one of "smtp" (default), "file" or "memory".
+func MAIL_TRANSPORT() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MAIL_TRANSPORT")
+}
+
+func SMTP_HOST() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMTP_HOST")
+}
+
+func SMTP_PORT() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMTP_PORT")
+}
+
+// SMTP_TLS is one of "starttls" (default), "implicit" or "none".
+func SMTP_TLS() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMTP_TLS")
+}
+
+func SMTP_USERNAME() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMTP_USERNAME")
+}
+
+func SMTP_PASSWORD() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SMTP_PASSWORD")
+}
+
+func MAIL_FROM() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MAIL_FROM")
+}
+
+// MAIL_DIR is where the file transport writes .eml files. When empty, emails are printed to stdout.
+func MAIL_DIR() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MAIL_DIR")
+}
+
+// MAIL_TEMPLATE_DIR holds operator overrides of the built-in email templates, laid out as <locale>/<file>.
+func MAIL_TEMPLATE_DIR() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("MAIL_TEMPLATE_DIR")
+}
+
+// OUTBOX_WORKERS is the number of goroutines delivering queued email and SMS messages. Defaults to 4.
+func OUTBOX_WORKERS() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("OUTBOX_WORKERS")
+}
+
+// OUTBOX_MAX_ATTEMPTS is how many delivery attempts a queued message gets before it is dead-lettered. Defaults to 8.
+func OUTBOX_MAX_ATTEMPTS() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("OUTBOX_MAX_ATTEMPTS")
+}
+
+// OUTBOX_BODY_RETENTION is how long dead-lettered messages keep their bodies, which can hold one-time codes and
+// sign-in links, so that they can still be retried, as a Go duration such as "24h". Defaults to 24 hours.
+func OUTBOX_BODY_RETENTION() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("OUTBOX_BODY_RETENTION")
+}
+
+// ALERT_EMAIL is a comma-separated list of addresses that receive error digests. Defaults to ADMIN.
+func ALERT_EMAIL() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("ALERT_EMAIL")
+}
+
+// ALERT_WEBHOOK_URL receives error digests as JSON POSTs when set.
+func ALERT_WEBHOOK_URL() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("ALERT_WEBHOOK_URL")
+}
+
+func ALERT_WEBHOOK_TOKEN() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("ALERT_WEBHOOK_TOKEN")
+}
+
+// ALERT_INTERVAL is how often error digests are sent, as a Go duration such as "5m". Defaults to 5 minutes.
+func ALERT_INTERVAL() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("ALERT_INTERVAL")
+}
+
+// ACCOUNT_DELETION_GRACE is how long a deleted account can still be restored by signing in, as a Go duration such as "720h". Defaults to 30 days.
+func ACCOUNT_DELETION_GRACE() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("ACCOUNT_DELETION_GRACE")
+}
+
+// EMAIL_PROVIDER_RULES set to "true" also folds case, dots and +tags out of addresses at providers
+// that ignore them, such as Gmail. Addresses already stored are rewritten at startup; accounts that
+// would then share an address are reported and keep theirs until an admin resolves them.
+func EMAIL_PROVIDER_RULES() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("EMAIL_PROVIDER_RULES")
+}
+
+// USERNAME_PATTERN is the regular expression usernames must match once lowercased. Defaults to 3 to
+// 30 letters, digits, dots, hyphens or underscores, starting with a letter.
+func USERNAME_PATTERN() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("USERNAME_PATTERN")
+}
+
+// USERNAME_RESERVED is a comma-separated list of usernames nobody may register, on top of the
+// built-in ones such as "admin" and "support".
+func USERNAME_RESERVED() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("USERNAME_RESERVED")
+}
+
+// USER_ATTRIBUTES_SCHEMA is the path of a JSON file defining the custom attributes users can have.
+// Without it, no attributes are accepted.
+func USER_ATTRIBUTES_SCHEMA() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("USER_ATTRIBUTES_SCHEMA")
+}
+
+// INVITATION_URL is the page invitation emails link to; the token is appended as ?token=.
+func INVITATION_URL() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("INVITATION_URL")
+}
+
+// INVITATION_LIFETIME is how long an invitation can be accepted, as a Go duration such as "72h". Defaults to 7 days.
+func INVITATION_LIFETIME() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("INVITATION_LIFETIME")
+}
+
+// OIDC_PROVIDERS is the path of a JSON file defining the external identity providers users can sign
+// in with. Without it, social login is off.
+func OIDC_PROVIDERS() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("OIDC_PROVIDERS")
+}
+
+// OIDC_AUTO_LINK set to "true" links a new provider identity to the existing account with the same
+// email address when the provider has verified it. Otherwise such a sign-in is refused until the
+// owner links the provider while signed in. An unverified account with a password is never linked.
+// Only turn it on for providers whose email verification you trust.
+func OIDC_AUTO_LINK() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("OIDC_AUTO_LINK")
+}
+
+// SAML_CONNECTIONS is the path of a JSON file defining the SAML identity providers of enterprise
+// single sign-on. Without it, SAML sign-in is off.
+func SAML_CONNECTIONS() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SAML_CONNECTIONS")
+}
+
+// SAML_BASE_URL is the public URL of the API root, e.g. https://api.example.com/api/v1, that the
+// metadata and Assertion Consumer Service URLs given to identity providers start with.
+func SAML_BASE_URL() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SAML_BASE_URL")
+}
+
+// SAML_SP_KEY is the path of the PEM-encoded RSA private key that signs AuthnRequests and decrypts
+// encrypted assertions.
+func SAML_SP_KEY() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SAML_SP_KEY")
+}
+
+// SAML_SP_CERT is the path of the PEM certificate of SAML_SP_KEY, published in the metadata.
+func SAML_SP_CERT() string {
+	err := loadEnv()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	return os.Getenv("SAML_SP_CERT")
+}
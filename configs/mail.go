@@ -0,0 +1,84 @@
+package configs
+
+import (
+	"Gin/Basics/mailer"
+	"strconv"
+	"sync"
+)
+
+var (
+	mail     mailer.Mailer
+	mailOnce sync.Once
+
+	templates     *mailer.Templates
+	templatesOnce sync.Once
+)
+
+// Mailer returns the transport all email is sent through, built from the MAIL_* and SMTP_* settings.
+// Without them it falls back to the Gmail account in EMAIL and PASSWORD.
+func Mailer() mailer.Mailer {
+	mailOnce.Do(func() {
+		from := MAIL_FROM()
+		if from == "" {
+			from = EMAIL()
+		}
+
+		switch MAIL_TRANSPORT() {
+		case "file":
+			mail = &mailer.FileMailer{Dir: MAIL_DIR(), From: from}
+		case "memory":
+			mail = &mailer.Recorder{}
+		default:
+			mail = smtpMailer(from)
+		}
+	})
+
+	return mail
+}
+
+// SetMailer replaces the transport returned by Mailer, e.g. with a *mailer.Recorder in tests.
+func SetMailer(m mailer.Mailer) {
+	mailOnce.Do(func() {})
+	mail = m
+}
+
+// Templates returns the email templates, with overrides from MAIL_TEMPLATE_DIR.
+func Templates() *mailer.Templates {
+	templatesOnce.Do(func() {
+		templates = mailer.NewTemplates(MAIL_TEMPLATE_DIR())
+	})
+
+	return templates
+}
+
+func smtpMailer(from string) *mailer.SMTPMailer {
+	m := &mailer.SMTPMailer{
+		Host:     SMTP_HOST(),
+		TLS:      SMTP_TLS(),
+		Username: SMTP_USERNAME(),
+		Password: SMTP_PASSWORD(),
+		From:     from,
+	}
+
+	if m.Host == "" {
+		m.Host = "smtp.gmail.com"
+	}
+	if m.TLS == "" {
+		m.TLS = mailer.TLSStartTLS
+	}
+	if m.Username == "" {
+		m.Username = EMAIL()
+		m.Password = PASSWORD()
+	}
+
+	port, err := strconv.Atoi(SMTP_PORT())
+	if err != nil {
+		port = 587
+		if m.TLS == mailer.TLSImplicit {
+			port = 465
+		}
+	}
+	m.Port = port
+
+	return m
+}
@@ -0,0 +1,32 @@
+package configs
+
+import (
+	"Gin/Basics/oidc"
+	"log"
+	"sync"
+)
+
+var (
+	oidcProviders     *oidc.Registry
+	oidcProvidersOnce sync.Once
+)
+
+// OIDCProviders returns the external identity providers loaded from OIDC_PROVIDERS. An unreadable
+// or invalid provider file stops the server.
+func OIDCProviders() *oidc.Registry {
+	oidcProvidersOnce.Do(func() {
+		registry, err := oidc.Load(OIDC_PROVIDERS())
+		if err != nil {
+			log.Fatal(err)
+		}
+		oidcProviders = registry
+	})
+
+	return oidcProviders
+}
+
+// SetOIDCProviders replaces the registry returned by OIDCProviders, e.g. with mock providers in tests.
+func SetOIDCProviders(registry *oidc.Registry) {
+	oidcProvidersOnce.Do(func() {})
+	oidcProviders = registry
+}
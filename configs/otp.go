@@ -0,0 +1,57 @@
+package configs
+
+import (
+	"log"
+	"strconv"
+	"sync"
+)
+
+const (
+	// minOTPPepperLength is the shortest OTP_PEPPER accepted, e.g. the output of `openssl rand -hex 16`.
+	minOTPPepperLength = 32
+
+	// MinOTPLength is the shortest OTP_LENGTH accepted, and the length used when it is unset.
+	MinOTPLength = 6
+)
+
+var (
+	otpPepper     []byte
+	otpPepperOnce sync.Once
+
+	otpLength     int
+	otpLengthOnce sync.Once
+)
+
+// OTPPepper returns the key OTPs are hashed with, from OTP_PEPPER. Codes are short, so without a
+// secret key their hashes could be reversed by trying every code: a missing or short pepper stops
+// the server.
+func OTPPepper() []byte {
+	otpPepperOnce.Do(func() {
+		pepper := OTP_PEPPER()
+		if len(pepper) < minOTPPepperLength {
+			log.Fatalf("OTP_PEPPER must be at least %d characters", minOTPPepperLength)
+		}
+		otpPepper = []byte(pepper)
+	})
+
+	return otpPepper
+}
+
+// OTPLength returns the number of characters in OTPs, from OTP_LENGTH. Verification codes are not
+// limited in attempts, so a length below MinOTPLength, or one that is not a number, stops the server.
+func OTPLength() int {
+	otpLengthOnce.Do(func() {
+		value := OTP_LENGTH()
+		if value == "" {
+			otpLength = MinOTPLength
+			return
+		}
+		length, err := strconv.Atoi(value)
+		if err != nil || length < MinOTPLength {
+			log.Fatalf("OTP_LENGTH must be a number of at least %d", MinOTPLength)
+		}
+		otpLength = length
+	})
+
+	return otpLength
+}
@@ -0,0 +1,43 @@
+package configs
+
+import (
+	"Gin/Basics/samlsp"
+	"log"
+	"sync"
+)
+
+var (
+	samlConnections     *samlsp.Registry
+	samlConnectionsOnce sync.Once
+)
+
+// SAMLConnections returns the SAML connections loaded from SAML_CONNECTIONS. An unreadable or
+// invalid connection file, key or certificate stops the server.
+func SAMLConnections() *samlsp.Registry {
+	samlConnectionsOnce.Do(func() {
+		path := SAML_CONNECTIONS()
+		if path == "" {
+			samlConnections = &samlsp.Registry{}
+			return
+		}
+
+		sp, err := samlsp.LoadServiceProvider(SAML_BASE_URL(), SAML_SP_KEY(), SAML_SP_CERT())
+		if err != nil {
+			log.Fatal(err)
+		}
+		registry, err := samlsp.Load(path, sp)
+		if err != nil {
+			log.Fatal(err)
+		}
+		samlConnections = registry
+	})
+
+	return samlConnections
+}
+
+// SetSAMLConnections replaces the registry returned by SAMLConnections, e.g. with mock identity
+// providers in tests.
+func SetSAMLConnections(registry *samlsp.Registry) {
+	samlConnectionsOnce.Do(func() {})
+	samlConnections = registry
+}
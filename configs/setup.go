@@ -5,22 +5,30 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var CONN *pgx.Conn
+// CONN is a pool rather than a single connection so that request handlers and the outbox workers
+// can use the database at the same time.
+var CONN *pgxpool.Pool
 
 const connectMsg string = "---------------------------------------------------------------------------------------------\nConnected to DB\n---------------------------------------------------------------------------------------------"
 
-func ConnectDB() *pgx.Conn {
+func ConnectDB() *pgxpool.Pool {
 	ctx := context.Background()
 	uri := SQLURI()
 
-	conn, err := pgx.Connect(ctx, uri)
+	conn, err := pgxpool.New(ctx, uri)
 	if err != nil {
 		log.Println(err)
 		return nil
 	}
+	//* The pool connects lazily, so checking that the database is reachable
+	if pingErr := conn.Ping(ctx); pingErr != nil {
+		log.Println(pingErr)
+		conn.Close()
+		return nil
+	}
 	CONN = conn
 
 	fmt.Println(connectMsg)
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/responses"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ^ DeleteAccount :
+//
+//	@Summary		Account deletion route
+//	@Description	Schedules the signed-in user's account for permanent deletion after a grace period (30 days by default) and signs it out everywhere. Signing in again before then cancels the deletion. A confirmation email is sent.
+//	@Tags			user
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		202	{object}	responses.UserResponse_doc	"deletion_scheduled_for"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/me [delete]
+func DeleteAccount(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	grace, parseErr := time.ParseDuration(configs.ACCOUNT_DELETION_GRACE())
+	if parseErr != nil || grace < 0 {
+		grace = model.DefaultAccountDeletionGrace
+	}
+	deleteAt := time.Now().Add(grace)
+
+	//* Marking the account and queueing the confirmation in one transaction
+	queries := db.New(configs.CONN)
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if scheduleErr := qtx.ScheduleUserDeletion(ctx, db.ScheduleUserDeletionParams{
+		ID:                   user.ID,
+		DeletionScheduledFor: pgtype.Timestamptz{Time: deleteAt, Valid: true},
+	}); scheduleErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", scheduleErr.Error())
+		return
+	}
+	if queueErr := model.QueueAccountDeletionScheduled(ctx, qtx, user, deleteAt); queueErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", queueErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditAccountDeletionRequested, map[string]interface{}{"deletion_scheduled_for": deleteAt})
+
+	r.JSON(http.StatusAccepted, responses.UserResponse{Message: "Account scheduled for deletion", Data: map[string]interface{}{"deletion_scheduled_for": deleteAt}})
+}
+
+// ^ ExportAccount :
+//
+//	@Summary		Personal data export route
+//	@Description	Downloads everything stored about the signed-in user as one JSON document: profile, second factors, passkeys, pending email change, sessions (sign-ins and revocations) and audit events. Secrets such as password and code hashes are left out.
+//	@Tags			user
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	map[string]interface{}	"JSON archive"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/me/export [get]
+func ExportAccount(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+	queries := db.New(configs.CONN)
+
+	//* Recording the export first, so that it is part of the archive
+	recordAudit(ctx, queries, r, user.ID, model.AuditAccountExported, nil)
+
+	events, eventsErr := queries.ListAuditEventsByUser(ctx, pgtype.Int8{Int64: user.ID, Valid: true})
+	if eventsErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", eventsErr.Error())
+		return
+	}
+	passkeys, passkeysErr := queries.ListWebAuthnCredentials(ctx, user.ID)
+	if passkeysErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", passkeysErr.Error())
+		return
+	}
+	linked, linkedErr := queries.ListUserIdentities(ctx, user.ID)
+	if linkedErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", linkedErr.Error())
+		return
+	}
+	recoveryCodes, countErr := queries.CountUnusedRecoveryCodes(ctx, user.ID)
+	if countErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", countErr.Error())
+		return
+	}
+
+	auditEvents := make([]map[string]interface{}, 0, len(events))
+	signIns := []map[string]interface{}{}
+	for _, event := range events {
+		auditEvents = append(auditEvents, auditEventResponse(event))
+		if event.Action == model.AuditLogin {
+			signIns = append(signIns, auditEventResponse(event))
+		}
+	}
+	credentials := make([]map[string]interface{}, 0, len(passkeys))
+	for _, row := range passkeys {
+		credentials = append(credentials, webAuthnCredentialResponse(row))
+	}
+	identities := make([]map[string]interface{}, 0, len(linked))
+	for _, row := range linked {
+		identities = append(identities, identityResponse(row))
+	}
+
+	sessions := map[string]interface{}{"sign_ins": signIns}
+	if user.SessionsRevokedAt.Valid {
+		sessions["revoked_at"] = user.SessionsRevokedAt.Time
+	}
+
+	archive := map[string]interface{}{
+		"exported_at": time.Now(),
+		"profile":     profileResponse(user),
+		"mfa": map[string]interface{}{
+			"totp_enabled":          user.MfaEnabled,
+			"sms_enabled":           user.SmsMfaEnabled,
+			"recovery_codes_unused": recoveryCodes,
+		},
+		"passkeys":     credentials,
+		"identities":   identities,
+		"sessions":     sessions,
+		"audit_events": auditEvents,
+	}
+	change, changeErr := queries.GetEmailChange(ctx, user.ID)
+	if changeErr == nil {
+		archive["pending_email_change"] = map[string]interface{}{"new_email": change.NewEmail, "expires_at": change.ExpiresAt.Time}
+	} else if !strings.Contains(changeErr.Error(), "no rows in result set") {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", changeErr.Error())
+		return
+	}
+
+	r.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d.json"`, user.ID))
+	r.JSON(http.StatusOK, archive)
+}
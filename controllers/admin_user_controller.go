@@ -0,0 +1,435 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/responses"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ^ ListUsers :
+//
+//	@Summary		User list route
+//	@Description	Lists users matching the filters, one page at a time. Pass next_cursor from a response as cursor to get the following page with the same filters and order.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			verified		query		bool						false	"Only verified (true) or unverified (false) users"
+//	@Param			status			query		string						false	"Only users with this status: pending, active, disabled, locked or deleted"
+//	@Param			created_after	query		string						false	"Only users created at or after this RFC 3339 time"
+//	@Param			created_before	query		string						false	"Only users created before this RFC 3339 time"
+//	@Param			email			query		string						false	"Only users whose email contains this text, ignoring case"
+//	@Param			sort			query		string						false	"id, created_at or email (default created_at)"
+//	@Param			order			query		string						false	"asc or desc (default desc)"
+//	@Param			limit			query		int							false	"Page size, at most 200 (default 50)"
+//	@Param			cursor			query		string						false	"next_cursor of the previous page"
+//	@Success		200				{object}	responses.UserResponse_doc	"users, next_cursor"
+//	@Failure		400				{object}	responses.ErrorResponse_doc	"Invalid filter, Invalid cursor"
+//	@Failure		401				{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403				{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		500				{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/users [get]
+func ListUsers(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+	var params db.ListUsersParams
+
+	//* Reading the filters
+	if verified := r.Query("verified"); verified != "" {
+		value, parseErr := strconv.ParseBool(verified)
+		if parseErr != nil {
+			respondWithError(r, http.StatusBadRequest, "invalid_filter", "verified")
+			return
+		}
+		params.Verified = pgtype.Bool{Bool: value, Valid: true}
+	}
+	if status := r.Query("status"); status != "" {
+		switch db.AccountStatus(status) {
+		case db.AccountStatusPending, db.AccountStatusActive, db.AccountStatusDisabled, db.AccountStatusLocked, db.AccountStatusDeleted:
+		default:
+			respondWithError(r, http.StatusBadRequest, "invalid_filter", "status")
+			return
+		}
+		params.Status = db.NullAccountStatus{AccountStatus: db.AccountStatus(status), Valid: true}
+	}
+	for name, field := range map[string]*pgtype.Timestamptz{"created_after": &params.CreatedAfter, "created_before": &params.CreatedBefore} {
+		if value := r.Query(name); value != "" {
+			parsed, parseErr := time.Parse(time.RFC3339, value)
+			if parseErr != nil {
+				respondWithError(r, http.StatusBadRequest, "invalid_filter", name)
+				return
+			}
+			*field = pgtype.Timestamptz{Time: parsed, Valid: true}
+		}
+	}
+	if email := strings.TrimSpace(r.Query("email")); email != "" {
+		params.EmailContains = pgtype.Text{String: model.EscapeLike(email), Valid: true}
+	}
+
+	//* Reading the order and page size
+	params.SortBy = r.DefaultQuery("sort", model.UserSortCreatedAt)
+	switch params.SortBy {
+	case model.UserSortID, model.UserSortCreatedAt, model.UserSortEmail:
+	default:
+		respondWithError(r, http.StatusBadRequest, "invalid_filter", "sort")
+		return
+	}
+	switch r.DefaultQuery("order", "desc") {
+	case "asc":
+	case "desc":
+		params.Descending = true
+	default:
+		respondWithError(r, http.StatusBadRequest, "invalid_filter", "order")
+		return
+	}
+	limit := model.DefaultUserPageSize
+	if value := r.Query("limit"); value != "" {
+		parsed, parseErr := strconv.Atoi(value)
+		if parseErr != nil || parsed < 1 || parsed > model.MaxUserPageSize {
+			respondWithError(r, http.StatusBadRequest, "invalid_filter", "limit")
+			return
+		}
+		limit = parsed
+	}
+	//* One extra row tells whether there is a next page
+	params.PageSize = int32(limit + 1)
+
+	//* Continuing after the cursor, which must come from a page in the same order
+	if value := r.Query("cursor"); value != "" {
+		cursor, decodeErr := model.DecodeUserCursor(value)
+		if decodeErr != nil || cursor.Sort != params.SortBy || cursor.Descending != params.Descending {
+			respondWithError(r, http.StatusBadRequest, "invalid_cursor")
+			return
+		}
+		params.CursorID = pgtype.Int8{Int64: cursor.ID, Valid: true}
+		params.CursorCreatedAt = pgtype.Timestamptz{Time: cursor.CreatedAt, Valid: true}
+		params.CursorEmail = pgtype.Text{String: cursor.Email, Valid: true}
+	}
+
+	rows, listErr := queries.ListUsers(ctx, params)
+	if listErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", listErr.Error())
+		return
+	}
+
+	data := map[string]interface{}{}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		data["next_cursor"] = model.UserCursor{
+			Sort:       params.SortBy,
+			Descending: params.Descending,
+			ID:         last.ID,
+			CreatedAt:  last.CreatedAt.Time,
+			Email:      last.Email,
+		}.Encode()
+	}
+	users := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, adminUserResponse(row))
+	}
+	data["users"] = users
+
+	recordAdminAudit(ctx, queries, r, model.AuditAdminUsersListed, map[string]interface{}{"query": r.Request.URL.RawQuery, "results": len(users)})
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: data})
+}
+
+// ^ GetUser :
+//
+//	@Summary		User detail route
+//	@Description	Returns a user with their account status.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"User ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"user"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"User not found"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/users/{id} [get]
+func GetUser(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+
+	user, found := findTargetUser(ctx, r, queries)
+	if !found {
+		return
+	}
+	recordAudit(ctx, queries, r, user.ID, model.AuditAdminUserViewed, nil)
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"user": adminUserResponse(user)}})
+}
+
+// ^ VerifyUser :
+//
+//	@Summary		User verification route
+//	@Description	Marks a user's email address as verified without an OTP.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"User ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"user"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"User not found"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/users/{id}/verify [post]
+func VerifyUser(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+
+	id, ok := targetUserID(r)
+	if !ok {
+		return
+	}
+	user, verifyErr := queries.VerifyUserByID(ctx, id)
+	if verifyErr != nil {
+		respondWithTargetUserError(r, verifyErr)
+		return
+	}
+	recordAudit(ctx, queries, r, user.ID, model.AuditAdminUserVerified, nil)
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"user": adminUserResponse(user)}})
+}
+
+// ^ DisableUser :
+//
+//	@Summary		User disable route
+//	@Description	Disables a user: their sessions end and they cannot sign in until the account is enabled again.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"User ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"user"
+//	@Failure		400	{object}	responses.ErrorResponse_doc	"Admins cannot disable their own account"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"User not found"
+//	@Failure		409	{object}	responses.ErrorResponse_doc	"Account deleted"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/users/{id}/disable [post]
+func DisableUser(r *gin.Context) {
+	setUserStatus(r, db.AccountStatusDisabled, model.AuditAdminUserDisabled)
+}
+
+// ^ LockUser :
+//
+//	@Summary		User lock route
+//	@Description	Locks a user out temporarily, for example while a compromise is investigated: their sessions end and they cannot sign in until the account is enabled again.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"User ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"user"
+//	@Failure		400	{object}	responses.ErrorResponse_doc	"Admins cannot lock their own account"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"User not found"
+//	@Failure		409	{object}	responses.ErrorResponse_doc	"Account deleted"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/users/{id}/lock [post]
+func LockUser(r *gin.Context) {
+	setUserStatus(r, db.AccountStatusLocked, model.AuditAdminUserLocked)
+}
+
+// ^ EnableUser :
+//
+//	@Summary		User enable route
+//	@Description	Lets a disabled or locked user sign in again. Users who have not verified their email address yet return to pending.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"User ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"user"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"User not found"
+//	@Failure		409	{object}	responses.ErrorResponse_doc	"Account deleted"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/users/{id}/enable [post]
+func EnableUser(r *gin.Context) {
+	setUserStatus(r, db.AccountStatusActive, model.AuditAdminUserEnabled)
+}
+
+func setUserStatus(r *gin.Context, status db.AccountStatus, action string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+
+	user, found := findTargetUser(ctx, r, queries)
+	if !found {
+		return
+	}
+	//* Keeping at least the acting admin able to sign in
+	if status != db.AccountStatusActive && user.ID == middleware.CurrentUser(r).ID {
+		respondWithError(r, http.StatusBadRequest, "cannot_modify_own_account")
+		return
+	}
+	//* Accounts pending deletion are restored only by their owner signing in
+	if user.Status == db.AccountStatusDeleted {
+		respondWithError(r, http.StatusConflict, "account_deleted")
+		return
+	}
+
+	user, updateErr := queries.SetUserStatus(ctx, db.SetUserStatusParams{Status: status, ID: user.ID})
+	if updateErr != nil {
+		respondWithTargetUserError(r, updateErr)
+		return
+	}
+	recordAudit(ctx, queries, r, user.ID, action, nil)
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"user": adminUserResponse(user)}})
+}
+
+// ^ LogoutUser :
+//
+//	@Summary		User sign-out route
+//	@Description	Ends all of a user's sessions. They can sign in again straight away.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"User ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"Sessions revoked"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"User not found"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/users/{id}/logout [post]
+func LogoutUser(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+
+	user, found := findTargetUser(ctx, r, queries)
+	if !found {
+		return
+	}
+	if revokeErr := queries.RevokeUserSessions(ctx, user.ID); revokeErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", revokeErr.Error())
+		return
+	}
+	recordAudit(ctx, queries, r, user.ID, model.AuditAdminSessionsRevoked, nil)
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Sessions revoked"})
+}
+
+// ^ DeleteUser :
+//
+//	@Summary		User delete route
+//	@Description	Deletes a user and their data immediately, without the grace period of self-service deletion. Their audit events are kept with the IP addresses, user agents and details removed.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"User ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"User deleted"
+//	@Failure		400	{object}	responses.ErrorResponse_doc	"Admins cannot delete their own account"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"User not found"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/users/{id} [delete]
+func DeleteUser(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+
+	user, found := findTargetUser(ctx, r, queries)
+	if !found {
+		return
+	}
+	if user.ID == middleware.CurrentUser(r).ID {
+		respondWithError(r, http.StatusBadRequest, "cannot_modify_own_account")
+		return
+	}
+
+	//* The account's audit events are kept, without what identifies the person
+	if scrubErr := queries.ScrubAuditEvents(ctx, []int64{user.ID}); scrubErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", scrubErr.Error())
+		return
+	}
+	deleted, deleteErr := queries.DeleteUser(ctx, user.ID)
+	if deleteErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", deleteErr.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(r, http.StatusNotFound, "account_not_found")
+		return
+	}
+	//* The account is gone, so this event is kept apart from it
+	recordAdminAudit(ctx, queries, r, model.AuditAdminUserDeleted, map[string]interface{}{"user_id": user.ID, "email": user.Email})
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "User deleted"})
+}
+
+// targetUserID parses the :id path parameter, answering with 404 when it is not a user id.
+func targetUserID(r *gin.Context) (int64, bool) {
+	id, parseErr := strconv.ParseInt(r.Param("id"), 10, 64)
+	if parseErr != nil {
+		respondWithError(r, http.StatusNotFound, "account_not_found")
+		return 0, false
+	}
+
+	return id, true
+}
+
+// findTargetUser loads the user named by the :id path parameter, answering with an error when
+// there is none.
+func findTargetUser(ctx context.Context, r *gin.Context, queries *db.Queries) (db.User, bool) {
+	id, ok := targetUserID(r)
+	if !ok {
+		return db.User{}, false
+	}
+	user, userErr := queries.GetUserByID(ctx, id)
+	if userErr != nil {
+		respondWithTargetUserError(r, userErr)
+		return db.User{}, false
+	}
+
+	return user, true
+}
+
+func respondWithTargetUserError(r *gin.Context, err error) {
+	if strings.Contains(err.Error(), "no rows in result set") {
+		respondWithError(r, http.StatusNotFound, "account_not_found")
+		return
+	}
+	respondWithError(r, http.StatusInternalServerError, "internal_error", err.Error())
+}
+
+// adminUserResponse returns the profile of a user together with the account state only admins see.
+func adminUserResponse(user db.User) map[string]interface{} {
+	response := profileResponse(user)
+	response["status"] = user.Status
+	response["created_at"] = user.CreatedAt.Time
+	response["updated_at"] = user.UpdatedAt.Time
+	response["disabled"] = user.DisabledAt.Valid
+	for name, value := range map[string]pgtype.Timestamptz{
+		"verified_at":            user.VerifiedAt,
+		"last_login_at":          user.LastLoginAt,
+		"disabled_at":            user.DisabledAt,
+		"sessions_revoked_at":    user.SessionsRevokedAt,
+		"deletion_scheduled_for": user.DeletionScheduledFor,
+		"deleted_at":             user.DeletedAt,
+	} {
+		if value.Valid {
+			response[name] = value.Time
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// recordAudit stores an audit event about the account userID, with the signed-in user (or, before
+// sign-in, the account itself) as the actor. A failure is reported to the admins but does not fail
+// the request, which has usually already made its change.
+func recordAudit(ctx context.Context, queries *db.Queries, r *gin.Context, userID int64, action string, details map[string]interface{}) {
+	insertAudit(ctx, queries, r, pgtype.Int8{Int64: userID, Valid: true}, action, details)
+}
+
+// recordAdminAudit stores an audit event made by the signed-in admin that is not tied to an
+// existing account, such as a search or a deletion.
+func recordAdminAudit(ctx context.Context, queries *db.Queries, r *gin.Context, action string, details map[string]interface{}) {
+	insertAudit(ctx, queries, r, pgtype.Int8{}, action, details)
+}
+
+func insertAudit(ctx context.Context, queries *db.Queries, r *gin.Context, userID pgtype.Int8, action string, details map[string]interface{}) {
+	actorID := userID
+	if actor, ok := r.Get("user"); ok {
+		actorID = pgtype.Int8{Int64: actor.(db.User).ID, Valid: true}
+	}
+
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	encoded, encodeErr := json.Marshal(details)
+	if encodeErr != nil {
+		configs.Alerts().Report(fmt.Errorf("audit: %s: %w", action, encodeErr))
+		return
+	}
+
+	if insertErr := queries.CreateAuditEvent(ctx, db.CreateAuditEventParams{
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    action,
+		Ip:        r.ClientIP(),
+		UserAgent: r.Request.UserAgent(),
+		Details:   encoded,
+	}); insertErr != nil {
+		configs.Alerts().Report(fmt.Errorf("audit: %s: %w", action, insertErr))
+	}
+}
+
+// auditEventResponse returns an audit event as shown to the account's owner.
+func auditEventResponse(row db.AuditEvent) map[string]interface{} {
+	event := map[string]interface{}{
+		"id":         row.ID,
+		"action":     row.Action,
+		"ip":         row.Ip,
+		"user_agent": row.UserAgent,
+		"details":    json.RawMessage(row.Details),
+		"created_at": row.CreatedAt.Time,
+	}
+	if row.ActorID.Valid {
+		event["actor_id"] = row.ActorID.Int64
+	}
+
+	return event
+}
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/responses"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ^ RequestEmailChange :
+//
+//	@Summary		Email change request route
+//	@Description	Starts changing the signed-in user's email address. A confirmation code is sent to the new address and a notice to the current one; the address only changes once the code is confirmed.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.EmailChange			true	"New email address"
+//	@Success		202		{object}	responses.UserResponse_doc	"Confirmation code has been sent to the new email address"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid Email, This is already your email address"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		409		{object}	responses.ErrorResponse_doc	"Email already registered"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/email [put]
+func RequestEmailChange(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.EmailChange
+	user := middleware.CurrentUser(r)
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating the new address
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_email")
+		return
+	}
+	email, emailErr := model.NormalizeEmail(req.Email)
+	if emailErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_email")
+		return
+	}
+	if strings.EqualFold(email, user.Email) {
+		respondWithError(r, http.StatusBadRequest, "same_email")
+		return
+	}
+
+	//* Checking that no account uses the new address yet
+	queries := db.New(configs.CONN)
+	if _, existingErr := queries.GetUserByEmail(ctx, email); existingErr == nil {
+		respondWithError(r, http.StatusConflict, "email_taken")
+		return
+	} else if !strings.Contains(existingErr.Error(), "no rows in result set") {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", existingErr.Error())
+		return
+	}
+
+	//* Generating the confirmation code
+	var pending model.User
+	if genOtpErr := pending.GenerateOTP(); genOtpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", genOtpErr.Error())
+		return
+	}
+
+	//* Storing the pending change and queueing both emails in one transaction
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if upsertErr := qtx.UpsertEmailChange(ctx, db.UpsertEmailChangeParams{
+		UserID:    user.ID,
+		NewEmail:  email,
+		CodeHash:  model.HashOTP(pending.OTP),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(model.EmailChangeLifetime), Valid: true},
+	}); upsertErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", upsertErr.Error())
+		return
+	}
+	if queueErr := model.QueueEmailChangeCode(ctx, qtx, email, user.Locale, pending.OTP); queueErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", queueErr.Error())
+		return
+	}
+	if queueErr := model.QueueEmailChangeRequested(ctx, qtx, user.Email, user.Locale, email); queueErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", queueErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditEmailChangeRequested, map[string]interface{}{"new_email": email})
+
+	r.JSON(http.StatusAccepted, responses.UserResponse{Message: "Confirmation code has been sent to the new email address"})
+}
+
+// ^ ConfirmEmailChange :
+//
+//	@Summary		Email change confirmation route
+//	@Description	Switches the signed-in user's email address to the pending one after checking the code sent to it. With revoke_sessions set, every other access token of the user stops working and a new token is returned.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.EmailChangeConfirm	true	"Code from the email and whether to sign out other sessions"
+//	@Success		200		{object}	responses.UserResponse_doc	"Email address changed, with a token when sessions were revoked"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, No email change pending"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired token, Invalid or expired code"
+//	@Failure		409		{object}	responses.ErrorResponse_doc	"Email already registered"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/email/confirm [post]
+func ConfirmEmailChange(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.EmailChangeConfirm
+	user := middleware.CurrentUser(r)
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	if _, changeErr := queries.GetEmailChange(ctx, user.ID); changeErr != nil {
+		if strings.Contains(changeErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusBadRequest, "no_email_change_pending")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", changeErr.Error())
+		return
+	}
+
+	//* Spending the change, swapping the address, which the unique index re-checks, and queueing the notice in one transaction
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	//* Checking the code; too many wrong guesses cancel the change
+	change, consumeErr := qtx.ConsumeEmailChange(ctx, db.ConsumeEmailChangeParams{
+		UserID:      user.ID,
+		CodeHash:    model.HashOTP(req.Code),
+		MaxAttempts: model.EmailChangeMaxAttempts,
+	})
+	if consumeErr != nil {
+		if !strings.Contains(consumeErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", consumeErr.Error())
+			return
+		}
+		tx.Rollback(ctx)
+		attempts, attemptsErr := queries.IncrementEmailChangeAttempts(ctx, user.ID)
+		if attemptsErr != nil && !strings.Contains(attemptsErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", attemptsErr.Error())
+			return
+		}
+		if attempts >= model.EmailChangeMaxAttempts {
+			if deleteErr := queries.DeleteEmailChange(ctx, user.ID); deleteErr != nil {
+				respondWithError(r, http.StatusInternalServerError, "internal_error", deleteErr.Error())
+				return
+			}
+		}
+		respondWithError(r, http.StatusUnauthorized, "invalid_or_expired_code")
+		return
+	}
+
+	if updateErr := qtx.UpdateUserEmail(ctx, db.UpdateUserEmailParams{ID: user.ID, Email: change.NewEmail}); updateErr != nil {
+		if strings.HasPrefix(updateErr.Error(), "ERROR: duplicate key") {
+			respondWithError(r, http.StatusConflict, "email_taken")
+			return
+		} else if strings.Contains(updateErr.Error(), "\"valid_email\"") {
+			respondWithError(r, http.StatusBadRequest, "invalid_email")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", updateErr.Error())
+		return
+	}
+	if req.RevokeSessions {
+		if revokeErr := qtx.RevokeUserSessions(ctx, user.ID); revokeErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", revokeErr.Error())
+			return
+		}
+	}
+	if queueErr := model.QueueEmailChanged(ctx, qtx, user.Email, user.Locale, change.NewEmail); queueErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", queueErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditEmailChanged, map[string]interface{}{"old_email": user.Email, "new_email": change.NewEmail})
+	if req.RevokeSessions {
+		recordAudit(ctx, queries, r, user.ID, model.AuditSessionsRevoked, nil)
+	}
+
+	if !req.RevokeSessions {
+		r.JSON(http.StatusOK, responses.UserResponse{Message: "Email address changed"})
+		return
+	}
+
+	//* Replacing the caller's token, which was revoked along with the others, without refreshing its auth_time
+	token, genJWTErr := auth.ReissueJWT(user.ID, r.MustGet("claims").(jwt.MapClaims), model.AttributeClaims(user))
+	if genJWTErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", genJWTErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Email address changed", Data: map[string]interface{}{"token": token}})
+}
@@ -0,0 +1,410 @@
+package controller
+
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/responses"
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ^ LinkProvider :
+//
+//	@Summary		Identity link route
+//	@Description	Starts linking an account at an identity provider to the signed-in user. Send the browser to url; the provider's callback route then links the identity. The browser must keep the cookie set by this response.
+//	@Tags			user
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			provider	path		string						true	"Provider name"
+//	@Success		200			{object}	responses.UserResponse_doc	"url"
+//	@Failure		401			{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		404			{object}	responses.ErrorResponse_doc	"Unknown identity provider"
+//	@Failure		500			{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/providers/{provider}/link [post]
+func LinkProvider(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	provider, found := configs.OIDCProviders().Get(r.Param("provider"))
+	if !found {
+		respondWithError(r, http.StatusNotFound, "provider_not_found")
+		return
+	}
+
+	authURL, ok := startProviderSignIn(ctx, r, provider, pgtype.Int8{Int64: user.ID, Valid: true})
+	if !ok {
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"url": authURL}})
+}
+
+// ^ ListIdentities :
+//
+//	@Summary		Identity list route
+//	@Description	Lists the identity provider accounts linked to the signed-in user.
+//	@Tags			user
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	responses.UserResponse_doc	"identities"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/identities [get]
+func ListIdentities(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	rows, listErr := db.New(configs.CONN).ListUserIdentities(ctx, user.ID)
+	if listErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", listErr.Error())
+		return
+	}
+
+	identities := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		identities = append(identities, identityResponse(row))
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"identities": identities}})
+}
+
+// ^ UnlinkIdentity :
+//
+//	@Summary		Identity unlink route
+//	@Description	Unlinks an identity provider account from the signed-in user. The last way to sign in cannot be removed.
+//	@Tags			user
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"Identity ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"Identity unlinked"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"Identity not found"
+//	@Failure		409	{object}	responses.ErrorResponse_doc	"This is the last way to sign in to the account"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/identities/{id} [delete]
+func UnlinkIdentity(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	id, parseErr := strconv.ParseInt(r.Param("id"), 10, 64)
+	if parseErr != nil {
+		respondWithError(r, http.StatusNotFound, "identity_not_found")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if _, lockErr := qtx.LockUserForUpdate(ctx, user.ID); lockErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", lockErr.Error())
+		return
+	}
+	identity, deleteErr := qtx.DeleteUserIdentity(ctx, db.DeleteUserIdentityParams{ID: id, UserID: user.ID})
+	if deleteErr != nil {
+		if strings.Contains(deleteErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusNotFound, "identity_not_found")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", deleteErr.Error())
+		return
+	}
+	if ok, status, code, detail := checkSignInMethodLeft(ctx, qtx, user); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditIdentityUnlinked, map[string]interface{}{"provider": identity.Provider})
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Identity unlinked"})
+}
+
+// linkIdentity finishes a link started at LinkProvider, linking identity to the user with userID.
+func linkIdentity(ctx context.Context, r *gin.Context, queries *db.Queries, userID int64, identity externalIdentity) {
+	user, userErr := queries.GetUserByID(ctx, userID)
+	if userErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", userErr.Error())
+		return
+	}
+	if statusCode, code, blocked := middleware.AccountStatusError(user.Status); blocked {
+		respondWithError(r, statusCode, code)
+		return
+	}
+
+	//* An identity signs in to one account only
+	linked, linkedErr := queries.GetUserIdentity(ctx, db.GetUserIdentityParams{Provider: identity.Provider, Subject: identity.Subject})
+	if linkedErr == nil {
+		if linked.UserID != user.ID {
+			respondWithError(r, http.StatusConflict, "identity_in_use")
+			return
+		}
+		r.JSON(http.StatusOK, responses.UserResponse{Message: "Identity linked", Data: map[string]interface{}{"identity": identityResponse(linked)}})
+		return
+	}
+	if !strings.Contains(linkedErr.Error(), "no rows in result set") {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", linkedErr.Error())
+		return
+	}
+
+	created, insertDBErr := queries.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if insertDBErr != nil {
+		if strings.HasPrefix(insertDBErr.Error(), "ERROR: duplicate key") {
+			respondWithError(r, http.StatusConflict, "identity_in_use")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", insertDBErr.Error())
+		return
+	}
+	recordAudit(ctx, queries, r, user.ID, model.AuditIdentityLinked, map[string]interface{}{"provider": identity.Provider})
+
+	r.JSON(http.StatusCreated, responses.UserResponse{Message: "Identity linked", Data: map[string]interface{}{"identity": identityResponse(created)}})
+}
+
+// checkSignInMethodLeft fails when user, after losing a sign-in method in queries' transaction, has
+// no way left to sign in: no password, linked identity or passkey, and magic links are off. The
+// transaction must hold LockUserForUpdate so that concurrent removals cannot both pass.
+func checkSignInMethodLeft(ctx context.Context, queries *db.Queries, user db.User) (bool, int, string, string) {
+	if user.Password != "" || configs.MAGIC_LINK_ENABLED() == "true" {
+		return true, 0, "", ""
+	}
+
+	identities, countErr := queries.CountUserIdentities(ctx, user.ID)
+	if countErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", countErr.Error()
+	}
+	passkeys, countErr := queries.CountWebAuthnCredentials(ctx, user.ID)
+	if countErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", countErr.Error()
+	}
+	if identities+passkeys == 0 {
+		return false, http.StatusConflict, "last_login_method", ""
+	}
+
+	return true, 0, "", ""
+}
+
+func identityResponse(row db.UserIdentity) map[string]interface{} {
+	identity := map[string]interface{}{
+		"id":         row.ID,
+		"provider":   row.Provider,
+		"email":      row.Email,
+		"created_at": row.CreatedAt.Time,
+	}
+	if row.LastUsedAt.Valid {
+		identity["last_used_at"] = row.LastUsedAt.Time
+	}
+
+	return identity
+}
+
+// externalIdentity is a user as reported by an OIDC, OAuth 2.0 or SAML identity provider. The
+// profile fields fill the account when one is created for the identity.
+type externalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+
+	Name       string
+	Username   string
+	Phone      string
+	Locale     string
+	Attributes map[string]interface{}
+}
+
+// signInWithIdentity finishes a sign-in through an identity provider and answers like login. The
+// owner of a linked identity is signed in. Otherwise a verified email address of an existing account
+// links the identity to it when autoLink is set, and a new address gets a new account when
+// provision is set.
+func signInWithIdentity(ctx context.Context, r *gin.Context, queries *db.Queries, identity externalIdentity, autoLink bool, provision bool) {
+	amr := []string{auth.AMRFederated}
+
+	//* Signing in the owner of a linked identity
+	linked, linkedErr := queries.GetUserIdentity(ctx, db.GetUserIdentityParams{Provider: identity.Provider, Subject: identity.Subject})
+	if linkedErr == nil {
+		user, userErr := queries.GetUserByID(ctx, linked.UserID)
+		if userErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", userErr.Error())
+			return
+		}
+		if touchErr := queries.TouchUserIdentity(ctx, db.TouchUserIdentityParams{ID: linked.ID, Email: identity.Email}); touchErr != nil {
+			log.Println(touchErr)
+		}
+		respondWithLogin(ctx, r, queries, user, amr)
+		return
+	}
+	if !strings.Contains(linkedErr.Error(), "no rows in result set") {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", linkedErr.Error())
+		return
+	}
+
+	//* Only an address the provider has verified may stand for an account
+	if identity.Email == "" || !identity.EmailVerified {
+		respondWithError(r, http.StatusForbidden, "provider_email_unverified")
+		return
+	}
+	email, emailErr := model.NormalizeEmail(identity.Email)
+	if emailErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_email")
+		return
+	}
+
+	user, userErr := queries.GetUserByEmail(ctx, email)
+	if userErr == nil {
+		//* Without auto-linking, the owner has to sign in another way and link the provider
+		if !autoLink {
+			respondWithError(r, http.StatusConflict, "identity_not_linked")
+			return
+		}
+		if ok, status, code, detail := autoLinkIdentity(ctx, r, queries, &user, identity); !ok {
+			respondWithError(r, status, code, detail)
+			return
+		}
+		respondWithLogin(ctx, r, queries, user, amr)
+		return
+	}
+	if !strings.Contains(userErr.Error(), "no rows in result set") {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", userErr.Error())
+		return
+	}
+
+	if !provision {
+		respondWithError(r, http.StatusForbidden, "user_not_provisioned")
+		return
+	}
+	created, ok, status, code, detail := createExternalUser(ctx, r, queries, identity, email)
+	if !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+	recordAudit(ctx, queries, r, created.ID, model.AuditRegistered, map[string]interface{}{"provider": identity.Provider})
+
+	respondWithLogin(ctx, r, queries, created, amr)
+}
+
+// autoLinkIdentity links identity to user, the owner of the same verified email address. An
+// unverified account with a password is refused: whoever registered it never proved they own the
+// address, and linking would let their password into the provider user's account.
+func autoLinkIdentity(ctx context.Context, r *gin.Context, queries *db.Queries, user *db.User, identity externalIdentity) (bool, int, string, string) {
+	if !user.Isverified && user.Password != "" {
+		return false, http.StatusConflict, "unverified_account_exists", ""
+	}
+
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", txErr.Error()
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if _, linkErr := qtx.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); linkErr != nil {
+		//* A concurrent sign-in with the same identity linked it first
+		if strings.HasPrefix(linkErr.Error(), "ERROR: duplicate key") {
+			return false, http.StatusConflict, "identity_in_use", ""
+		}
+		return false, http.StatusInternalServerError, "internal_error", linkErr.Error()
+	}
+
+	//* The provider's verification proves ownership of the address
+	verified := *user
+	if !user.Isverified {
+		var verifyErr error
+		verified, verifyErr = qtx.VerifyUserByID(ctx, user.ID)
+		if verifyErr != nil {
+			return false, http.StatusInternalServerError, "internal_error", verifyErr.Error()
+		}
+	}
+
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", commitErr.Error()
+	}
+	*user = verified
+	recordAudit(ctx, queries, r, user.ID, model.AuditIdentityLinked, map[string]interface{}{"provider": identity.Provider, "automatic": true})
+
+	return true, 0, "", ""
+}
+
+// createExternalUser creates the verified account of a user who first signs in through a provider,
+// with identity linked to it. The account has no password.
+func createExternalUser(ctx context.Context, r *gin.Context, queries *db.Queries, identity externalIdentity, email string) (db.User, bool, int, string, string) {
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	params := db.CreateUserParams{Name: name, Email: email, Phone: identity.Phone}
+	if ok, status, code, detail := checkSignupFields(&params, identity.Username, identity.Attributes); !ok {
+		return db.User{}, false, status, code, detail
+	}
+
+	params.OtpChannel = model.OTPChannelEmail
+	params.Locale = model.PreferredLocale(identity.Locale, r.GetHeader("Accept-Language"))
+
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		return db.User{}, false, http.StatusInternalServerError, "internal_error", txErr.Error()
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	created, insertDBErr := qtx.CreateUser(ctx, params)
+	if insertDBErr != nil {
+		if strings.Contains(insertDBErr.Error(), "\"users_username_lower_key\"") {
+			return db.User{}, false, http.StatusConflict, "username_taken", ""
+		} else if strings.HasPrefix(insertDBErr.Error(), "ERROR: duplicate key") {
+			return db.User{}, false, http.StatusConflict, "user_exists", ""
+		} else if strings.Contains(insertDBErr.Error(), "\"valid_phone\"") {
+			return db.User{}, false, http.StatusBadRequest, "invalid_phone", ""
+		}
+		return db.User{}, false, http.StatusInternalServerError, "insert_failed", insertDBErr.Error()
+	}
+	user, verifyErr := qtx.VerifyUserByID(ctx, created.ID)
+	if verifyErr != nil {
+		return db.User{}, false, http.StatusInternalServerError, "internal_error", verifyErr.Error()
+	}
+	if _, linkErr := qtx.CreateUserIdentity(ctx, db.CreateUserIdentityParams{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); linkErr != nil {
+		return db.User{}, false, http.StatusInternalServerError, "internal_error", linkErr.Error()
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		return db.User{}, false, http.StatusInternalServerError, "internal_error", commitErr.Error()
+	}
+
+	return user, true, 0, "", ""
+}
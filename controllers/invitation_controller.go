@@ -0,0 +1,445 @@
+package controller
+
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/mailer"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/responses"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const invitationPageSize = 50
+
+// ^ CreateInvitation :
+//
+//	@Summary		Invitation create route
+//	@Description	Emails an invitation with a single-use link. Accepting it creates a verified account without an OTP.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.InvitationCreate		true	"Invitee's email, and optionally their name and locale"
+//	@Success		201		{object}	responses.UserResponse_doc	"invitation"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid Email, Invalid locale"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403		{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		409		{object}	responses.ErrorResponse_doc	"Email already registered, An open invitation already exists for this email"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/invitations [post]
+func CreateInvitation(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.InvitationCreate
+	admin := middleware.CurrentUser(r)
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_email")
+		return
+	}
+	email, emailErr := model.NormalizeEmail(req.Email)
+	if emailErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_email")
+		return
+	}
+	locale := mailer.DefaultLocale
+	if req.Locale != "" {
+		canonical, ok := model.CanonicalLocale(req.Locale)
+		if !ok {
+			respondWithError(r, http.StatusBadRequest, "invalid_locale")
+			return
+		}
+		locale = canonical
+	}
+
+	//* Inviting an address that already has an account would only confuse its owner
+	queries := db.New(configs.CONN)
+	if _, existingErr := queries.GetUserByEmail(ctx, email); existingErr == nil {
+		respondWithError(r, http.StatusConflict, "email_taken")
+		return
+	} else if !strings.Contains(existingErr.Error(), "no rows in result set") {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", existingErr.Error())
+		return
+	}
+
+	token, tokenHash, tokenErr := model.GenerateInvitationToken()
+	if tokenErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", tokenErr.Error())
+		return
+	}
+
+	//* Storing the invitation and queueing its email in one transaction
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	invitation, insertErr := qtx.CreateInvitation(ctx, db.CreateInvitationParams{
+		Email:     email,
+		Name:      strings.TrimSpace(req.Name),
+		Locale:    locale,
+		TokenHash: tokenHash,
+		InvitedBy: pgtype.Int8{Int64: admin.ID, Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(invitationLifetime()), Valid: true},
+	})
+	if insertErr != nil {
+		if strings.HasPrefix(insertErr.Error(), "ERROR: duplicate key") {
+			respondWithError(r, http.StatusConflict, "invitation_exists")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", insertErr.Error())
+		return
+	}
+	if ok, status, code, detail := queueInvitation(ctx, qtx, invitation, admin, token); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAdminAudit(ctx, queries, r, model.AuditAdminInvitationCreated, map[string]interface{}{"invitation_id": invitation.ID, "email": invitation.Email})
+
+	r.JSON(http.StatusCreated, responses.UserResponse{Message: "Invitation sent", Data: map[string]interface{}{"invitation": invitationResponse(invitation)}})
+}
+
+// ^ ListInvitations :
+//
+//	@Summary		Invitation list route
+//	@Description	Lists invitations by state, newest first.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			status	query		string						false	"pending, expired, accepted, revoked or all (default pending)"
+//	@Param			offset	query		int							false	"Number of invitations to skip"
+//	@Success		200		{object}	responses.UserResponse_doc	"invitations"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid status"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403		{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/invitations [get]
+func ListInvitations(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	status := r.DefaultQuery("status", model.InvitationPending)
+	switch status {
+	case model.InvitationPending, model.InvitationExpired, model.InvitationAccepted, model.InvitationRevoked, "all":
+	default:
+		respondWithError(r, http.StatusBadRequest, "invalid_status")
+		return
+	}
+	offset, _ := strconv.Atoi(r.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, listErr := db.New(configs.CONN).ListInvitations(ctx, db.ListInvitationsParams{
+		Status: status,
+		Limit:  invitationPageSize,
+		Offset: int32(offset),
+	})
+	if listErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", listErr.Error())
+		return
+	}
+
+	invitations := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		invitations = append(invitations, invitationResponse(row))
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"invitations": invitations}})
+}
+
+// ^ ResendInvitation :
+//
+//	@Summary		Invitation resend route
+//	@Description	Emails a new link for an invitation that has not been accepted or revoked, restarting its lifetime. Earlier links stop working.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"Invitation ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"invitation"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"Open invitation not found"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/invitations/{id}/resend [post]
+func ResendInvitation(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	admin := middleware.CurrentUser(r)
+
+	id, parseErr := strconv.ParseInt(r.Param("id"), 10, 64)
+	if parseErr != nil {
+		respondWithError(r, http.StatusNotFound, "invitation_not_found")
+		return
+	}
+	token, tokenHash, tokenErr := model.GenerateInvitationToken()
+	if tokenErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", tokenErr.Error())
+		return
+	}
+
+	//* Replacing the token and queueing the new link in one transaction
+	queries := db.New(configs.CONN)
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	invitation, renewErr := qtx.RenewInvitation(ctx, db.RenewInvitationParams{
+		ID:        id,
+		TokenHash: tokenHash,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(invitationLifetime()), Valid: true},
+	})
+	if renewErr != nil {
+		if strings.Contains(renewErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusNotFound, "invitation_not_found")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", renewErr.Error())
+		return
+	}
+	if ok, status, code, detail := queueInvitation(ctx, qtx, invitation, admin, token); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAdminAudit(ctx, queries, r, model.AuditAdminInvitationResent, map[string]interface{}{"invitation_id": invitation.ID, "email": invitation.Email})
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Invitation sent", Data: map[string]interface{}{"invitation": invitationResponse(invitation)}})
+}
+
+// ^ RevokeInvitation :
+//
+//	@Summary		Invitation revoke route
+//	@Description	Cancels an invitation that has not been accepted, so that its link stops working.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"Invitation ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"invitation"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"Open invitation not found"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/invitations/{id} [delete]
+func RevokeInvitation(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	id, parseErr := strconv.ParseInt(r.Param("id"), 10, 64)
+	if parseErr != nil {
+		respondWithError(r, http.StatusNotFound, "invitation_not_found")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	invitation, revokeErr := queries.RevokeInvitation(ctx, id)
+	if revokeErr != nil {
+		if strings.Contains(revokeErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusNotFound, "invitation_not_found")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", revokeErr.Error())
+		return
+	}
+
+	recordAdminAudit(ctx, queries, r, model.AuditAdminInvitationRevoked, map[string]interface{}{"invitation_id": invitation.ID, "email": invitation.Email})
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Invitation revoked", Data: map[string]interface{}{"invitation": invitationResponse(invitation)}})
+}
+
+// ^ AcceptInvitation :
+//
+//	@Summary		Invitation accept route
+//	@Description	Creates a verified account for the invited email address and signs it in, without an OTP. The token comes from the invitation link.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			Body	body		model.InvitationAccept		true	"Token from the invitation link, password, and optionally name, username and attributes"
+//	@Success		200		{object}	responses.UserResponse_doc	"Successful response"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid username, This username is reserved, Invalid attributes"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired invitation"
+//	@Failure		409		{object}	responses.ErrorResponse_doc	"Email already registered, Username already taken"
+//	@Failure		422		{object}	responses.ErrorResponse_doc	"Please provide with sufficient credentials"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/invitations/accept [post]
+func AcceptInvitation(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.InvitationAccept
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusUnprocessableEntity, "insufficient_credentials")
+		return
+	}
+
+	//* Checking the optional username and the custom attributes
+	var params db.CreateUserParams
+	if ok, status, code, detail := checkSignupFields(&params, req.Username, req.Attributes); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	//* Hashing Password
+	var pending model.User
+	if hashPassErr := pending.HashPassword(req.Password); hashPassErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", hashPassErr.Error())
+		return
+	}
+
+	//* Consuming the invitation and creating the account in one transaction
+	queries := db.New(configs.CONN)
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	invitation, invitationErr := qtx.GetOpenInvitationByTokenHash(ctx, model.HashOTP(req.Token))
+	if invitationErr != nil {
+		if strings.Contains(invitationErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusUnauthorized, "invalid_invitation")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", invitationErr.Error())
+		return
+	}
+
+	params.Name = strings.TrimSpace(req.Name)
+	if params.Name == "" {
+		params.Name = invitation.Name
+	}
+	if params.Name == "" {
+		respondWithError(r, http.StatusUnprocessableEntity, "insufficient_credentials")
+		return
+	}
+	params.Email = invitation.Email
+	params.Password = pending.Password
+	params.OtpChannel = model.OTPChannelEmail
+	params.Locale = invitation.Locale
+
+	created, insertDBErr := qtx.CreateUser(ctx, params)
+	if insertDBErr != nil {
+		if strings.Contains(insertDBErr.Error(), "\"users_username_lower_key\"") {
+			respondWithError(r, http.StatusConflict, "username_taken")
+			return
+		} else if strings.HasPrefix(insertDBErr.Error(), "ERROR: duplicate key") {
+			respondWithError(r, http.StatusConflict, "email_taken")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", insertDBErr.Error())
+		return
+	}
+	//* Receiving the invitation proves ownership of the address
+	user, verifyErr := qtx.VerifyUserByID(ctx, created.ID)
+	if verifyErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", verifyErr.Error())
+		return
+	}
+	if acceptErr := qtx.AcceptInvitation(ctx, db.AcceptInvitationParams{
+		ID:             invitation.ID,
+		AcceptedUserID: pgtype.Int8{Int64: user.ID, Valid: true},
+	}); acceptErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", acceptErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditRegistered, map[string]interface{}{"invitation_id": invitation.ID})
+
+	respondWithToken(ctx, r, queries, user, []string{auth.AMREmail})
+}
+
+// queueInvitation queues the email carrying token for invitation, sent on behalf of admin.
+func queueInvitation(ctx context.Context, queries *db.Queries, invitation db.Invitation, admin db.User, token string) (bool, int, string, string) {
+	//* The link target must come from config: building it from the Host header would let anyone redirect it
+	linkURL := configs.INVITATION_URL()
+	if linkURL == "" {
+		return false, http.StatusInternalServerError, "internal_error", "INVITATION_URL is not configured"
+	}
+
+	inviter := admin.DisplayName
+	if inviter == "" {
+		inviter = admin.Name
+	}
+	if queueErr := model.QueueInvitation(ctx, queries, invitation, inviter, linkWithToken(linkURL, token)); queueErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", queueErr.Error()
+	}
+
+	return true, 0, "", ""
+}
+
+// invitationResponse returns an invitation as shown to admins; the token hash is left out.
+func invitationResponse(invitation db.Invitation) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":         invitation.ID,
+		"email":      invitation.Email,
+		"name":       invitation.Name,
+		"locale":     invitation.Locale,
+		"status":     model.InvitationStatus(invitation),
+		"expires_at": invitation.ExpiresAt.Time,
+		"created_at": invitation.CreatedAt.Time,
+	}
+	if invitation.InvitedBy.Valid {
+		response["invited_by"] = invitation.InvitedBy.Int64
+	}
+	if invitation.AcceptedAt.Valid {
+		response["accepted_at"] = invitation.AcceptedAt.Time
+	}
+	if invitation.AcceptedUserID.Valid {
+		response["accepted_user_id"] = invitation.AcceptedUserID.Int64
+	}
+	if invitation.RevokedAt.Valid {
+		response["revoked_at"] = invitation.RevokedAt.Time
+	}
+
+	return response
+}
+
+func invitationLifetime() time.Duration {
+	lifetime, err := time.ParseDuration(configs.INVITATION_LIFETIME())
+	if err != nil || lifetime <= 0 {
+		return model.DefaultInvitationLifetime
+	}
+
+	return lifetime
+}
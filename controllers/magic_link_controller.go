@@ -0,0 +1,280 @@
+package controller
+
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	model "Gin/Basics/models"
+	"Gin/Basics/responses"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultMagicLinkLifetime = 15 * time.Minute
+
+	//* Same answer whether or not the email is registered, so the endpoint can't be used to probe for accounts
+	magicLinkSentMessage = "If the email is registered, a sign-in link has been sent to it."
+)
+
+// ^ RequestMagicLink :
+//
+//	@Summary		Magic link request route
+//	@Description	Emails a single-use, short-lived sign-in link. The email is sent in the background, so the response is the same, and as fast, whether or not the email is registered.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			Body	body		model.MagicLink				true	"User's email"
+//	@Success		202		{object}	responses.UserResponse_doc	"If the email is registered, a sign-in link has been sent to it."
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid Email"
+//	@Failure		404		{object}	responses.ErrorResponse_doc	"Magic-link login is disabled"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/magic-link [post]
+func RequestMagicLink(r *gin.Context) {
+	var req model.MagicLink
+
+	if configs.MAGIC_LINK_ENABLED() != "true" {
+		respondWithError(r, http.StatusNotFound, "magic_link_disabled")
+		return
+	}
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	//* The link target must come from config: building it from the Host header would let anyone redirect it
+	linkURL := configs.MAGIC_LINK_URL()
+	if linkURL == "" {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", "MAGIC_LINK_URL is not configured")
+		return
+	}
+
+	//* Normalizing the email so that any spelling of it finds the account
+	email, emailErr := model.NormalizeEmail(req.Email)
+	if emailErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_email")
+		return
+	}
+
+	//* Looking up the account and queueing the email after answering, so that the response time doesn't tell whether the email is registered
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		if sendErr := sendMagicLink(ctx, db.New(configs.CONN), email, linkURL); sendErr != nil {
+			configs.Alerts().Report(fmt.Errorf("magic link: %w", sendErr))
+		}
+	}()
+
+	r.JSON(http.StatusAccepted, responses.UserResponse{Message: magicLinkSentMessage})
+}
+
+// sendMagicLink records a sign-in link for the account registered with email and queues it for
+// delivery. An unregistered email is not an error.
+func sendMagicLink(ctx context.Context, queries *db.Queries, email string, linkURL string) error {
+	user, userErr := queries.GetUserByEmail(ctx, email)
+	if userErr != nil {
+		if strings.Contains(userErr.Error(), "no rows in result set") {
+			return nil
+		}
+		return userErr
+	}
+
+	//* Recording the link so that it can be used only once
+	jtiBytes := make([]byte, 32)
+	if _, randErr := rand.Read(jtiBytes); randErr != nil {
+		return randErr
+	}
+	jti := base64.RawURLEncoding.EncodeToString(jtiBytes)
+	expiresAt := time.Now().Add(magicLinkLifetime())
+
+	if cleanupErr := queries.DeleteExpiredMagicLinks(ctx); cleanupErr != nil {
+		return cleanupErr
+	}
+
+	//* Signing the link
+	token, tokenErr := auth.GenerateMagicLinkToken(user.ID, jti, expiresAt)
+	if tokenErr != nil {
+		return tokenErr
+	}
+	link := linkWithToken(linkURL, token)
+
+	//* Storing the link and queueing the email in one transaction
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		return txErr
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if insertDBErr := qtx.CreateMagicLink(ctx, db.CreateMagicLinkParams{
+		ID:        jti,
+		UserID:    user.ID,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}); insertDBErr != nil {
+		return insertDBErr
+	}
+	if queueErr := model.QueueMagicLink(ctx, qtx, user.Email, user.Locale, link); queueErr != nil {
+		return queueErr
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ^ MagicLinkCallback :
+//
+//	@Summary		Magic link check route
+//	@Description	Checks the token from a magic link without using it. Mail scanners and link previews open links too, so the page behind the link asks the user to confirm and then posts the token to the confirm route.
+//	@Tags			user
+//	@Produce		json
+//	@Param			token	query		string						true	"Token from the emailed link"
+//	@Success		200		{object}	responses.UserResponse_doc	"Link is valid, confirm to sign in"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired link"
+//	@Failure		404		{object}	responses.ErrorResponse_doc	"Magic-link login is disabled"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/magic-link/callback [get]
+func MagicLinkCallback(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if configs.MAGIC_LINK_ENABLED() != "true" {
+		respondWithError(r, http.StatusNotFound, "magic_link_disabled")
+		return
+	}
+
+	userID, jti, tokenErr := auth.ValidateMagicLinkToken(r.Query("token"))
+	if tokenErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_link")
+		return
+	}
+
+	//* Checking that the link is still unused, without using it
+	queries := db.New(configs.CONN)
+	linkUserID, getErr := queries.GetMagicLink(ctx, jti)
+	if getErr != nil {
+		if strings.Contains(getErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusUnauthorized, "invalid_link")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", getErr.Error())
+		return
+	}
+	if linkUserID != userID {
+		respondWithError(r, http.StatusUnauthorized, "invalid_link")
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Link is valid, confirm to sign in"})
+}
+
+// ^ ConfirmMagicLink :
+//
+//	@Summary		Magic link confirm route
+//	@Description	Exchanges the token from a magic link for the same response as login, using up the link. Using a link also verifies the email address.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			Body	body		model.MagicLinkConfirm		true	"Token from the emailed link"
+//	@Success		200		{object}	responses.UserResponse_doc	"Successful response, or mfa_required with a challenge_token and the available mfa_methods"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired link"
+//	@Failure		404		{object}	responses.ErrorResponse_doc	"Magic-link login is disabled"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/magic-link/callback [post]
+func ConfirmMagicLink(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.MagicLinkConfirm
+
+	if configs.MAGIC_LINK_ENABLED() != "true" {
+		respondWithError(r, http.StatusNotFound, "magic_link_disabled")
+		return
+	}
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	userID, jti, tokenErr := auth.ValidateMagicLinkToken(req.Token)
+	if tokenErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_link")
+		return
+	}
+
+	//* Consuming the link
+	queries := db.New(configs.CONN)
+	linkUserID, useErr := queries.UseMagicLink(ctx, jti)
+	if useErr != nil {
+		if strings.Contains(useErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusUnauthorized, "invalid_link")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", useErr.Error())
+		return
+	}
+	if linkUserID != userID {
+		respondWithError(r, http.StatusUnauthorized, "invalid_link")
+		return
+	}
+
+	user, userErr := queries.GetUserByID(ctx, userID)
+	if userErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_link")
+		return
+	}
+
+	//* Receiving the link proves ownership of the address
+	if !user.Isverified {
+		if updateUserErr := queries.UpdateUser(ctx, user.Email); updateUserErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", updateUserErr.Error())
+			return
+		}
+		user.Isverified = true
+	}
+
+	respondWithLogin(ctx, r, queries, user, []string{auth.AMREmail})
+}
+
+// linkWithToken adds token to the query string of the configured page linkURL.
+func linkWithToken(linkURL string, token string) string {
+	if strings.Contains(linkURL, "?") {
+		return linkURL + "&token=" + url.QueryEscape(token)
+	}
+
+	return linkURL + "?token=" + url.QueryEscape(token)
+}
+
+func magicLinkLifetime() time.Duration {
+	minutes, err := strconv.Atoi(configs.MAGIC_LINK_LIFETIME())
+	if err != nil || minutes <= 0 {
+		return defaultMagicLinkLifetime
+	}
+
+	return time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,599 @@
+package controller
+
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/notify"
+	"Gin/Basics/responses"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const defaultMFAIssuer = "Auth API"
+
+// ^ EnrollTOTP :
+//
+//	@Summary		TOTP enrollment route
+//	@Description	Generates a new TOTP secret for the signed-in user and returns its otpauth:// URI and a QR code PNG (base64). Two-factor login is enabled only after the first code is confirmed.
+//	@Tags			mfa
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	responses.UserResponse_doc	"otpauth_uri, secret and qr_code"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		409	{object}	responses.ErrorResponse_doc	"Two-factor authentication is already enabled"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/mfa/totp/enroll [post]
+func EnrollTOTP(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	if user.MfaEnabled {
+		respondWithError(r, http.StatusConflict, "mfa_already_enabled")
+		return
+	}
+
+	//* Generating and storing the encrypted secret
+	secret, genSecretErr := auth.GenerateTOTPSecret()
+	if genSecretErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", genSecretErr.Error())
+		return
+	}
+	encrypted, encryptErr := auth.EncryptSecret(secret)
+	if encryptErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", encryptErr.Error())
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	if updateErr := queries.SetUserTOTPSecret(ctx, db.SetUserTOTPSecretParams{ID: user.ID, TotpSecret: encrypted}); updateErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", updateErr.Error())
+		return
+	}
+
+	//* Building the provisioning URI and QR code
+	issuer := configs.MFA_ISSUER()
+	if issuer == "" {
+		issuer = defaultMFAIssuer
+	}
+	uri := auth.TOTPURI(issuer, user.Email, secret)
+	png, qrErr := auth.TOTPQRCode(uri)
+	if qrErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", qrErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{
+		"otpauth_uri": uri,
+		"secret":      secret,
+		"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}})
+}
+
+// ^ ConfirmTOTP :
+//
+//	@Summary		TOTP confirmation route
+//	@Description	Confirms TOTP enrollment with a first code from the authenticator app and enables two-factor login.
+//	@Tags			mfa
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.TOTPConfirm			true	"Code from the authenticator app"
+//	@Success		200		{object}	responses.UserResponse_doc	"Two-factor authentication enabled, with recovery_codes"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, TOTP enrollment has not been started"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid code"
+//	@Failure		409		{object}	responses.ErrorResponse_doc	"Two-factor authentication is already enabled"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/mfa/totp/confirm [post]
+func ConfirmTOTP(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.TOTPConfirm
+	user := middleware.CurrentUser(r)
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	if user.MfaEnabled {
+		respondWithError(r, http.StatusConflict, "mfa_already_enabled")
+		return
+	}
+	if user.TotpSecret == "" {
+		respondWithError(r, http.StatusBadRequest, "totp_not_started")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	if ok, status, code, detail := checkTOTP(ctx, queries, user, req.Code); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	//* Enabling MFA and issuing the first set of recovery codes together
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if enableErr := qtx.EnableUserMFA(ctx, user.ID); enableErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", enableErr.Error())
+		return
+	}
+	codes, codesErr := replaceRecoveryCodes(ctx, qtx, user.ID)
+	if codesErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", codesErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditMFAEnabled, nil)
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Two-factor authentication enabled", Data: map[string]interface{}{"recovery_codes": codes}})
+}
+
+// ^ RegenerateRecoveryCodes :
+//
+//	@Summary		Recovery code regeneration route
+//	@Description	Replaces the signed-in user's MFA recovery codes with a new set. All previous codes stop working.
+//	@Tags			mfa
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	responses.UserResponse_doc	"recovery_codes"
+//	@Failure		400	{object}	responses.ErrorResponse_doc	"Two-factor authentication is not enabled"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	if !user.MfaEnabled {
+		respondWithError(r, http.StatusBadRequest, "mfa_not_enabled")
+		return
+	}
+
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	codes, codesErr := replaceRecoveryCodes(ctx, db.New(tx), user.ID)
+	if codesErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", codesErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, db.New(configs.CONN), r, user.ID, model.AuditRecoveryCodesGenerated, nil)
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"recovery_codes": codes}})
+}
+
+// ^ VerifyMFA :
+//
+//	@Summary		MFA verification route
+//	@Description	Exchanges the challenge token returned by login and a second-factor code for an access token. A recovery code may be given instead of a TOTP code; each one works once, as does the challenge token. Five wrong codes in a row lock the user's second factors for 15 minutes.
+//	@Tags			mfa
+//	@Accept			json
+//	@Produce		json
+//	@Param			Body	body		model.MFAVerify				true	"Challenge token and either a TOTP code or a recovery code"
+//	@Success		200		{object}	responses.UserResponse_doc	"Successful response"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid, expired or already used challenge token, Invalid code, Invalid recovery code"
+//	@Failure		429		{object}	responses.ErrorResponse_doc	"Too many wrong codes, second factors are locked for a while"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/mfa/verify [post]
+func VerifyMFA(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.MFAVerify
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	//* Resolving the user from the challenge
+	userID, jti, amr, challengeErr := auth.ValidateMFAChallenge(req.ChallengeToken)
+	if challengeErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	user, userErr := queries.GetUserByID(ctx, userID)
+	if userErr != nil || !user.MfaEnabled {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+	if ok, status, code, detail := checkMFAChallenge(ctx, queries, user, jti); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	//* Checking the second factor: a TOTP code or, failing that, a recovery code
+	if req.Code != "" {
+		if ok, status, code, detail := checkTOTP(ctx, queries, user, req.Code); !ok {
+			respondWithMFAFailure(ctx, r, queries, user.ID, status, code, detail)
+			return
+		}
+		if ok, status, code, detail := spendMFAChallenge(ctx, queries, user.ID, jti); !ok {
+			respondWithError(r, status, code, detail)
+			return
+		}
+		amr = append(amr, auth.AMROTP)
+	} else {
+		//* Spending the code and queueing the notice about it in one transaction
+		tx, txErr := configs.CONN.Begin(ctx)
+		if txErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+			return
+		}
+		defer tx.Rollback(ctx)
+		qtx := queries.WithTx(tx)
+
+		_, useCodeErr := qtx.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{UserID: user.ID, CodeHash: model.HashRecoveryCode(req.RecoveryCode)})
+		if useCodeErr != nil {
+			if strings.Contains(useCodeErr.Error(), "no rows in result set") {
+				respondWithMFAFailure(ctx, r, queries, user.ID, http.StatusUnauthorized, "invalid_recovery_code", "")
+				return
+			}
+			respondWithError(r, http.StatusInternalServerError, "internal_error", useCodeErr.Error())
+			return
+		}
+
+		//* Letting the user know a recovery code was spent
+		remaining, countErr := qtx.CountUnusedRecoveryCodes(ctx, user.ID)
+		if countErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", countErr.Error())
+			return
+		}
+		if queueErr := model.QueueRecoveryCodeUsed(ctx, qtx, user.Email, user.Locale, remaining); queueErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", queueErr.Error())
+			return
+		}
+		if ok, status, code, detail := spendMFAChallenge(ctx, qtx, user.ID, jti); !ok {
+			respondWithError(r, status, code, detail)
+			return
+		}
+		if commitErr := tx.Commit(ctx); commitErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+			return
+		}
+		amr = append(amr, auth.AMRRecoveryCode)
+	}
+
+	respondWithToken(ctx, r, queries, user, amr)
+}
+
+// ^ SendSMSMFA :
+//
+//	@Summary		SMS second factor send route
+//	@Description	Sends a one-time code by SMS to the user of an MFA challenge token returned by login.
+//	@Tags			mfa
+//	@Accept			json
+//	@Produce		json
+//	@Param			Body	body		model.SMSMFASend			true	"Challenge token"
+//	@Success		200		{object}	responses.UserResponse_doc	"Code sent"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, SMS codes are not enabled, Text messages are not available on this server"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid, expired or already used challenge token"
+//	@Failure		429		{object}	responses.ErrorResponse_doc	"Too many wrong codes, second factors are locked for a while"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/mfa/sms/send [post]
+func SendSMSMFA(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.SMSMFASend
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	userID, jti, _, challengeErr := auth.ValidateMFAChallenge(req.ChallengeToken)
+	if challengeErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	user, userErr := queries.GetUserByID(ctx, userID)
+	if userErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+	if ok, status, code, detail := checkMFAChallenge(ctx, queries, user, jti); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+	if !user.SmsMfaEnabled || !user.PhoneVerified {
+		respondWithError(r, http.StatusBadRequest, "sms_mfa_not_enabled")
+		return
+	}
+	if !notify.SMSEnabled() {
+		respondWithError(r, http.StatusBadRequest, "sms_disabled")
+		return
+	}
+
+	if sendErr := sendPhoneOTP(ctx, queries, user); sendErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", sendErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Code has been sent to your phone"})
+}
+
+// ^ VerifySMSMFA :
+//
+//	@Summary		SMS second factor verification route
+//	@Description	Exchanges an MFA challenge token and the code sent by SMS for an access token.
+//	@Tags			mfa
+//	@Accept			json
+//	@Produce		json
+//	@Param			Body	body		model.SMSMFAVerify			true	"Challenge token and code"
+//	@Success		200		{object}	responses.UserResponse_doc	"Successful response"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid, expired or already used challenge token, Invalid or expired code"
+//	@Failure		429		{object}	responses.ErrorResponse_doc	"Too many wrong codes, second factors are locked for a while"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/mfa/sms/verify [post]
+func VerifySMSMFA(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.SMSMFAVerify
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	userID, jti, amr, challengeErr := auth.ValidateMFAChallenge(req.ChallengeToken)
+	if challengeErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	user, userErr := queries.GetUserByID(ctx, userID)
+	if userErr != nil || !user.SmsMfaEnabled {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+	if ok, status, code, detail := checkMFAChallenge(ctx, queries, user, jti); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	if ok, status, code, detail := checkSMSCode(ctx, queries, user, req.Code); !ok {
+		respondWithMFAFailure(ctx, r, queries, user.ID, status, code, detail)
+		return
+	}
+	if ok, status, code, detail := spendMFAChallenge(ctx, queries, user.ID, jti); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+	amr = append(amr, auth.AMRSMS)
+
+	respondWithToken(ctx, r, queries, user, amr)
+}
+
+// checkTOTP validates code against the user's stored secret and records the used time step.
+// When it fails it returns the status, error code and detail to respond with.
+func checkTOTP(ctx context.Context, queries *db.Queries, user db.User, code string) (bool, int, string, string) {
+	secret, decryptErr := auth.DecryptSecret(user.TotpSecret)
+	if decryptErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", decryptErr.Error()
+	}
+
+	counter, valid := auth.ValidateTOTP(secret, code, time.Now(), user.TotpLastCounter)
+	if !valid {
+		return false, http.StatusUnauthorized, "invalid_code", ""
+	}
+
+	//* Spending the time step only if no other request has spent it in the meantime
+	if _, consumeErr := queries.ConsumeTOTPCounter(ctx, db.ConsumeTOTPCounterParams{ID: user.ID, Counter: counter}); consumeErr != nil {
+		if strings.Contains(consumeErr.Error(), "no rows in result set") {
+			return false, http.StatusUnauthorized, "invalid_code", ""
+		}
+		return false, http.StatusInternalServerError, "internal_error", consumeErr.Error()
+	}
+
+	return true, 0, "", ""
+}
+
+// checkSMSCode spends the code last sent to the user's phone if code matches it. When it fails it
+// returns the status, error code and detail to respond with.
+func checkSMSCode(ctx context.Context, queries *db.Queries, user db.User, code string) (bool, int, string, string) {
+	//* Spending the code only if it matches, so that each code works once even under concurrent requests
+	if _, consumeErr := queries.ConsumeUserPhoneOTP(ctx, db.ConsumeUserPhoneOTPParams{ID: user.ID, PhoneOtp: model.HashOTP(code)}); consumeErr != nil {
+		if strings.Contains(consumeErr.Error(), "no rows in result set") {
+			return false, http.StatusUnauthorized, "invalid_or_expired_code", ""
+		}
+		return false, http.StatusInternalServerError, "internal_error", consumeErr.Error()
+	}
+
+	return true, 0, "", ""
+}
+
+// sendPhoneOTP stores a new code for the user's phone, replacing any earlier one, and queues the
+// SMS that delivers it.
+func sendPhoneOTP(ctx context.Context, queries *db.Queries, user db.User) error {
+	var pending model.User
+	if err := pending.GenerateOTP(); err != nil {
+		return err
+	}
+
+	//* Storing the code and queueing the SMS in one transaction
+	tx, err := configs.CONN.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if err := qtx.SetUserPhoneOTP(ctx, db.SetUserPhoneOTPParams{
+		ID:                user.ID,
+		PhoneOtp:          model.HashOTP(pending.OTP),
+		PhoneOtpExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(model.PhoneOTPLifetime), Valid: true},
+	}); err != nil {
+		return err
+	}
+	if err := model.QueueOTPSMS(ctx, qtx, user.Phone, pending.OTP); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// startMFAChallenge records a sign-in of userID that waits for a second factor and returns its
+// challenge token.
+func startMFAChallenge(ctx context.Context, queries *db.Queries, userID int64, amr []string) (string, error) {
+	//* Clearing out abandoned challenges
+	if err := queries.DeleteExpiredMFAChallenges(ctx); err != nil {
+		return "", err
+	}
+
+	jtiBytes := make([]byte, 32)
+	if _, err := rand.Read(jtiBytes); err != nil {
+		return "", err
+	}
+	jti := base64.RawURLEncoding.EncodeToString(jtiBytes)
+	expiresAt := time.Now().Add(model.MFAChallengeLifetime)
+
+	if err := queries.CreateMFAChallenge(ctx, db.CreateMFAChallengeParams{
+		ID:        jti,
+		UserID:    userID,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	}); err != nil {
+		return "", err
+	}
+
+	return auth.GenerateMFAChallenge(userID, jti, amr, expiresAt)
+}
+
+// checkMFAChallenge checks that the challenge jti of user has not been completed yet and that the
+// user's second factors are not locked after too many wrong codes. When it fails it returns the
+// status, error code and detail to respond with.
+func checkMFAChallenge(ctx context.Context, queries *db.Queries, user db.User, jti string) (bool, int, string, string) {
+	if _, challengeErr := queries.GetMFAChallenge(ctx, db.GetMFAChallengeParams{ID: jti, UserID: user.ID}); challengeErr != nil {
+		if strings.Contains(challengeErr.Error(), "no rows in result set") {
+			return false, http.StatusUnauthorized, "invalid_challenge_token", ""
+		}
+		return false, http.StatusInternalServerError, "internal_error", challengeErr.Error()
+	}
+	if user.MfaLockedUntil.Valid && time.Now().Before(user.MfaLockedUntil.Time) {
+		return false, http.StatusTooManyRequests, "mfa_locked", ""
+	}
+
+	return true, 0, "", ""
+}
+
+// spendMFAChallenge completes the challenge jti once its second factor has been checked, so that the
+// token cannot sign in again, and clears the user's count of wrong codes.
+func spendMFAChallenge(ctx context.Context, queries *db.Queries, userID int64, jti string) (bool, int, string, string) {
+	if _, spendErr := queries.SpendMFAChallenge(ctx, db.SpendMFAChallengeParams{ID: jti, UserID: userID}); spendErr != nil {
+		if strings.Contains(spendErr.Error(), "no rows in result set") {
+			return false, http.StatusUnauthorized, "invalid_challenge_token", ""
+		}
+		return false, http.StatusInternalServerError, "internal_error", spendErr.Error()
+	}
+	if resetErr := queries.ResetMFAFailures(ctx, userID); resetErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", resetErr.Error()
+	}
+
+	return true, 0, "", ""
+}
+
+// respondWithMFAFailure answers a second factor that was not accepted. A wrong code counts against
+// the user, and the one that reaches MFAMaxAttempts locks their second factors for MFALockout.
+func respondWithMFAFailure(ctx context.Context, r *gin.Context, queries *db.Queries, userID int64, status int, code string, detail string) {
+	if status == http.StatusUnauthorized {
+		lockedUntil, recordErr := queries.RecordMFAFailure(ctx, db.RecordMFAFailureParams{
+			ID:          userID,
+			MaxAttempts: model.MFAMaxAttempts,
+			LockedUntil: pgtype.Timestamptz{Time: time.Now().Add(model.MFALockout), Valid: true},
+		})
+		if recordErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", recordErr.Error())
+			return
+		}
+		if lockedUntil.Valid && time.Now().Before(lockedUntil.Time) {
+			respondWithError(r, http.StatusTooManyRequests, "mfa_locked")
+			return
+		}
+	}
+
+	respondWithError(r, status, code, detail)
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores the hashes of a new set,
+// returning the plaintext codes to show to the user once.
+func replaceRecoveryCodes(ctx context.Context, queries *db.Queries, userID int64) ([]string, error) {
+	codes, err := model.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := queries.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		if err := queries.CreateRecoveryCode(ctx, db.CreateRecoveryCodeParams{UserID: userID, CodeHash: model.HashRecoveryCode(code)}); err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/oidc"
+	"Gin/Basics/responses"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	oauthStateLifetime = 10 * time.Minute
+
+	//* Holds the state in the browser that started the sign-in, so that a callback URL cannot be replayed in another one
+	oauthStateCookie = "oauth_state"
+)
+
+// ^ ListProviders :
+//
+//	@Summary		Identity provider list route
+//	@Description	Lists the external identity providers users can sign in with.
+//	@Tags			user
+//	@Produce		json
+//	@Success		200	{object}	responses.UserResponse_doc	"providers, each with a name and display_name"
+//	@Router			/auth/providers [get]
+func ListProviders(r *gin.Context) {
+	registry := configs.OIDCProviders()
+
+	providers := make([]map[string]interface{}, 0, len(registry.Providers))
+	for _, name := range registry.Names() {
+		provider, _ := registry.Get(name)
+		providers = append(providers, map[string]interface{}{"name": name, "display_name": provider.DisplayName})
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"providers": providers}})
+}
+
+// ^ ProviderLogin :
+//
+//	@Summary		Identity provider sign-in route
+//	@Description	Redirects the browser to the provider's sign-in page. The provider sends it back to the callback route.
+//	@Tags			user
+//	@Param			provider	path	string	true	"Provider name"
+//	@Success		302
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"Unknown identity provider"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/providers/{provider}/login [get]
+func ProviderLogin(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	provider, found := configs.OIDCProviders().Get(r.Param("provider"))
+	if !found {
+		respondWithError(r, http.StatusNotFound, "provider_not_found")
+		return
+	}
+
+	authURL, ok := startProviderSignIn(ctx, r, provider, pgtype.Int8{})
+	if !ok {
+		return
+	}
+	r.Redirect(http.StatusFound, authURL)
+}
+
+// ^ ProviderCallback :
+//
+//	@Summary		Identity provider callback route
+//	@Description	Finishes a sign-in started at the provider sign-in route and answers like login. A new identity with a verified email address gets a new account; when OIDC_AUTO_LINK is on, it is linked to an existing account with that address instead. Finishing a sign-in started at the link route links the identity to the signed-in user and answers with it.
+//	@Tags			user
+//	@Produce		json
+//	@Param			provider	path		string						true	"Provider name"
+//	@Param			code		query		string						true	"Authorization code from the provider"
+//	@Param			state		query		string						true	"State from the provider"
+//	@Success		200			{object}	responses.UserResponse_doc	"Successful response, or mfa_required with a challenge_token and the available mfa_methods"
+//	@Success		201			{object}	responses.UserResponse_doc	"identity, when linking"
+//	@Failure		400			{object}	responses.ErrorResponse_doc	"Invalid email, Invalid attributes"
+//	@Failure		401			{object}	responses.ErrorResponse_doc	"Invalid or expired sign-in, Sign-in cancelled, Sign-in failed"
+//	@Failure		403			{object}	responses.ErrorResponse_doc	"The provider has not verified the email address, Account disabled or locked"
+//	@Failure		404			{object}	responses.ErrorResponse_doc	"Unknown identity provider"
+//	@Failure		409			{object}	responses.ErrorResponse_doc	"An account with this email exists but the identity is not linked to it, An unverified account with this email exists, Identity linked to another account"
+//	@Failure		500			{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/providers/{provider}/callback [get]
+func ProviderCallback(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	provider, found := configs.OIDCProviders().Get(r.Param("provider"))
+	if !found {
+		respondWithError(r, http.StatusNotFound, "provider_not_found")
+		return
+	}
+
+	//* The provider reports a refused or failed sign-in in the error parameter
+	if providerErr := r.Query("error"); providerErr != "" {
+		respondWithError(r, http.StatusUnauthorized, "provider_denied", providerErr)
+		return
+	}
+
+	//* Checking that this browser started the sign-in, then consuming it
+	state := r.Query("state")
+	cookie, cookieErr := r.Cookie(oauthStateCookie)
+	if cookieErr != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		respondWithError(r, http.StatusUnauthorized, "invalid_state")
+		return
+	}
+	r.SetCookie(oauthStateCookie, "", -1, "/", "", strings.HasPrefix(provider.RedirectURL, "https://"), true)
+
+	queries := db.New(configs.CONN)
+	request, takeErr := queries.TakeOAuthState(ctx, db.TakeOAuthStateParams{ID: state, Provider: provider.Name})
+	if takeErr != nil {
+		if strings.Contains(takeErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusUnauthorized, "invalid_state")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", takeErr.Error())
+		return
+	}
+
+	identity, exchangeErr := provider.Exchange(ctx, r.Query("code"), request.CodeVerifier, request.Nonce)
+	if exchangeErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "provider_login_failed", exchangeErr.Error())
+		return
+	}
+
+	//* A sign-in started by a signed-in user links the identity to them
+	external := externalIdentity{
+		Provider:      identity.Provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+	}
+	if request.UserID.Valid {
+		linkIdentity(ctx, r, queries, request.UserID.Int64, external)
+		return
+	}
+
+	signInWithIdentity(ctx, r, queries, external, configs.OIDC_AUTO_LINK() == "true", true)
+}
+
+// startProviderSignIn records a sign-in through provider and returns the provider page to send the
+// browser to. A sign-in for userID links the identity to that user instead.
+func startProviderSignIn(ctx context.Context, r *gin.Context, provider *oidc.Provider, userID pgtype.Int8) (string, bool) {
+	state, nonce, verifier, requestErr := oidc.NewAuthRequest()
+	if requestErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", requestErr.Error())
+		return "", false
+	}
+	authURL, urlErr := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if urlErr != nil {
+		configs.Alerts().Report(urlErr)
+		respondWithError(r, http.StatusInternalServerError, "internal_error", urlErr.Error())
+		return "", false
+	}
+
+	//* Recording the sign-in so that its callback can be finished only once
+	queries := db.New(configs.CONN)
+	if cleanupErr := queries.DeleteExpiredOAuthStates(ctx); cleanupErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", cleanupErr.Error())
+		return "", false
+	}
+	if insertDBErr := queries.CreateOAuthState(ctx, db.CreateOAuthStateParams{
+		ID:           state,
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(oauthStateLifetime), Valid: true},
+		UserID:       userID,
+	}); insertDBErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", insertDBErr.Error())
+		return "", false
+	}
+
+	//* Lax, not Strict: the cookie has to come back with the provider's cross-site redirect
+	r.SetSameSite(http.SameSiteLaxMode)
+	r.SetCookie(oauthStateCookie, state, int(oauthStateLifetime.Seconds()), "/", "", strings.HasPrefix(provider.RedirectURL, "https://"), true)
+
+	return authURL, true
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/oidc"
+	"Gin/Basics/oidc/oidctest"
+	"Gin/Basics/responses"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useMockProvider registers a provider called "mock" against a mock IdP for the length of the test.
+func useMockProvider(t *testing.T) *oidctest.IdP {
+	t.Helper()
+
+	idp := oidctest.NewIdP(t, "test-client")
+	registry, err := oidc.Parse([]byte(fmt.Sprintf(`{"mock": {"type": "oidc", "issuer": %q, "client_id": "test-client",
+		"client_secret": "secret", "redirect_url": "https://api.example.com/api/v1/auth/providers/mock/callback"}}`, idp.URL())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs.SetOIDCProviders(registry)
+	t.Cleanup(func() { configs.SetOIDCProviders(&oidc.Registry{}) })
+
+	return idp
+}
+
+// callback calls ProviderCallback of the mock provider with the query values and state cookie.
+func callback(t *testing.T, query url.Values, cookie string) (*httptest.ResponseRecorder, responses.UserResponse) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	r, _ := gin.CreateTestContext(recorder)
+	r.Request = httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
+	if cookie != "" {
+		r.Request.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: cookie})
+	}
+	r.Params = gin.Params{{Key: "provider", Value: "mock"}}
+
+	ProviderCallback(r)
+
+	var body responses.UserResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", recorder.Body, err)
+	}
+
+	return recorder, body
+}
+
+func TestProviderCallbackRejectsStateMismatch(t *testing.T) {
+	useMockProvider(t)
+
+	//* The cookie is checked before the database is used, so none is needed
+	for _, tc := range []struct {
+		name   string
+		state  string
+		cookie string
+	}{
+		{name: "no cookie", state: "state-1"},
+		{name: "other browser", state: "state-1", cookie: "state-2"},
+		{name: "no state", cookie: "state-1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder, body := callback(t, url.Values{"code": {"code"}, "state": {tc.state}}, tc.cookie)
+			if recorder.Code != http.StatusUnauthorized || body.Code != "invalid_state" {
+				t.Fatalf("got %d %q, want 401 invalid_state", recorder.Code, body.Code)
+			}
+		})
+	}
+}
+
+// providerSignIn runs a sign-in through the mock provider as subject and returns the callback's answer.
+func providerSignIn(t *testing.T, idp *oidctest.IdP, subject string, email string) (*httptest.ResponseRecorder, responses.UserResponse) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	r, _ := gin.CreateTestContext(recorder)
+	r.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Params = gin.Params{{Key: "provider", Value: "mock"}}
+	ProviderLogin(r)
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("ProviderLogin status = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var cookie string
+	for _, c := range recorder.Result().Cookies() {
+		if c.Name == oauthStateCookie {
+			cookie = c.Value
+		}
+	}
+	code, state := idp.Authorize(t, recorder.Header().Get("Location"), subject, email)
+
+	return callback(t, url.Values{"code": {code}, "state": {state}}, cookie)
+}
+
+func TestProviderCallbackCreatesThenFindsUser(t *testing.T) {
+	queries := testQueries(t)
+	ctx := context.Background()
+	t.Setenv("JWT_LIFETIME", "15")
+	t.Setenv("OIDC_AUTO_LINK", "false")
+	idp := useMockProvider(t)
+
+	recorder, body := providerSignIn(t, idp, "subject-1", "ada@example.com")
+	if recorder.Code != http.StatusOK || body.Data["token"] == nil {
+		t.Fatalf("first sign-in: %d %s", recorder.Code, recorder.Body)
+	}
+	created, err := queries.GetUserByEmail(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatalf("user was not created: %v", err)
+	}
+	if created.Password != "" || !created.Isverified {
+		t.Fatalf("created user has a password or is not verified: %+v", created)
+	}
+
+	//* The identity, not the address, finds the user again
+	recorder, body = providerSignIn(t, idp, "subject-1", "ada@new.example.com")
+	if recorder.Code != http.StatusOK || body.Data["token"] == nil {
+		t.Fatalf("second sign-in: %d %s", recorder.Code, recorder.Body)
+	}
+	linked, err := queries.GetUserIdentity(ctx, db.GetUserIdentityParams{Provider: "mock", Subject: "subject-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if linked.UserID != created.ID {
+		t.Fatalf("identity belongs to user %d, want %d", linked.UserID, created.ID)
+	}
+	if _, err := queries.GetUserByEmail(ctx, "ada@new.example.com"); err == nil {
+		t.Fatal("second sign-in created another user")
+	}
+}
+
+func TestProviderCallbackAutoLink(t *testing.T) {
+	queries := testQueries(t)
+	ctx := context.Background()
+	t.Setenv("JWT_LIFETIME", "15")
+	t.Setenv("OIDC_AUTO_LINK", "true")
+	idp := useMockProvider(t)
+
+	for _, tc := range []struct {
+		name       string
+		email      string
+		password   string
+		wantStatus int
+	}{
+		{name: "unverified with password", email: "squatter@example.com", password: "bcrypt-hash", wantStatus: http.StatusConflict},
+		{name: "unverified passwordless", email: "imported@example.com", password: "", wantStatus: http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := queries.CreateUser(ctx, db.CreateUserParams{
+				Name:       "Ada",
+				Email:      tc.email,
+				Password:   tc.password,
+				OtpChannel: "email",
+				Locale:     "en",
+				Attributes: []byte("{}"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder, _ := providerSignIn(t, idp, tc.email, tc.email)
+			if recorder.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tc.wantStatus, recorder.Body)
+			}
+
+			after, err := queries.GetUserByID(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			identities, err := queries.CountUserIdentities(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			linked := tc.wantStatus == http.StatusOK
+			if after.Isverified != linked || (identities == 1) != linked {
+				t.Fatalf("verified = %v, %d identities, want linked = %v", after.Isverified, identities, linked)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/outbox"
+	"Gin/Basics/responses"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const outboxPageSize = 50
+
+// ^ ListOutboxMessages :
+//
+//	@Summary		Outbox list route
+//	@Description	Lists queued email and SMS messages by delivery status, newest first. Message bodies are not included.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			status	query		string						false	"pending, sending, sent or dead (default dead)"
+//	@Param			offset	query		int							false	"Number of messages to skip"
+//	@Success		200		{object}	responses.UserResponse_doc	"messages"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid status"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403		{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/outbox [get]
+func ListOutboxMessages(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	status := r.DefaultQuery("status", outbox.StatusDead)
+	switch status {
+	case outbox.StatusPending, outbox.StatusSending, outbox.StatusSent, outbox.StatusDead:
+	default:
+		respondWithError(r, http.StatusBadRequest, "invalid_status")
+		return
+	}
+	offset, _ := strconv.Atoi(r.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	rows, listErr := db.New(configs.CONN).ListOutboxMessages(ctx, db.ListOutboxMessagesParams{
+		Status: status,
+		Limit:  outboxPageSize,
+		Offset: int32(offset),
+	})
+	if listErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", listErr.Error())
+		return
+	}
+
+	messages := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, outboxMessageResponse(row))
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"messages": messages}})
+}
+
+// ^ RetryOutboxMessage :
+//
+//	@Summary		Outbox retry route
+//	@Description	Puts a dead-lettered message back in the queue with a fresh set of delivery attempts. Messages can be retried until their bodies are purged, OUTBOX_BODY_RETENTION after they were queued.
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"Message ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"Message queued for delivery"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		403	{object}	responses.ErrorResponse_doc	"Admin access required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"Dead-lettered message not found, or its body has been purged"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/admin/outbox/{id}/retry [post]
+func RetryOutboxMessage(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	id, parseErr := strconv.ParseInt(r.Param("id"), 10, 64)
+	if parseErr != nil {
+		respondWithError(r, http.StatusNotFound, "outbox_message_not_found")
+		return
+	}
+
+	row, retryErr := db.New(configs.CONN).RetryOutboxMessage(ctx, id)
+	if retryErr != nil {
+		if strings.Contains(retryErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusNotFound, "outbox_message_not_found")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", retryErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Message queued for delivery", Data: map[string]interface{}{"message": outboxMessageResponse(row)}})
+}
+
+// outboxMessageResponse leaves out the message bodies, which can hold one-time codes and login links.
+func outboxMessageResponse(row db.OutboxMessage) map[string]interface{} {
+	message := map[string]interface{}{
+		"id":              row.ID,
+		"channel":         row.Channel,
+		"recipient":       row.Recipient,
+		"subject":         row.Subject,
+		"status":          row.Status,
+		"attempts":        row.Attempts,
+		"next_attempt_at": row.NextAttemptAt.Time,
+		"last_error":      row.LastError,
+		"created_at":      row.CreatedAt.Time,
+	}
+	if row.SentAt.Valid {
+		message["sent_at"] = row.SentAt.Time
+	}
+
+	return message
+}
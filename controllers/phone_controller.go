@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/notify"
+	"Gin/Basics/responses"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ^ SetPhone :
+//
+//	@Summary		Phone number route
+//	@Description	Sets the signed-in user's phone number (E.164, e.g. +14155550123) and sends a verification code to it by SMS. Changing the number resets SMS delivery preferences.
+//	@Tags			phone
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.Phone					true	"Phone number"
+//	@Success		200		{object}	responses.UserResponse_doc	"Verification code sent"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid phone number, Text messages are not available on this server"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/phone [put]
+func SetPhone(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.Phone
+	user := middleware.CurrentUser(r)
+
+	if !notify.SMSEnabled() {
+		respondWithError(r, http.StatusBadRequest, "sms_disabled")
+		return
+	}
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating the phone number
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_phone")
+		return
+	}
+
+	//* Generating the verification code
+	var pending model.User
+	if genOtpErr := pending.GenerateOTP(); genOtpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", genOtpErr.Error())
+		return
+	}
+
+	//* Storing the number and queueing the code in one transaction
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := db.New(configs.CONN).WithTx(tx)
+
+	updateErr := qtx.SetUserPhone(ctx, db.SetUserPhoneParams{
+		ID:                user.ID,
+		Phone:             req.Phone,
+		PhoneOtp:          model.HashOTP(pending.OTP),
+		PhoneOtpExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(model.PhoneOTPLifetime), Valid: true},
+	})
+	if updateErr != nil {
+		if strings.Contains(updateErr.Error(), "\"valid_phone\"") {
+			respondWithError(r, http.StatusBadRequest, "invalid_phone")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", updateErr.Error())
+		return
+	}
+
+	if queueErr := model.QueueOTPSMS(ctx, qtx, req.Phone, pending.OTP); queueErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", queueErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, db.New(configs.CONN), r, user.ID, model.AuditPhoneChanged, map[string]interface{}{"phone": req.Phone})
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Verification code has been sent to your phone"})
+}
+
+// ^ VerifyPhone :
+//
+//	@Summary		Phone verification route
+//	@Description	Verifies the signed-in user's phone number with the code sent by SMS.
+//	@Tags			phone
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.PhoneVerify			true	"Code from the SMS"
+//	@Success		200		{object}	responses.UserResponse_doc	"Phone number verified"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, No phone number pending verification"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired code"
+//	@Failure		429		{object}	responses.ErrorResponse_doc	"Too many wrong codes, second factors are locked for a while"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/phone/verify [post]
+func VerifyPhone(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.PhoneVerify
+	user := middleware.CurrentUser(r)
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	if user.Phone == "" || user.PhoneVerified {
+		respondWithError(r, http.StatusBadRequest, "no_phone_pending")
+		return
+	}
+
+	//* Wrong codes count towards the same lockout as second factors
+	if user.MfaLockedUntil.Valid && time.Now().Before(user.MfaLockedUntil.Time) {
+		respondWithError(r, http.StatusTooManyRequests, "mfa_locked")
+		return
+	}
+
+	//* Spending the code and marking the number verified in one transaction
+	queries := db.New(configs.CONN)
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if ok, status, code, detail := checkSMSCode(ctx, qtx, user, req.Code); !ok {
+		tx.Rollback(ctx)
+		respondWithMFAFailure(ctx, r, queries, user.ID, status, code, detail)
+		return
+	}
+
+	if verifyErr := qtx.VerifyUserPhone(ctx, user.ID); verifyErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", verifyErr.Error())
+		return
+	}
+	if resetErr := qtx.ResetMFAFailures(ctx, user.ID); resetErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", resetErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditPhoneVerified, map[string]interface{}{"phone": user.Phone})
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Phone number verified"})
+}
+
+// ^ UpdateMessagingPreferences :
+//
+//	@Summary		Messaging preferences route
+//	@Description	Chooses whether OTPs are delivered by email or SMS, and whether SMS codes can be used as a second factor. SMS requires a verified phone number.
+//	@Tags			phone
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.MessagingPreferences	true	"otp_channel (email or sms) and sms_mfa"
+//	@Success		200		{object}	responses.UserResponse_doc	"Preferences updated"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Please verify your phone number first, Text messages are not available on this server"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/phone/preferences [put]
+func UpdateMessagingPreferences(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.MessagingPreferences
+	user := middleware.CurrentUser(r)
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	if (req.OTPChannel == model.OTPChannelSMS || req.SMSMFA) && !notify.SMSEnabled() {
+		respondWithError(r, http.StatusBadRequest, "sms_disabled")
+		return
+	}
+	if (req.OTPChannel == model.OTPChannelSMS || req.SMSMFA) && !user.PhoneVerified {
+		respondWithError(r, http.StatusBadRequest, "phone_not_verified")
+		return
+	}
+
+	if updateErr := db.New(configs.CONN).UpdateUserMessagingPreferences(ctx, db.UpdateUserMessagingPreferencesParams{
+		ID:            user.ID,
+		OtpChannel:    req.OTPChannel,
+		SmsMfaEnabled: req.SMSMFA,
+	}); updateErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", updateErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Preferences updated"})
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/responses"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ^ GetProfile :
+//
+//	@Summary		Current user route
+//	@Description	Returns the signed-in user's profile.
+//	@Tags			user
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	responses.UserResponse_doc	"user"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Router			/me [get]
+func GetProfile(r *gin.Context) {
+	user := middleware.CurrentUser(r)
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"user": profileResponse(user)}})
+}
+
+// ^ UpdateProfile :
+//
+//	@Summary		Current user update route
+//	@Description	Updates the signed-in user's name, display name, locale, timezone or editable custom attributes. Fields left out are unchanged; email and verification status cannot be changed here.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.ProfileUpdate			true	"Fields to change"
+//	@Success		200		{object}	responses.UserResponse_doc	"user"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid profile, Invalid locale, Invalid timezone"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/me [patch]
+func UpdateProfile(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.ProfileUpdate
+	user := middleware.CurrentUser(r)
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating the fields that were sent
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_profile")
+		return
+	}
+
+	//* Merging the changes into the current profile
+	params := db.UpdateUserProfileParams{
+		ID:          user.ID,
+		Name:        user.Name,
+		DisplayName: user.DisplayName,
+		Locale:      user.Locale,
+		Timezone:    user.Timezone,
+		Attributes:  user.Attributes,
+	}
+	if req.Name != nil {
+		params.Name = strings.TrimSpace(*req.Name)
+		if params.Name == "" {
+			respondWithError(r, http.StatusBadRequest, "invalid_profile")
+			return
+		}
+	}
+	if req.DisplayName != nil {
+		params.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.Locale != nil {
+		locale, ok := model.CanonicalLocale(*req.Locale)
+		if !ok {
+			respondWithError(r, http.StatusBadRequest, "invalid_locale")
+			return
+		}
+		params.Locale = locale
+	}
+	if req.Timezone != nil {
+		if !model.ValidTimezone(*req.Timezone) {
+			respondWithError(r, http.StatusBadRequest, "invalid_timezone")
+			return
+		}
+		params.Timezone = *req.Timezone
+	}
+	if req.Attributes != nil {
+		attrs, attrsErr := configs.AttributeSchema().Update(model.DecodeAttributes(user.Attributes), req.Attributes)
+		if attrsErr != nil {
+			respondWithError(r, http.StatusBadRequest, "invalid_attributes", attrsErr.Error())
+			return
+		}
+		encoded, encodeErr := json.Marshal(attrs)
+		if encodeErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", encodeErr.Error())
+			return
+		}
+		params.Attributes = encoded
+	}
+
+	updated, updateErr := db.New(configs.CONN).UpdateUserProfile(ctx, params)
+	if updateErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", updateErr.Error())
+		return
+	}
+
+	recordAudit(ctx, db.New(configs.CONN), r, user.ID, model.AuditProfileUpdated, nil)
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"user": profileResponse(updated)}})
+}
+
+// profileResponse returns the fields of user that the user may see; secrets and hashes are left out.
+func profileResponse(user db.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             user.ID,
+		"name":           user.Name,
+		"display_name":   user.DisplayName,
+		"email":          user.Email,
+		"username":       user.Username.String,
+		"email_verified": user.Isverified,
+		"phone":          user.Phone,
+		"phone_verified": user.PhoneVerified,
+		"otp_channel":    user.OtpChannel,
+		"mfa_enabled":    user.MfaEnabled,
+		"sms_mfa":        user.SmsMfaEnabled,
+		"locale":         user.Locale,
+		"timezone":       user.Timezone,
+		"role":           user.Role,
+		"attributes":     model.DecodeAttributes(user.Attributes),
+	}
+}
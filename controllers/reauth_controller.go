@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/notify"
+	"Gin/Basics/responses"
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+// ^ Reauthenticate :
+//
+//	@Summary		Reauthentication route
+//	@Description	Proves the signed-in user's identity again, as strongly as signing in: with their password when the account has one, and with a second factor whenever one is enabled. method picks the second factor: "totp" or "sms" with a code, or "webauthn" with the session_id of the passkey reauthentication route and the credential from navigator.credentials.get(). Wrong passwords count towards the same lockout as wrong codes. Returns an elevated token whose auth_time is now. Sensitive routes answer 401 with error "reauthentication_required" until this is done.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			Body	body		model.Reauthenticate		true	"Password and second factor"
+//	@Success		200		{object}	responses.UserResponse_doc	"Successful response"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Two-factor authentication is not enabled, SMS codes are not enabled, Invalid or expired passkey session"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid Credentials, A second factor is required, Invalid code, Passkey verification failed"
+//	@Failure		429		{object}	responses.ErrorResponse_doc	"Too many wrong passwords or codes, reauthentication is locked for a while"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/reauthenticate [post]
+func Reauthenticate(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.Reauthenticate
+	user := middleware.CurrentUser(r)
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	passkeys, countErr := queries.CountWebAuthnCredentials(ctx, user.ID)
+	if countErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", countErr.Error())
+		return
+	}
+	secondFactor := user.MfaEnabled || user.SmsMfaEnabled || passkeys > 0
+	if user.Password == "" && !secondFactor {
+		respondWithError(r, http.StatusBadRequest, "mfa_not_enabled")
+		return
+	}
+
+	//* Wrong passwords and codes count towards the same lockout, so neither can be guessed here
+	if user.MfaLockedUntil.Valid && time.Now().Before(user.MfaLockedUntil.Time) {
+		respondWithError(r, http.StatusTooManyRequests, "mfa_locked")
+		return
+	}
+
+	var amr []string
+	if user.Password != "" {
+		//* Verifying password
+		if credentialsError := model.CheckPassword(req.Password, user.Password); credentialsError != nil {
+			respondWithMFAFailure(ctx, r, queries, user.ID, http.StatusUnauthorized, "invalid_credentials", "")
+			return
+		}
+		amr = append(amr, auth.AMRPassword)
+	}
+
+	//* Verifying the second factor, as at sign-in
+	if secondFactor {
+		method, ok, status, code, detail := checkReauthSecondFactor(ctx, queries, user, req)
+		if !ok {
+			//* Only wrong codes count towards the lockout, not unusable passkeys or sessions
+			if method == auth.AMROTP || method == auth.AMRSMS {
+				respondWithMFAFailure(ctx, r, queries, user.ID, status, code, detail)
+			} else {
+				respondWithError(r, status, code, detail)
+			}
+			return
+		}
+		amr = append(amr, method)
+	}
+	if resetErr := queries.ResetMFAFailures(ctx, user.ID); resetErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", resetErr.Error())
+		return
+	}
+
+	//* Generating the elevated token
+	token, genJWTErr := auth.GenerateJWT(user.ID, amr, model.AttributeClaims(user))
+	if genJWTErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", genJWTErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"token": token}})
+}
+
+// ^ SendReauthSMS :
+//
+//	@Summary		Reauthentication SMS route
+//	@Description	Sends a one-time code by SMS to the signed-in user, to reauthenticate with method "sms".
+//	@Tags			user
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	responses.UserResponse_doc	"Code sent"
+//	@Failure		400	{object}	responses.ErrorResponse_doc	"SMS codes are not enabled, Text messages are not available on this server"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		429	{object}	responses.ErrorResponse_doc	"Too many wrong codes, second factors are locked for a while"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/reauthenticate/sms [post]
+func SendReauthSMS(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	if user.MfaLockedUntil.Valid && time.Now().Before(user.MfaLockedUntil.Time) {
+		respondWithError(r, http.StatusTooManyRequests, "mfa_locked")
+		return
+	}
+	if !user.SmsMfaEnabled || !user.PhoneVerified {
+		respondWithError(r, http.StatusBadRequest, "sms_mfa_not_enabled")
+		return
+	}
+	if !notify.SMSEnabled() {
+		respondWithError(r, http.StatusBadRequest, "sms_disabled")
+		return
+	}
+
+	if sendErr := sendPhoneOTP(ctx, db.New(configs.CONN), user); sendErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", sendErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Code has been sent to your phone"})
+}
+
+// ^ BeginReauthWebAuthn :
+//
+//	@Summary		Reauthentication passkey route
+//	@Description	Starts a passkey assertion for the signed-in user, to reauthenticate with method "webauthn". Pass options to navigator.credentials.get() and send the result to the reauthentication route with the session_id.
+//	@Tags			user
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	responses.UserResponse_doc	"options and session_id"
+//	@Failure		400	{object}	responses.ErrorResponse_doc	"No passkeys registered"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/reauthenticate/webauthn [post]
+func BeginReauthWebAuthn(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+	queries := db.New(configs.CONN)
+
+	relyingParty, rpErr := auth.NewWebAuthn()
+	if rpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", rpErr.Error())
+		return
+	}
+	webAuthnUser, loadErr := loadWebAuthnUser(ctx, queries, user)
+	if loadErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", loadErr.Error())
+		return
+	}
+	if len(webAuthnUser.Credentials) == 0 {
+		respondWithError(r, http.StatusBadRequest, "no_passkeys")
+		return
+	}
+
+	options, session, beginErr := relyingParty.BeginLogin(webAuthnUser)
+	if beginErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", beginErr.Error())
+		return
+	}
+
+	sessionID, saveErr := saveWebAuthnSession(ctx, queries, webAuthnPurposeReauth, user.ID, session)
+	if saveErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", saveErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"options": options, "session_id": sessionID}})
+}
+
+// checkReauthSecondFactor checks the second factor of a reauthentication and returns its AMR value.
+// Without a method, a passkey assertion is checked when one is sent, then a TOTP code when TOTP is
+// enabled, then an SMS code. When it fails it returns the status, error code and detail to respond
+// with, along with the AMR value of a code that was checked and did not match.
+func checkReauthSecondFactor(ctx context.Context, queries *db.Queries, user db.User, req model.Reauthenticate) (string, bool, int, string, string) {
+	method := req.Method
+	if method == "" {
+		switch {
+		case req.SessionID != "":
+			method = "webauthn"
+		case user.MfaEnabled:
+			method = "totp"
+		default:
+			method = "sms"
+		}
+	}
+	if method != "webauthn" && req.Code == "" {
+		return "", false, http.StatusUnauthorized, "second_factor_required", ""
+	}
+
+	switch method {
+	case "totp":
+		if !user.MfaEnabled {
+			return "", false, http.StatusBadRequest, "mfa_not_enabled", ""
+		}
+		ok, status, code, detail := checkTOTP(ctx, queries, user, req.Code)
+		return auth.AMROTP, ok, status, code, detail
+	case "sms":
+		if !user.SmsMfaEnabled {
+			return "", false, http.StatusBadRequest, "sms_mfa_not_enabled", ""
+		}
+		ok, status, code, detail := checkSMSCode(ctx, queries, user, req.Code)
+		return auth.AMRSMS, ok, status, code, detail
+	}
+
+	//* A passkey assertion started at the passkey reauthentication route for this user
+	if req.SessionID == "" || len(req.Credential) == 0 {
+		return "", false, http.StatusUnauthorized, "second_factor_required", ""
+	}
+	row, session, sessionErr := takeWebAuthnSession(ctx, queries, req.SessionID, webAuthnPurposeReauth)
+	if sessionErr != nil || row.UserID.Int64 != user.ID {
+		return "", false, http.StatusBadRequest, "invalid_passkey_session", ""
+	}
+
+	relyingParty, rpErr := auth.NewWebAuthn()
+	if rpErr != nil {
+		return "", false, http.StatusInternalServerError, "internal_error", rpErr.Error()
+	}
+	webAuthnUser, loadErr := loadWebAuthnUser(ctx, queries, user)
+	if loadErr != nil {
+		return "", false, http.StatusInternalServerError, "internal_error", loadErr.Error()
+	}
+
+	parsed, parseErr := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if parseErr != nil {
+		return "", false, http.StatusUnauthorized, "passkey_verification_failed", webAuthnErrorDetails(parseErr)
+	}
+	credential, validateErr := relyingParty.ValidateLogin(webAuthnUser, session, parsed)
+	if validateErr != nil {
+		return "", false, http.StatusUnauthorized, "passkey_verification_failed", webAuthnErrorDetails(validateErr)
+	}
+	if ok, status, code, detail := recordWebAuthnUse(ctx, queries, credential); !ok {
+		return "", false, status, code, detail
+	}
+
+	return auth.AMRHardwareKey, true, 0, "", ""
+}
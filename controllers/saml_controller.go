@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"Gin/Basics/attributes"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	samlRequestLifetime = 10 * time.Minute
+
+	//* Holds the AuthnRequest ID in the browser that started the sign-in, so that a response cannot be replayed in another one
+	samlRequestCookie = "saml_request"
+)
+
+// ^ SAMLMetadata :
+//
+//	@Summary		SAML service provider metadata route
+//	@Description	Returns the SAML 2.0 service provider metadata of a connection, to register with its identity provider.
+//	@Tags			user
+//	@Produce		xml
+//	@Param			connection	path		string						true	"Connection name"
+//	@Success		200			{string}	string						"EntityDescriptor"
+//	@Failure		404			{object}	responses.ErrorResponse_doc	"Unknown SAML connection"
+//	@Failure		500			{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/saml/{connection}/metadata [get]
+func SAMLMetadata(r *gin.Context) {
+	connection, found := configs.SAMLConnections().Get(r.Param("connection"))
+	if !found {
+		respondWithError(r, http.StatusNotFound, "saml_connection_not_found")
+		return
+	}
+
+	metadata, metadataErr := connection.Metadata()
+	if metadataErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", metadataErr.Error())
+		return
+	}
+	r.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// ^ SAMLLogin :
+//
+//	@Summary		SAML sign-in route
+//	@Description	Redirects the browser to the identity provider of a connection with an AuthnRequest. The provider posts its response to the Assertion Consumer Service route.
+//	@Tags			user
+//	@Param			connection	path	string	true	"Connection name"
+//	@Success		302
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"Unknown SAML connection"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/saml/{connection}/login [get]
+func SAMLLogin(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	connection, found := configs.SAMLConnections().Get(r.Param("connection"))
+	if !found {
+		respondWithError(r, http.StatusNotFound, "saml_connection_not_found")
+		return
+	}
+
+	redirectURL, requestID, requestErr := connection.AuthnRequestURL(ctx)
+	if requestErr != nil {
+		configs.Alerts().Report(requestErr)
+		respondWithError(r, http.StatusInternalServerError, "internal_error", requestErr.Error())
+		return
+	}
+
+	//* Recording the request so that only one response can answer it
+	queries := db.New(configs.CONN)
+	if cleanupErr := queries.DeleteExpiredSAMLRequests(ctx); cleanupErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", cleanupErr.Error())
+		return
+	}
+	if insertDBErr := queries.CreateSAMLRequest(ctx, db.CreateSAMLRequestParams{
+		ID:         requestID,
+		Connection: connection.Name,
+		ExpiresAt:  pgtype.Timestamptz{Time: time.Now().Add(samlRequestLifetime), Valid: true},
+	}); insertDBErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", insertDBErr.Error())
+		return
+	}
+
+	setSAMLRequestCookie(r, requestID, int(samlRequestLifetime.Seconds()))
+	r.Redirect(http.StatusFound, redirectURL)
+}
+
+// ^ SAMLACS :
+//
+//	@Summary		SAML Assertion Consumer Service route
+//	@Description	Finishes a sign-in started at the SAML sign-in route and answers like login. The response must be signed by the identity provider, answer the request, and be addressed to this service within its validity period. Asserted email addresses must be at one of the connection's allowed_domains. Attributes fill the new account of a user the connection provisions; with link_by_email, the identity is linked to an existing account with the same email address instead.
+//	@Tags			user
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			connection		path		string						true	"Connection name"
+//	@Param			SAMLResponse	formData	string						true	"Base64-encoded SAML response"
+//	@Success		200				{object}	responses.UserResponse_doc	"Successful response, or mfa_required with a challenge_token and the available mfa_methods"
+//	@Failure		400				{object}	responses.ErrorResponse_doc	"Invalid email, Invalid attributes"
+//	@Failure		401				{object}	responses.ErrorResponse_doc	"Invalid or expired sign-in, Invalid SAML response"
+//	@Failure		403				{object}	responses.ErrorResponse_doc	"No email address asserted, Email domain not allowed for the connection, The connection does not provision new users, Account disabled or locked"
+//	@Failure		404				{object}	responses.ErrorResponse_doc	"Unknown SAML connection"
+//	@Failure		409				{object}	responses.ErrorResponse_doc	"An account with this email exists but the identity is not linked to it, An unverified account with this email exists, Identity linked to another account"
+//	@Failure		500				{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/saml/{connection}/acs [post]
+func SAMLACS(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	connection, found := configs.SAMLConnections().Get(r.Param("connection"))
+	if !found {
+		respondWithError(r, http.StatusNotFound, "saml_connection_not_found")
+		return
+	}
+
+	//* Checking that this browser started the sign-in, then consuming its request
+	requestID, cookieErr := r.Cookie(samlRequestCookie)
+	if cookieErr != nil || requestID == "" {
+		respondWithError(r, http.StatusUnauthorized, "invalid_state")
+		return
+	}
+	setSAMLRequestCookie(r, "", -1)
+
+	queries := db.New(configs.CONN)
+	request, takeErr := queries.TakeSAMLRequest(ctx, db.TakeSAMLRequestParams{ID: requestID, Connection: connection.Name})
+	if takeErr != nil {
+		if strings.Contains(takeErr.Error(), "no rows in result set") {
+			respondWithError(r, http.StatusUnauthorized, "invalid_state")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", takeErr.Error())
+		return
+	}
+
+	identity, parseErr := connection.ParseResponse(ctx, r.Request, request.ID)
+	if parseErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_saml_response", parseErr.Error())
+		return
+	}
+
+	//* An IdP speaks only for its own domains, before any account is linked or created
+	if identity.Email != "" && !connection.AllowsEmail(identity.Email) {
+		respondWithError(r, http.StatusForbidden, "saml_email_domain_not_allowed")
+		return
+	}
+
+	attrs, attrsErr := samlAttributes(identity.Custom)
+	if attrsErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_attributes", attrsErr.Error())
+		return
+	}
+
+	//* The IdP is the directory of the customer's users, so the addresses it asserts count as verified
+	signInWithIdentity(ctx, r, queries, externalIdentity{
+		Provider:      "saml:" + connection.Name,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: true,
+		Name:          identity.Name,
+		Username:      identity.Username,
+		Phone:         identity.Phone,
+		Locale:        identity.Locale,
+		Attributes:    attrs,
+	}, connection.LinkByEmail, connection.Provision)
+}
+
+// setSAMLRequestCookie stores the AuthnRequest ID for the Assertion Consumer Service. The IdP
+// posts the response from its own site, so over HTTPS the cookie has to be SameSite=None.
+func setSAMLRequestCookie(r *gin.Context, requestID string, maxAge int) {
+	secure := strings.HasPrefix(configs.SAML_BASE_URL(), "https://")
+	if secure {
+		r.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		r.SetSameSite(http.SameSiteLaxMode)
+	}
+	r.SetCookie(samlRequestCookie, requestID, maxAge, "/", "", secure, true)
+}
+
+// samlAttributes converts the custom attributes asserted by an IdP, which are text, to the types of
+// the attribute schema. Multi-valued attributes keep their first value.
+func samlAttributes(custom map[string][]string) (map[string]interface{}, error) {
+	schema := configs.AttributeSchema()
+
+	attrs := map[string]interface{}{}
+	for name, values := range custom {
+		value := values[0]
+		field, known := schema.Fields[name]
+		if !known {
+			attrs[name] = value
+			continue
+		}
+
+		switch field.Type {
+		case attributes.TypeNumber, attributes.TypeInteger:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: must be a number", name)
+			}
+			attrs[name] = number
+		case attributes.TypeBoolean:
+			boolean, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: must be true or false", name)
+			}
+			attrs[name] = boolean
+		default:
+			attrs[name] = value
+		}
+	}
+
+	return attrs, nil
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"Gin/Basics/attributes"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/responses"
+	"Gin/Basics/samlsp"
+	"Gin/Basics/samlsp/samlsptest"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// useMockSAMLConnection registers the connection "acme" to a mock IdP, with settings added to its
+// definition, for the length of the test.
+func useMockSAMLConnection(t *testing.T, settings string) (*samlsptest.IdP, *samlsp.Connection) {
+	t.Helper()
+
+	idp := samlsptest.NewIdP(t)
+	key, cert := samlsptest.KeyPair(t, "api.example.com")
+	base, _ := url.Parse("https://api.example.com/api/v1")
+	registry, err := samlsp.Parse([]byte(fmt.Sprintf(`{"acme": {"idp_metadata_file": %q, "attributes": {"name": "displayName",
+		"custom": {"department": "department", "employee_number": "employeeNumber", "contractor": "contractor"}}%s}}`,
+		idp.MetadataFile(t), settings)), &samlsp.ServiceProvider{BaseURL: base, Key: key, Certificate: cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs.SetSAMLConnections(registry)
+	t.Cleanup(func() { configs.SetSAMLConnections(&samlsp.Registry{}) })
+	connection, _ := registry.Get("acme")
+
+	return idp, connection
+}
+
+// samlResponse returns a response of idp signing in email to the connection "acme", answering requestID.
+func samlResponse(t *testing.T, idp *samlsptest.IdP, requestID string, email string) string {
+	t.Helper()
+
+	return idp.Post(t, samlsptest.Response{
+		RequestID:    requestID,
+		SPEntityID:   "https://api.example.com/api/v1/auth/saml/acme/metadata",
+		ACSURL:       "https://api.example.com/api/v1/auth/saml/acme/acs",
+		NameID:       "employee-42",
+		NameIDFormat: saml.PersistentNameIDFormat,
+		Attributes: map[string][]string{
+			"mail":           {email},
+			"displayName":    {"Ada Lovelace"},
+			"department":     {"Engineering", "Research"},
+			"employeeNumber": {"42"},
+			"contractor":     {"false"},
+		},
+	})
+}
+
+func TestSAMLAttributes(t *testing.T) {
+	schema, err := attributes.Parse([]byte(`{"department": {"type": "string"}, "employee_number": {"type": "integer"},
+		"contractor": {"type": "boolean"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs.SetAttributeSchema(schema)
+	t.Cleanup(func() { configs.SetAttributeSchema(&attributes.Schema{}) })
+	idp, connection := useMockSAMLConnection(t, "")
+
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{
+		"SAMLResponse": {samlResponse(t, idp, "id-request-1", "ada@acme.com")},
+	}.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	identity, err := connection.ParseResponse(context.Background(), request, "id-request-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs, err := samlAttributes(identity.Custom)
+	if err != nil {
+		t.Fatalf("samlAttributes: %v", err)
+	}
+	//* Text is converted to the schema's types and multi-valued attributes keep their first value
+	want := map[string]interface{}{"department": "Engineering", "employee_number": float64(42), "contractor": false}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Fatalf("attributes = %v, want %v", attrs, want)
+	}
+
+	if _, err := samlAttributes(map[string][]string{"employee_number": {"forty-two"}}); err == nil {
+		t.Fatal("expected an error for a number that does not parse")
+	}
+	if _, err := samlAttributes(map[string][]string{"contractor": {"maybe"}}); err == nil {
+		t.Fatal("expected an error for a boolean that does not parse")
+	}
+}
+
+func TestSAMLACSRejectsEmailOutsideAllowedDomains(t *testing.T) {
+	queries := testQueries(t)
+	ctx := context.Background()
+	idp, _ := useMockSAMLConnection(t, `, "provision": true, "link_by_email": true, "allowed_domains": ["acme.com"]`)
+
+	if err := queries.CreateSAMLRequest(ctx, db.CreateSAMLRequestParams{
+		ID:         "id-request-1",
+		Connection: "acme",
+		ExpiresAt:  pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	r, _ := gin.CreateTestContext(recorder)
+	r.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{
+		"SAMLResponse": {samlResponse(t, idp, "id-request-1", "ada@other.example.com")},
+	}.Encode()))
+	r.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Request.AddCookie(&http.Cookie{Name: samlRequestCookie, Value: "id-request-1"})
+	r.Params = gin.Params{{Key: "connection", Value: "acme"}}
+
+	SAMLACS(r)
+
+	var body responses.UserResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Code != http.StatusForbidden || body.Code != "saml_email_domain_not_allowed" {
+		t.Fatalf("got %d %q, want 403 saml_email_domain_not_allowed", recorder.Code, body.Code)
+	}
+	if _, err := queries.GetUserByEmail(ctx, "ada@other.example.com"); err == nil {
+		t.Fatal("a user was provisioned at a domain outside the connection's")
+	}
+}
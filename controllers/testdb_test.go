@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testQueries points configs.CONN at a fresh schema of the database in TEST_SQLURI, loaded from
+// db/schema.sql and dropped when the test ends. Tests that need a database skip without one.
+func testQueries(t *testing.T) *db.Queries {
+	t.Helper()
+
+	uri := os.Getenv("TEST_SQLURI")
+	if uri == "" {
+		t.Skip("TEST_SQLURI is not set")
+	}
+	ctx := context.Background()
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(suffix)
+
+	admin, err := pgxpool.New(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+
+	config, err := pgxpool.ParseConfig(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := configs.CONN
+	configs.CONN = pool
+	t.Cleanup(func() {
+		configs.CONN = previous
+		pool.Close()
+		admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		admin.Close()
+	})
+
+	ddl, err := os.ReadFile("../db/schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, string(ddl)); err != nil {
+		t.Fatalf("loading db/schema.sql: %v", err)
+	}
+
+	return db.New(pool)
+}
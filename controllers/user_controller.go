@@ -4,9 +4,14 @@ import (
 	"Gin/Basics/auth"
 	"Gin/Basics/configs"
 	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/i18n"
+	"Gin/Basics/middleware"
 	model "Gin/Basics/models"
+	"Gin/Basics/notify"
 	"Gin/Basics/responses"
 	"context"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -14,6 +19,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 var validate = validator.New()
@@ -21,13 +27,13 @@ var validate = validator.New()
 // ^ Login :
 //
 //	@Summary		Login route
-//	@Description	Allows users to login into their account.
+//	@Description	Allows users to login into their account. With the right password, an unverified account is sent a new verification code instead.
 //	@Tags			user
 //	@Accept			json
 //	@Produce		json
-//	@Param			Body	body		model.Login					true	"User's email and password"
-//	@Success		200		{object}	responses.UserResponse_doc	"Successful response"
-//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data"
+//	@Param			Body	body		model.Login					true	"User's email or username and password"
+//	@Success		200		{object}	responses.UserResponse_doc	"Successful response, or mfa_required with a challenge_token and the available mfa_methods"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid Email"
 //	@Failure		400		{object}	responses.ErrorResponse_doc	"Please provide with sufficient credentials"
 //	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid Credentials"
 //	@Failure		404		{object}	responses.ErrorResponse_doc	"User is not registered"
@@ -41,55 +47,82 @@ func Login(r *gin.Context) {
 
 	//* Checking for invalid json format
 	if err := r.BindJSON(&req); err != nil {
-		respondWithError(r, http.StatusBadRequest, "Invalid JSON data")
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
 		return
 	}
 
 	//* Validating if all the fields are present
 	if validationErr := validate.Struct(&req); validationErr != nil {
-		respondWithError(r, http.StatusBadRequest, "Please provide the required credentials.")
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
 		return
 	}
 
+	identifier := req.Identifier
+	if identifier == "" {
+		identifier = req.Email
+	}
+
 	queries := db.New(configs.CONN)
 	//* Checking whether the user is registered
-	user, userErr := queries.GetUserByEmail(ctx, req.Email)
+	user, userErr := findUserByIdentifier(ctx, queries, identifier)
 	if userErr != nil {
+		if errors.Is(userErr, model.ErrInvalidEmail) {
+			respondWithError(r, http.StatusBadRequest, "invalid_email")
+			return
+		}
 		if strings.Contains(userErr.Error(), "no rows in result set") {
-			respondWithError(r, http.StatusNotFound, "User is not registered.")
+			respondWithError(r, http.StatusNotFound, "user_not_registered")
 			return
 		}
-		go func() {
-			configs.NotifyAdmin(userErr)
-		}()
-		respondWithError(r, http.StatusInternalServerError, "Internal server error : "+userErr.Error())
+		configs.Alerts().Report(userErr)
+		respondWithError(r, http.StatusInternalServerError, "internal_error", userErr.Error())
 		return
 	}
-
-	//* Checking for verification of the user
-	if !user.Isverified {
-		go func() {
-			model.SendOTP(user.Email, user.Otp)
-		}()
-		respondWithError(r, http.StatusUnprocessableEntity, "Email is already registered. Please verify your email address using the OTP sent to your registered email.")
+	if !checkSignInStatus(r, user) {
 		return
 	}
 
-	//* Verifying password
+	//* Verifying password first, so that only the owner can have a new code sent
 	credentialsError := model.CheckPassword(req.Password, user.Password)
 	if credentialsError != nil {
-		respondWithError(r, http.StatusUnauthorized, "Invalid Credentials")
+		respondWithError(r, http.StatusUnauthorized, "invalid_credentials")
 		return
 	}
 
-	//* Generating Token
-	token, genJWTErr := auth.GenerateJWT()
-	if genJWTErr != nil {
-		respondWithError(r, http.StatusInternalServerError, "Internal Server Error : "+genJWTErr.Error())
+	//* Checking for verification of the user
+	if !user.Isverified {
+		//* Only the hash of the previous OTP is stored, so a fresh one is issued
+		var pending model.User
+		if genOtpErr := pending.GenerateOTP(); genOtpErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", genOtpErr.Error())
+			return
+		}
+		//* Storing the OTP and queueing it in one transaction, so it is sent exactly when it can be used
+		tx, txErr := configs.CONN.Begin(ctx)
+		if txErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+			return
+		}
+		defer tx.Rollback(ctx)
+		qtx := queries.WithTx(tx)
+
+		if updateOtpErr := qtx.UpdateUserOTP(ctx, db.UpdateUserOTPParams{Email: user.Email, Otp: model.HashOTP(pending.OTP)}); updateOtpErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", updateOtpErr.Error())
+			return
+		}
+		if queueErr := model.DeliverOTP(ctx, qtx, user, pending.OTP); queueErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", queueErr.Error())
+			return
+		}
+		if commitErr := tx.Commit(ctx); commitErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+			return
+		}
+		respondWithError(r, http.StatusUnprocessableEntity, "unverified_login")
 		return
 	}
 
-	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"token": token}})
+	respondWithLogin(ctx, r, queries, user, []string{auth.AMRPassword})
 }
 
 // ^ Register :
@@ -99,11 +132,11 @@ func Login(r *gin.Context) {
 //	@Tags			user
 //	@Accept			json
 //	@Produce		json
-//	@Param			user	body		model.Register				true	"User name, email, password"
+//	@Param			user	body		model.Register				true	"User name, email, password, and optionally a phone number, otp_channel (email or sms) and locale (defaults to Accept-Language)"
 //	@Success		201		{object}	responses.UserResponse_doc	"Successful response"
-//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid Email"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, Invalid Email, Invalid phone number, Invalid username, This username is reserved, Text messages are not available on this server"
 //	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid Credentials"
-//	@Failure		409		{object}	responses.ErrorResponse_doc	"User already exists"
+//	@Failure		409		{object}	responses.ErrorResponse_doc	"User already exists, Username already taken"
 //	@Failure		422		{object}	responses.ErrorResponse_doc	"Please provide with sufficient credentials"
 //	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal Server Error, Error in inserting the document"
 //	@Router			/auth/register [post]
@@ -111,70 +144,112 @@ func Register(r *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	var user model.User
 	defer cancel()
-	var queries *db.Queries
-	go func() {
-		queries = db.New(configs.CONN)
-	}()
 
 	//* Checking for invalid json format
 	if invalidJsonErr := r.BindJSON(&user); invalidJsonErr != nil {
-		respondWithError(r, http.StatusBadRequest, "Invalid JSON data")
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
 		return
 	}
 
 	//* Validating if all the fields are present
 	if validationErr := validate.Struct(&user); validationErr != nil {
-		respondWithError(r, http.StatusUnprocessableEntity, "Please provide with sufficient credentials")
+		respondWithError(r, http.StatusUnprocessableEntity, "insufficient_credentials")
+		return
+	}
+
+	//* Storing the email in its normalized form
+	email, emailErr := model.NormalizeEmail(user.Email)
+	if emailErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_email")
+		return
+	}
+	user.Email = email
+
+	//* Checking the optional username and the custom attributes
+	params := db.CreateUserParams{Name: user.Name, Email: user.Email, Phone: user.Phone}
+	if ok, status, code, detail := checkSignupFields(&params, user.Username, user.Attributes); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	//* Defaulting to email delivery; SMS needs a phone number
+	if user.OTPChannel == "" {
+		user.OTPChannel = model.OTPChannelEmail
+	}
+	if user.OTPChannel == model.OTPChannelSMS && !notify.SMSEnabled() {
+		respondWithError(r, http.StatusBadRequest, "sms_disabled")
+		return
+	}
+	if user.OTPChannel == model.OTPChannelSMS && user.Phone == "" {
+		respondWithError(r, http.StatusUnprocessableEntity, "phone_required")
 		return
 	}
 
 	//* Hashing Password
 	if hashPassErr := user.HashPassword(user.Password); hashPassErr != nil {
-		respondWithError(r, http.StatusInternalServerError, "Internal Server Error : "+hashPassErr.Error())
+		respondWithError(r, http.StatusInternalServerError, "internal_error", hashPassErr.Error())
 		return
 	}
 
 	//* Generating OTP
 	if genOtpErr := user.GenerateOTP(); genOtpErr != nil {
-		respondWithError(r, http.StatusInternalServerError, "Internal Server Error : "+genOtpErr.Error())
+		respondWithError(r, http.StatusInternalServerError, "internal_error", genOtpErr.Error())
 		return
 	}
 
-	//* Creating User
-	_, insertDBErr := queries.CreateUser(ctx, db.CreateUserParams{
-		Name:     user.Name,
-		Email:    user.Email,
-		Password: user.Password,
-		Otp:      user.OTP,
-	})
+	//* Creating the user and queueing the OTP in one transaction
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := db.New(configs.CONN).WithTx(tx)
+
+	params.Password = user.Password
+	params.Otp = model.HashOTP(user.OTP)
+	params.OtpChannel = user.OTPChannel
+	params.Locale = model.PreferredLocale(user.Locale, r.GetHeader("Accept-Language"))
+	created, insertDBErr := qtx.CreateUser(ctx, params)
 
 	//* Checking for errors while inserting in the DB
 	if insertDBErr != nil {
-		if strings.HasPrefix(insertDBErr.Error(), "ERROR: duplicate key") {
-			respondWithError(r, http.StatusConflict, "User already exists")
+		if strings.Contains(insertDBErr.Error(), "\"users_username_lower_key\"") {
+			respondWithError(r, http.StatusConflict, "username_taken")
+			return
+		} else if strings.HasPrefix(insertDBErr.Error(), "ERROR: duplicate key") {
+			respondWithError(r, http.StatusConflict, "user_exists")
 			return
 		} else if strings.Contains(insertDBErr.Error(), "\"valid_email\"") {
-			respondWithError(r, http.StatusBadRequest, "Invalid Email")
+			respondWithError(r, http.StatusBadRequest, "invalid_email")
+			return
+		} else if strings.Contains(insertDBErr.Error(), "\"valid_phone\"") {
+			respondWithError(r, http.StatusBadRequest, "invalid_phone")
 			return
 		}
 
 		log.Println(insertDBErr)
-		go func() {
-			sendEmailErrAdm := configs.NotifyAdmin(insertDBErr)
-			log.Println(sendEmailErrAdm)
-		}()
-		respondWithError(r, http.StatusInternalServerError, insertDBErr.Error()+"  : Error in inserting the document")
+		configs.Alerts().Report(insertDBErr)
+		respondWithError(r, http.StatusInternalServerError, "insert_failed", insertDBErr.Error())
 		return
 	}
 
-	//* Sending OTP
-	go func() {
-		if sendEmailErr := model.SendOTP(user.Email, user.OTP); sendEmailErr != nil {
-			respondWithError(r, http.StatusInternalServerError, "Internal Server Error : "+sendEmailErr.Error())
-			return
-		}
-	}()
+	//* Queueing OTP
+	if queueErr := model.DeliverOTP(ctx, qtx, created, user.OTP); queueErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", queueErr.Error())
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, db.New(configs.CONN), r, created.ID, model.AuditRegistered, nil)
 
+	if created.OtpChannel == model.OTPChannelSMS {
+		r.JSON(http.StatusCreated, responses.UserResponse{Message: "OTP has been sent to your phone"})
+		return
+	}
 	r.JSON(http.StatusCreated, responses.UserResponse{Message: "OTP has been sent to your email"})
 }
 
@@ -194,62 +269,218 @@ func Register(r *gin.Context) {
 //	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal Server Error"
 //	@Router			/auth/otp [post]
 func ValidateOTP(r *gin.Context) {
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
 	var req model.OTP
 	//* Checking for invalid json format
 	if err := r.BindJSON(&req); err != nil {
-		respondWithError(r, http.StatusBadRequest, "Invalid JSON data")
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
 		return
 	}
 
 	//* Validating if all the fields are present
 	if validationErr := validate.Struct(&req); validationErr != nil {
-		respondWithError(r, http.StatusBadRequest, "Please provide the required credentials.")
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	//* Normalizing the email so that any spelling of it finds the account
+	email, emailErr := model.NormalizeEmail(req.Email)
+	if emailErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_email")
 		return
 	}
 
 	queries := db.New(configs.CONN)
 
 	//* Checking whether user exists or not
-	user, getUserErr := queries.GetUserByEmail(ctx, req.Email)
+	user, getUserErr := queries.GetUserByEmail(ctx, email)
 	if getUserErr != nil {
-		respondWithError(r, http.StatusNotFound, "User does not exist. Please register to generate OTP.")
+		respondWithError(r, http.StatusNotFound, "user_not_found")
 		return
 	}
 
 	//* Checking if user is already verified
 	if user.Isverified {
-		respondWithError(r, http.StatusOK, "User already verified. Please login.")
+		respondWithError(r, http.StatusOK, "already_verified")
 		return
 	}
 
 	//* Validating OTP
-	if user.Otp != req.OTP {
-		respondWithError(r, http.StatusUnauthorized, "Invalid OTP")
+	if !model.CheckOTP(req.OTP, user.Otp) {
+		respondWithError(r, http.StatusUnauthorized, "invalid_otp")
 		return
 	}
 
-	//* Updating user to be verified
-	go func() {
-		updateUserErr := queries.UpdateUser(ctx, req.Email)
-		if updateUserErr != nil {
-			respondWithError(r, http.StatusInternalServerError, "Internal Server Error : "+updateUserErr.Error())
+	//* Updating user to be verified before the token is issued
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if updateUserErr := qtx.UpdateUser(ctx, user.Email); updateUserErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", updateUserErr.Error())
+		return
+	}
+	//* An OTP received by SMS also proves the phone number
+	if user.OtpChannel == model.OTPChannelSMS && user.Phone != "" {
+		if verifyPhoneErr := qtx.VerifyUserPhone(ctx, user.ID); verifyPhoneErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", verifyPhoneErr.Error())
+			return
+		}
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+	user.Isverified = true
+
+	//* Generating Token
+	amr := []string{auth.AMREmail}
+	if user.OtpChannel == model.OTPChannelSMS && user.Phone != "" {
+		amr = []string{auth.AMRSMS}
+	}
+	respondWithToken(ctx, r, queries, user, amr)
+}
+
+// checkSignupFields checks the optional username against the format rules and reserved names and
+// the custom attributes against the operator's schema, storing both in params.
+func checkSignupFields(params *db.CreateUserParams, username string, attrs map[string]interface{}) (bool, int, string, string) {
+	if username != "" {
+		normalized, usernameErr := model.NormalizeUsername(username)
+		if errors.Is(usernameErr, model.ErrReservedUsername) {
+			return false, http.StatusBadRequest, "username_reserved", ""
+		} else if errors.Is(usernameErr, model.ErrInvalidUsername) {
+			return false, http.StatusBadRequest, "invalid_username", ""
+		} else if usernameErr != nil {
+			return false, http.StatusInternalServerError, "internal_error", usernameErr.Error()
+		}
+		params.Username = pgtype.Text{String: normalized, Valid: true}
+	}
+
+	validated, attrsErr := configs.AttributeSchema().Validate(attrs)
+	if attrsErr != nil {
+		return false, http.StatusBadRequest, "invalid_attributes", attrsErr.Error()
+	}
+	encoded, encodeErr := json.Marshal(validated)
+	if encodeErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", encodeErr.Error()
+	}
+	params.Attributes = encoded
+
+	return true, 0, "", ""
+}
+
+// findUserByIdentifier looks a user up by email when identifier contains an "@", and by username
+// otherwise.
+func findUserByIdentifier(ctx context.Context, queries *db.Queries, identifier string) (db.User, error) {
+	if strings.Contains(identifier, "@") {
+		//* Normalizing the email so that any spelling of it finds the account
+		email, emailErr := model.NormalizeEmail(identifier)
+		if emailErr != nil {
+			return db.User{}, emailErr
+		}
+		return queries.GetUserByEmail(ctx, email)
+	}
+
+	return queries.GetUserByUsername(ctx, strings.TrimSpace(identifier))
+}
+
+// respondWithLogin finishes a first-factor sign-in made with the methods in amr: it answers with an
+// MFA challenge when the user has a second factor set up, and with the access token otherwise.
+func respondWithLogin(ctx context.Context, r *gin.Context, queries *db.Queries, user db.User, amr []string) {
+	if !checkSignInStatus(r, user) {
+		return
+	}
+
+	//* Asking for the second factor when TOTP or SMS codes are enabled or a passkey is registered
+	passkeys, countErr := queries.CountWebAuthnCredentials(ctx, user.ID)
+	if countErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", countErr.Error())
+		return
+	}
+	if user.MfaEnabled || passkeys > 0 || user.SmsMfaEnabled {
+		methods := []string{}
+		if user.MfaEnabled {
+			methods = append(methods, "totp", "recovery_code")
+		}
+		if passkeys > 0 {
+			methods = append(methods, "webauthn")
+		}
+		if user.SmsMfaEnabled {
+			methods = append(methods, "sms")
+		}
+
+		challenge, challengeErr := startMFAChallenge(ctx, queries, user.ID, amr)
+		if challengeErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", challengeErr.Error())
+			return
+		}
+		r.JSON(http.StatusOK, responses.UserResponse{Message: "mfa_required", Data: map[string]interface{}{"mfa_required": true, "challenge_token": challenge, "mfa_methods": methods}})
+		return
+	}
+
+	respondWithToken(ctx, r, queries, user, amr)
+}
+
+// respondWithToken completes a sign-in with the methods in amr: it cancels a pending deletion of
+// the account, records the sign-in and answers with an access token.
+func respondWithToken(ctx context.Context, r *gin.Context, queries *db.Queries, user db.User, amr []string) {
+	//* An admin may have disabled or locked the account while a second factor was pending
+	if !checkSignInStatus(r, user) {
+		return
+	}
+
+	//* Signing in during the grace period keeps the account
+	if user.DeletionScheduledFor.Valid {
+		if cancelErr := queries.CancelUserDeletion(ctx, user.ID); cancelErr != nil {
+			respondWithError(r, http.StatusInternalServerError, "internal_error", cancelErr.Error())
 			return
 		}
-	}()
+		recordAudit(ctx, queries, r, user.ID, model.AuditAccountDeletionCancelled, nil)
+	}
 
 	//* Generating Token
-	token, tokenErr := auth.GenerateJWT()
-	if tokenErr != nil {
-		respondWithError(r, http.StatusInternalServerError, "Internal Server Error : "+tokenErr.Error())
+	token, genJWTErr := auth.GenerateJWT(user.ID, amr, model.AttributeClaims(user))
+	if genJWTErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", genJWTErr.Error())
 		return
 	}
+	if loginErr := queries.RecordUserLogin(ctx, user.ID); loginErr != nil {
+		log.Println(loginErr)
+	}
+	recordAudit(ctx, queries, r, user.ID, model.AuditLogin, map[string]interface{}{"amr": amr})
 
 	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"token": token}})
 }
 
-func respondWithError(ctx *gin.Context, statusCode int, message string) {
+// checkSignInStatus answers with an error when user's account status rules out signing in. Pending
+// accounts are verified by signing in and deleted ones restored during the grace period, so only
+// disabled and locked accounts are turned away.
+func checkSignInStatus(r *gin.Context, user db.User) bool {
+	switch user.Status {
+	case db.AccountStatusDisabled, db.AccountStatusLocked:
+		statusCode, code, _ := middleware.AccountStatusError(user.Status)
+		respondWithError(r, statusCode, code)
+		return false
+	}
+
+	return true
+}
+
+// respondWithError answers with the message for code in the client's language. A detail, such as
+// the underlying error of an internal error, is appended to the message untranslated.
+func respondWithError(ctx *gin.Context, statusCode int, code string, detail ...string) {
+	message := i18n.Message(middleware.Locale(ctx), code)
+	if len(detail) > 0 && detail[0] != "" {
+		message += " : " + detail[0]
+	}
+
 	ctx.JSON(statusCode, responses.UserResponse{
 		Message: message,
+		Code:    code,
 	})
 }
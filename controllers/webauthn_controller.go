@@ -0,0 +1,612 @@
+package controller
+
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/middleware"
+	model "Gin/Basics/models"
+	"Gin/Basics/responses"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	webAuthnPurposeRegister = "register"
+	webAuthnPurposeLogin    = "login"
+	webAuthnPurposeMFA      = "mfa"
+	webAuthnPurposeReauth   = "reauth"
+
+	webAuthnSessionLifetime = 5 * time.Minute
+)
+
+var errWebAuthnSession = errors.New("invalid or expired passkey session")
+
+// ^ BeginWebAuthnRegistration :
+//
+//	@Summary		Passkey registration start route
+//	@Description	Starts registering a passkey for the signed-in user. Pass options to navigator.credentials.create() and send the result to the finish route with the session_id.
+//	@Tags			webauthn
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	responses.UserResponse_doc	"options and session_id"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/webauthn/register/begin [post]
+func BeginWebAuthnRegistration(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+	queries := db.New(configs.CONN)
+
+	relyingParty, rpErr := auth.NewWebAuthn()
+	if rpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", rpErr.Error())
+		return
+	}
+	webAuthnUser, loadErr := loadWebAuthnUser(ctx, queries, user)
+	if loadErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", loadErr.Error())
+		return
+	}
+
+	//* Excluding existing credentials so the same authenticator isn't registered twice
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(webAuthnUser.Credentials))
+	for _, credential := range webAuthnUser.Credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	options, session, beginErr := relyingParty.BeginRegistration(webAuthnUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if beginErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", beginErr.Error())
+		return
+	}
+
+	sessionID, saveErr := saveWebAuthnSession(ctx, queries, webAuthnPurposeRegister, user.ID, session)
+	if saveErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", saveErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"options": options, "session_id": sessionID}})
+}
+
+// ^ FinishWebAuthnRegistration :
+//
+//	@Summary		Passkey registration finish route
+//	@Description	Verifies the attestation returned by navigator.credentials.create() and stores the new passkey.
+//	@Tags			webauthn
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			session_id	query		string						true	"Session ID from the begin route"
+//	@Param			name		query		string						false	"Name to show for the passkey"
+//	@Param			Body		body		object						true	"PublicKeyCredential from navigator.credentials.create()"
+//	@Success		201			{object}	responses.UserResponse_doc	"Passkey registered"
+//	@Failure		400			{object}	responses.ErrorResponse_doc	"Invalid or expired passkey session"
+//	@Failure		401			{object}	responses.ErrorResponse_doc	"Passkey verification failed, Reauthentication required"
+//	@Failure		409			{object}	responses.ErrorResponse_doc	"Passkey already registered"
+//	@Failure		500			{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/webauthn/register/finish [post]
+func FinishWebAuthnRegistration(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+	queries := db.New(configs.CONN)
+
+	row, session, sessionErr := takeWebAuthnSession(ctx, queries, r.Query("session_id"), webAuthnPurposeRegister)
+	if sessionErr != nil || row.UserID.Int64 != user.ID {
+		respondWithError(r, http.StatusBadRequest, "invalid_passkey_session")
+		return
+	}
+
+	relyingParty, rpErr := auth.NewWebAuthn()
+	if rpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", rpErr.Error())
+		return
+	}
+	webAuthnUser, loadErr := loadWebAuthnUser(ctx, queries, user)
+	if loadErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", loadErr.Error())
+		return
+	}
+
+	credential, finishErr := relyingParty.FinishRegistration(webAuthnUser, session, r.Request)
+	if finishErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "passkey_verification_failed", webAuthnErrorDetails(finishErr))
+		return
+	}
+
+	encoded, encodeErr := json.Marshal(credential)
+	if encodeErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", encodeErr.Error())
+		return
+	}
+
+	stored, insertDBErr := queries.CreateWebAuthnCredential(ctx, db.CreateWebAuthnCredentialParams{
+		UserID:       user.ID,
+		CredentialID: credential.ID,
+		Name:         r.Query("name"),
+		Credential:   encoded,
+		SignCount:    int64(credential.Authenticator.SignCount),
+	})
+	if insertDBErr != nil {
+		if strings.HasPrefix(insertDBErr.Error(), "ERROR: duplicate key") {
+			respondWithError(r, http.StatusConflict, "passkey_already_registered")
+			return
+		}
+		respondWithError(r, http.StatusInternalServerError, "internal_error", insertDBErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditPasskeyRegistered, map[string]interface{}{"credential_id": stored.ID})
+
+	r.JSON(http.StatusCreated, responses.UserResponse{Message: "Passkey registered", Data: map[string]interface{}{"credential": webAuthnCredentialResponse(stored)}})
+}
+
+// ^ ListWebAuthnCredentials :
+//
+//	@Summary		Passkey list route
+//	@Description	Lists the signed-in user's passkeys.
+//	@Tags			webauthn
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	responses.UserResponse_doc	"credentials"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/webauthn/credentials [get]
+func ListWebAuthnCredentials(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	stored, listErr := db.New(configs.CONN).ListWebAuthnCredentials(ctx, user.ID)
+	if listErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", listErr.Error())
+		return
+	}
+
+	credentials := make([]map[string]interface{}, 0, len(stored))
+	for _, row := range stored {
+		credentials = append(credentials, webAuthnCredentialResponse(row))
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"credentials": credentials}})
+}
+
+// ^ DeleteWebAuthnCredential :
+//
+//	@Summary		Passkey removal route
+//	@Description	Removes one of the signed-in user's passkeys.
+//	@Tags			webauthn
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int							true	"Credential ID"
+//	@Success		200	{object}	responses.UserResponse_doc	"Passkey removed"
+//	@Failure		401	{object}	responses.ErrorResponse_doc	"Invalid or expired token, Reauthentication required"
+//	@Failure		404	{object}	responses.ErrorResponse_doc	"Passkey not found"
+//	@Failure		409	{object}	responses.ErrorResponse_doc	"This is the last way to sign in to the account"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/webauthn/credentials/{id} [delete]
+func DeleteWebAuthnCredential(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	user := middleware.CurrentUser(r)
+
+	id, parseErr := strconv.ParseInt(r.Param("id"), 10, 64)
+	if parseErr != nil {
+		respondWithError(r, http.StatusNotFound, "passkey_not_found")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	tx, txErr := configs.CONN.Begin(ctx)
+	if txErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", txErr.Error())
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := queries.WithTx(tx)
+
+	if _, lockErr := qtx.LockUserForUpdate(ctx, user.ID); lockErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", lockErr.Error())
+		return
+	}
+	deleted, deleteErr := qtx.DeleteWebAuthnCredential(ctx, db.DeleteWebAuthnCredentialParams{ID: id, UserID: user.ID})
+	if deleteErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", deleteErr.Error())
+		return
+	}
+	if deleted == 0 {
+		respondWithError(r, http.StatusNotFound, "passkey_not_found")
+		return
+	}
+	if ok, status, code, detail := checkSignInMethodLeft(ctx, qtx, user); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+	if commitErr := tx.Commit(ctx); commitErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", commitErr.Error())
+		return
+	}
+
+	recordAudit(ctx, queries, r, user.ID, model.AuditPasskeyRemoved, map[string]interface{}{"credential_id": id})
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "Passkey removed"})
+}
+
+// ^ BeginWebAuthnLogin :
+//
+//	@Summary		Passkey login start route
+//	@Description	Starts a passwordless sign-in with a discoverable passkey. Pass options to navigator.credentials.get() and send the result to the finish route with the session_id.
+//	@Tags			webauthn
+//	@Produce		json
+//	@Success		200	{object}	responses.UserResponse_doc	"options and session_id"
+//	@Failure		500	{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/webauthn/login/begin [post]
+func BeginWebAuthnLogin(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+
+	relyingParty, rpErr := auth.NewWebAuthn()
+	if rpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", rpErr.Error())
+		return
+	}
+
+	//* A passkey on its own must prove the user, not just their presence
+	options, session, beginErr := relyingParty.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if beginErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", beginErr.Error())
+		return
+	}
+
+	sessionID, saveErr := saveWebAuthnSession(ctx, queries, webAuthnPurposeLogin, 0, session)
+	if saveErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", saveErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"options": options, "session_id": sessionID}})
+}
+
+// ^ FinishWebAuthnLogin :
+//
+//	@Summary		Passkey login finish route
+//	@Description	Verifies the assertion returned by navigator.credentials.get() and signs the user in.
+//	@Tags			webauthn
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id	query		string						true	"Session ID from the begin route"
+//	@Param			Body		body		object						true	"PublicKeyCredential from navigator.credentials.get()"
+//	@Success		200			{object}	responses.UserResponse_doc	"Successful response"
+//	@Failure		400			{object}	responses.ErrorResponse_doc	"Invalid or expired passkey session"
+//	@Failure		401			{object}	responses.ErrorResponse_doc	"Passkey verification failed"
+//	@Failure		500			{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/webauthn/login/finish [post]
+func FinishWebAuthnLogin(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+
+	_, session, sessionErr := takeWebAuthnSession(ctx, queries, r.Query("session_id"), webAuthnPurposeLogin)
+	if sessionErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_passkey_session")
+		return
+	}
+
+	relyingParty, rpErr := auth.NewWebAuthn()
+	if rpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", rpErr.Error())
+		return
+	}
+
+	parsed, parseErr := protocol.ParseCredentialRequestResponse(r.Request)
+	if parseErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "passkey_verification_failed", webAuthnErrorDetails(parseErr))
+		return
+	}
+
+	//* Resolving the user from the user handle the authenticator returned
+	var user db.User
+	credential, validateErr := relyingParty.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, handleErr := auth.UserIDFromWebAuthnHandle(userHandle)
+		if handleErr != nil {
+			return nil, handleErr
+		}
+		found, userErr := queries.GetUserByID(ctx, userID)
+		if userErr != nil {
+			return nil, userErr
+		}
+		user = found
+		return loadWebAuthnUser(ctx, queries, found)
+	}, session, parsed)
+	if validateErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "passkey_verification_failed", webAuthnErrorDetails(validateErr))
+		return
+	}
+
+	if ok, status, code, detail := recordWebAuthnUse(ctx, queries, credential); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	if !user.Isverified {
+		respondWithError(r, http.StatusUnprocessableEntity, "email_not_verified")
+		return
+	}
+
+	respondWithToken(ctx, r, queries, user, []string{auth.AMRHardwareKey})
+}
+
+// ^ BeginWebAuthnMFA :
+//
+//	@Summary		Passkey second factor start route
+//	@Description	Starts a passkey assertion for the user of an MFA challenge token returned by login.
+//	@Tags			mfa
+//	@Accept			json
+//	@Produce		json
+//	@Param			Body	body		model.WebAuthnMFABegin		true	"Challenge token"
+//	@Success		200		{object}	responses.UserResponse_doc	"options and session_id"
+//	@Failure		400		{object}	responses.ErrorResponse_doc	"Invalid JSON data, No passkeys registered"
+//	@Failure		401		{object}	responses.ErrorResponse_doc	"Invalid or expired challenge token"
+//	@Failure		500		{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/mfa/webauthn/begin [post]
+func BeginWebAuthnMFA(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	var req model.WebAuthnMFABegin
+
+	//* Checking for invalid json format
+	if err := r.BindJSON(&req); err != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_json")
+		return
+	}
+
+	//* Validating if all the fields are present
+	if validationErr := validate.Struct(&req); validationErr != nil {
+		respondWithError(r, http.StatusBadRequest, "missing_credentials")
+		return
+	}
+
+	userID, jti, _, challengeErr := auth.ValidateMFAChallenge(req.ChallengeToken)
+	if challengeErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+
+	queries := db.New(configs.CONN)
+	user, userErr := queries.GetUserByID(ctx, userID)
+	if userErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+	if ok, status, code, detail := checkMFAChallenge(ctx, queries, user, jti); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	relyingParty, rpErr := auth.NewWebAuthn()
+	if rpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", rpErr.Error())
+		return
+	}
+	webAuthnUser, loadErr := loadWebAuthnUser(ctx, queries, user)
+	if loadErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", loadErr.Error())
+		return
+	}
+	if len(webAuthnUser.Credentials) == 0 {
+		respondWithError(r, http.StatusBadRequest, "no_passkeys")
+		return
+	}
+
+	options, session, beginErr := relyingParty.BeginLogin(webAuthnUser)
+	if beginErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", beginErr.Error())
+		return
+	}
+
+	sessionID, saveErr := saveWebAuthnSession(ctx, queries, webAuthnPurposeMFA, user.ID, session)
+	if saveErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", saveErr.Error())
+		return
+	}
+
+	r.JSON(http.StatusOK, responses.UserResponse{Message: "success", Data: map[string]interface{}{"options": options, "session_id": sessionID}})
+}
+
+// ^ FinishWebAuthnMFA :
+//
+//	@Summary		Passkey second factor finish route
+//	@Description	Verifies the passkey assertion for an MFA challenge and returns the access token.
+//	@Tags			mfa
+//	@Accept			json
+//	@Produce		json
+//	@Param			session_id		query		string						true	"Session ID from the begin route"
+//	@Param			challenge_token	query		string						true	"Challenge token returned by login"
+//	@Param			Body			body		object						true	"PublicKeyCredential from navigator.credentials.get()"
+//	@Success		200				{object}	responses.UserResponse_doc	"Successful response"
+//	@Failure		400				{object}	responses.ErrorResponse_doc	"Invalid or expired passkey session"
+//	@Failure		401				{object}	responses.ErrorResponse_doc	"Invalid, expired or already used challenge token, Passkey verification failed"
+//	@Failure		500				{object}	responses.ErrorResponse_doc	"Internal server error"
+//	@Router			/auth/mfa/webauthn/finish [post]
+func FinishWebAuthnMFA(r *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	queries := db.New(configs.CONN)
+
+	//* The challenge token is needed again to carry the first factor's methods into the access token
+	challengeUserID, jti, amr, challengeErr := auth.ValidateMFAChallenge(r.Query("challenge_token"))
+	if challengeErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "invalid_challenge_token")
+		return
+	}
+
+	row, session, sessionErr := takeWebAuthnSession(ctx, queries, r.Query("session_id"), webAuthnPurposeMFA)
+	if sessionErr != nil || row.UserID.Int64 != challengeUserID {
+		respondWithError(r, http.StatusBadRequest, "invalid_passkey_session")
+		return
+	}
+
+	user, userErr := queries.GetUserByID(ctx, row.UserID.Int64)
+	if userErr != nil {
+		respondWithError(r, http.StatusBadRequest, "invalid_passkey_session")
+		return
+	}
+	if ok, status, code, detail := checkMFAChallenge(ctx, queries, user, jti); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	relyingParty, rpErr := auth.NewWebAuthn()
+	if rpErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", rpErr.Error())
+		return
+	}
+	webAuthnUser, loadErr := loadWebAuthnUser(ctx, queries, user)
+	if loadErr != nil {
+		respondWithError(r, http.StatusInternalServerError, "internal_error", loadErr.Error())
+		return
+	}
+
+	credential, finishErr := relyingParty.FinishLogin(webAuthnUser, session, r.Request)
+	if finishErr != nil {
+		respondWithError(r, http.StatusUnauthorized, "passkey_verification_failed", webAuthnErrorDetails(finishErr))
+		return
+	}
+
+	if ok, status, code, detail := recordWebAuthnUse(ctx, queries, credential); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+	if ok, status, code, detail := spendMFAChallenge(ctx, queries, user.ID, jti); !ok {
+		respondWithError(r, status, code, detail)
+		return
+	}
+
+	respondWithToken(ctx, r, queries, user, append(amr, auth.AMRHardwareKey))
+}
+
+func loadWebAuthnUser(ctx context.Context, queries *db.Queries, user db.User) (*auth.WebAuthnUser, error) {
+	stored, err := queries.ListWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewWebAuthnUser(user, stored)
+}
+
+// saveWebAuthnSession stores the ceremony state and returns the ID the client sends back to finish it.
+// A userID of 0 stores a session that isn't bound to a user yet (discoverable login).
+func saveWebAuthnSession(ctx context.Context, queries *db.Queries, purpose string, userID int64, session *webauthn.SessionData) (string, error) {
+	//* Clearing out abandoned ceremonies
+	if err := queries.DeleteExpiredWebAuthnSessions(ctx); err != nil {
+		return "", err
+	}
+
+	idBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(idBytes)
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	err = queries.CreateWebAuthnSession(ctx, db.CreateWebAuthnSessionParams{
+		ID:        id,
+		UserID:    pgtype.Int8{Int64: userID, Valid: userID != 0},
+		Purpose:   purpose,
+		Data:      data,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(webAuthnSessionLifetime), Valid: true},
+	})
+
+	return id, err
+}
+
+// takeWebAuthnSession deletes and returns the ceremony state, so that each session can be finished only once.
+func takeWebAuthnSession(ctx context.Context, queries *db.Queries, id string, purpose string) (db.WebauthnSession, webauthn.SessionData, error) {
+	var session webauthn.SessionData
+
+	row, err := queries.TakeWebAuthnSession(ctx, db.TakeWebAuthnSessionParams{ID: id, Purpose: purpose})
+	if err != nil {
+		return row, session, err
+	}
+	if row.ExpiresAt.Time.Before(time.Now()) {
+		return row, session, errWebAuthnSession
+	}
+
+	err = json.Unmarshal(row.Data, &session)
+
+	return row, session, err
+}
+
+// recordWebAuthnUse stores the authenticator's new sign counter after a successful assertion.
+// An assertion whose counter went backwards is rejected, as the authenticator may have been cloned.
+func recordWebAuthnUse(ctx context.Context, queries *db.Queries, credential *webauthn.Credential) (bool, int, string, string) {
+	if credential.Authenticator.CloneWarning {
+		return false, http.StatusUnauthorized, "passkey_verification_failed", "sign counter did not increase"
+	}
+
+	stored, getErr := queries.GetWebAuthnCredentialByCredentialID(ctx, credential.ID)
+	if getErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", getErr.Error()
+	}
+
+	encoded, encodeErr := json.Marshal(credential)
+	if encodeErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", encodeErr.Error()
+	}
+
+	if updateErr := queries.UpdateWebAuthnCredentialUsage(ctx, db.UpdateWebAuthnCredentialUsageParams{
+		ID:         stored.ID,
+		Credential: encoded,
+		SignCount:  int64(credential.Authenticator.SignCount),
+	}); updateErr != nil {
+		return false, http.StatusInternalServerError, "internal_error", updateErr.Error()
+	}
+
+	return true, 0, "", ""
+}
+
+func webAuthnCredentialResponse(row db.WebauthnCredential) map[string]interface{} {
+	credential := map[string]interface{}{
+		"id":         row.ID,
+		"name":       row.Name,
+		"sign_count": row.SignCount,
+		"created_at": row.CreatedAt.Time,
+	}
+	if row.LastUsedAt.Valid {
+		credential["last_used_at"] = row.LastUsedAt.Time
+	}
+
+	return credential
+}
+
+func webAuthnErrorDetails(err error) string {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) && protocolErr.Details != "" {
+		return protocolErr.Details
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+func TestRecordWebAuthnUseRejectsCloneWarning(t *testing.T) {
+	credential := &webauthn.Credential{Authenticator: webauthn.Authenticator{SignCount: 10, CloneWarning: true}}
+
+	//* The check comes before any database access
+	ok, status, code, _ := recordWebAuthnUse(context.Background(), nil, credential)
+	if ok || status != http.StatusUnauthorized || code != "passkey_verification_failed" {
+		t.Fatalf("got %v, %d, %q", ok, status, code)
+	}
+}
+
+func TestDeleteWebAuthnCredentialKeepsLastSignInMethod(t *testing.T) {
+	queries := testQueries(t)
+	ctx := context.Background()
+	t.Setenv("MAGIC_LINK_ENABLED", "false")
+
+	for _, tc := range []struct {
+		name       string
+		password   string
+		wantStatus int
+		wantLeft   int64
+	}{
+		{name: "passwordless", password: "", wantStatus: http.StatusConflict, wantLeft: 1},
+		{name: "with password", password: "bcrypt-hash", wantStatus: http.StatusOK, wantLeft: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := queries.CreateUser(ctx, db.CreateUserParams{
+				Name:       "Ada",
+				Email:      tc.name + "@example.com",
+				Password:   tc.password,
+				OtpChannel: "email",
+				Locale:     "en",
+				Attributes: []byte("{}"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			passkey, err := queries.CreateWebAuthnCredential(ctx, db.CreateWebAuthnCredentialParams{
+				UserID:       user.ID,
+				CredentialID: []byte(tc.name),
+				Name:         "Laptop",
+				Credential:   []byte("{}"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			r, _ := gin.CreateTestContext(recorder)
+			r.Request = httptest.NewRequest(http.MethodDelete, "/", nil)
+			r.Params = gin.Params{{Key: "id", Value: strconv.FormatInt(passkey.ID, 10)}}
+			r.Set("user", user)
+
+			DeleteWebAuthnCredential(r)
+			if recorder.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tc.wantStatus, recorder.Body)
+			}
+
+			left, err := queries.CountWebAuthnCredentials(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if left != tc.wantLeft {
+				t.Fatalf("%d passkeys left, want %d", left, tc.wantLeft)
+			}
+		})
+	}
+}
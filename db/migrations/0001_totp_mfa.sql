@@ -0,0 +1,6 @@
+-- TOTP two-factor authentication.
+-- totp_secret holds the AES-GCM encrypted, base64 encoded secret.
+ALTER TABLE users
+    ADD COLUMN mfa_enabled BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN totp_secret text NOT NULL DEFAULT '',
+    ADD COLUMN totp_last_counter bigint NOT NULL DEFAULT 0;
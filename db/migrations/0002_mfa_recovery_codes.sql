@@ -0,0 +1,10 @@
+-- One-time MFA recovery codes, stored as keyed hashes.
+CREATE TABLE mfa_recovery_codes (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash  text NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id);
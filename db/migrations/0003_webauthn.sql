@@ -0,0 +1,23 @@
+-- WebAuthn / passkey credentials and in-flight ceremony state.
+-- credential holds the JSON encoded webauthn.Credential; sign_count mirrors its authenticator counter.
+CREATE TABLE webauthn_credentials (
+    id            bigserial PRIMARY KEY,
+    user_id       bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id bytea UNIQUE NOT NULL,
+    name          text NOT NULL DEFAULT '',
+    credential    jsonb NOT NULL,
+    sign_count    bigint NOT NULL DEFAULT 0,
+    created_at    timestamptz NOT NULL DEFAULT now(),
+    last_used_at  timestamptz
+);
+
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+-- Sessions are single use: they are deleted when the ceremony is finished.
+CREATE TABLE webauthn_sessions (
+    id         text PRIMARY KEY,
+    user_id    bigint REFERENCES users(id) ON DELETE CASCADE,
+    purpose    text NOT NULL,
+    data       jsonb NOT NULL,
+    expires_at timestamptz NOT NULL
+);
@@ -0,0 +1,7 @@
+-- Magic-link login tokens. id is the jti of the signed link token; a link works once.
+CREATE TABLE magic_links (
+    id         text PRIMARY KEY,
+    user_id    bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz
+);
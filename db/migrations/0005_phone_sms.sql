@@ -0,0 +1,11 @@
+-- Phone numbers (E.164) and SMS delivery of OTP and MFA codes.
+-- phone_otp holds the keyed hash of the last code sent by SMS.
+ALTER TABLE users
+    ADD COLUMN phone text NOT NULL DEFAULT '',
+    ADD COLUMN phone_verified BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN phone_otp text NOT NULL DEFAULT '',
+    ADD COLUMN phone_otp_expires_at timestamptz,
+    ADD COLUMN otp_channel text NOT NULL DEFAULT 'email',
+    ADD COLUMN sms_mfa_enabled BOOLEAN NOT NULL DEFAULT false,
+    ADD CONSTRAINT valid_phone CHECK (phone = '' OR phone ~ '^\+[1-9][0-9]{1,14}$'),
+    ADD CONSTRAINT valid_otp_channel CHECK (otp_channel IN ('email', 'sms'));
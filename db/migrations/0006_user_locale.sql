@@ -0,0 +1,3 @@
+-- Preferred language of the user, used to pick localized email templates.
+ALTER TABLE users
+    ADD COLUMN locale text NOT NULL DEFAULT 'en';
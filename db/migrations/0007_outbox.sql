@@ -0,0 +1,21 @@
+-- Outbox of email and SMS messages, written in the same transaction as the change that triggers
+-- them and delivered by the outbox worker pool. While a message is 'sending', next_attempt_at is
+-- the end of the worker's lease; a message whose lease ran out is picked up again.
+CREATE TABLE outbox_messages (
+    id              bigserial PRIMARY KEY,
+    channel         text NOT NULL,
+    recipient       text NOT NULL,
+    subject         text NOT NULL DEFAULT '',
+    body_text       text NOT NULL DEFAULT '',
+    body_html       text NOT NULL DEFAULT '',
+    status          text NOT NULL DEFAULT 'pending',
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error      text NOT NULL DEFAULT '',
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    sent_at         timestamptz,
+    CONSTRAINT valid_outbox_channel CHECK (channel IN ('email', 'sms')),
+    CONSTRAINT valid_outbox_status CHECK (status IN ('pending', 'sending', 'sent', 'dead'))
+);
+
+CREATE INDEX outbox_messages_due_idx ON outbox_messages (next_attempt_at) WHERE status IN ('pending', 'sending');
@@ -0,0 +1,5 @@
+-- Role of each user. Admins can use the /admin routes; grant it with
+--   UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users
+    ADD COLUMN role text NOT NULL DEFAULT 'user',
+    ADD CONSTRAINT valid_role CHECK (role IN ('user', 'admin'));
@@ -0,0 +1,14 @@
+-- Pending email-address changes, at most one per user. code_hash is the keyed hash of the code
+-- sent to new_email; the address is only swapped once that code is confirmed.
+CREATE TABLE email_changes (
+    user_id    bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    new_email  VARCHAR(255) NOT NULL,
+    code_hash  text NOT NULL,
+    attempts   integer NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Access tokens issued before sessions_revoked_at are rejected.
+ALTER TABLE users
+    ADD COLUMN sessions_revoked_at timestamptz;
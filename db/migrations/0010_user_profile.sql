@@ -0,0 +1,4 @@
+-- Profile fields editable through PATCH /me. timezone is an IANA zone name such as "Europe/Madrid".
+ALTER TABLE users
+    ADD COLUMN display_name text NOT NULL DEFAULT '',
+    ADD COLUMN timezone text NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,15 @@
+-- Security-relevant actions on accounts. user_id is the account acted on and actor_id whoever
+-- acted, which differs from user_id for admin actions. Events outlive the account: hard deletion
+-- clears both ids, and the erasure path scrubs what identifies the person first.
+CREATE TABLE audit_events (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint REFERENCES users(id) ON DELETE SET NULL,
+    actor_id   bigint REFERENCES users(id) ON DELETE SET NULL,
+    action     text NOT NULL,
+    ip         text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    details    jsonb NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (user_id, id);
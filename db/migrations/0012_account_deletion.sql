@@ -0,0 +1,8 @@
+-- Self-service account deletion. deleted_at marks the request; the row and everything that
+-- references it are hard-deleted once deletion_scheduled_for has passed, unless the user signs
+-- in again before then.
+ALTER TABLE users
+    ADD COLUMN deleted_at timestamptz,
+    ADD COLUMN deletion_scheduled_for timestamptz;
+
+CREATE INDEX users_deletion_scheduled_for_idx ON users (deletion_scheduled_for) WHERE deletion_scheduled_for IS NOT NULL;
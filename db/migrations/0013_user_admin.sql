@@ -0,0 +1,7 @@
+-- Columns used by the admin user-management API. Accounts that existed before this migration get
+-- its time as created_at. A disabled account cannot sign in until an admin enables it again.
+ALTER TABLE users
+    ADD COLUMN disabled_at timestamptz,
+    ADD COLUMN created_at timestamptz NOT NULL DEFAULT now();
+
+CREATE INDEX users_created_at_idx ON users (created_at, id);
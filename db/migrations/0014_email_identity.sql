@@ -0,0 +1,37 @@
+-- Email addresses identify an account regardless of case. Stored domains are lowercased like
+-- NormalizeEmail does; their punycode form needs no rewrite, as valid_email only admits ASCII.
+--
+-- Accounts whose addresses differ only in case collide under the new unique index. They are listed
+-- as warnings and the migration stops, so an admin can decide which account to keep (e.g. with
+-- DELETE /admin/users/{id}) before running it again. To list them without migrating:
+--
+--   SELECT lower(email), array_agg(id ORDER BY id) FROM users GROUP BY 1 HAVING count(*) > 1;
+DO $$
+DECLARE
+    collision record;
+    collisions integer := 0;
+BEGIN
+    FOR collision IN
+        SELECT lower(email) AS email, array_agg(id ORDER BY id) AS user_ids, array_agg(email ORDER BY id) AS spellings
+        FROM users
+        GROUP BY lower(email)
+        HAVING count(*) > 1
+        ORDER BY lower(email)
+    LOOP
+        RAISE WARNING 'email collision: % is used by users % as %', collision.email, collision.user_ids, collision.spellings;
+        collisions := collisions + 1;
+    END LOOP;
+
+    IF collisions > 0 THEN
+        RAISE EXCEPTION '% email address(es) belong to more than one account', collisions
+            USING HINT = 'Keep one account per address listed above, then run this migration again.';
+    END IF;
+END $$;
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+
+UPDATE users
+SET email = split_part(email, '@', 1) || '@' || lower(split_part(email, '@', 2))
+WHERE split_part(email, '@', 2) <> lower(split_part(email, '@', 2));
+
+CREATE UNIQUE INDEX users_email_lower_key ON users (lower(email));
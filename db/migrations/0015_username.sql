@@ -0,0 +1,5 @@
+-- Optional handle users can sign in with instead of their email. Usernames are stored lowercased;
+-- the index also keeps them unique regardless of how they were written before that rule.
+ALTER TABLE users ADD COLUMN username text;
+
+CREATE UNIQUE INDEX users_username_lower_key ON users (lower(username));
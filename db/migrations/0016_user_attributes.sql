@@ -0,0 +1,2 @@
+-- Custom attributes defined by USER_ATTRIBUTES_SCHEMA, e.g. {"company": "Acme", "marketing_consent": true}.
+ALTER TABLE users ADD COLUMN attributes jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,17 @@
+-- Invitations let admins bring in users who then sign up without an OTP. Only a hash of the
+-- emailed token is stored; resending replaces it. An address has at most one open invitation.
+CREATE TABLE invitations (
+    id               bigserial PRIMARY KEY,
+    email            text NOT NULL,
+    name             text NOT NULL DEFAULT '',
+    locale           text NOT NULL DEFAULT 'en',
+    token_hash       text NOT NULL UNIQUE,
+    invited_by       bigint REFERENCES users(id) ON DELETE SET NULL,
+    expires_at       timestamptz NOT NULL,
+    accepted_at      timestamptz,
+    accepted_user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at       timestamptz,
+    created_at       timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX invitations_open_email_key ON invitations (lower(email)) WHERE accepted_at IS NULL AND revoked_at IS NULL;
@@ -0,0 +1,37 @@
+-- Lifecycle columns for support questions, and an explicit account status that sign-in and the
+-- auth middleware enforce:
+--   pending   registered, email not verified yet
+--   active    verified and usable
+--   disabled  turned off by an admin
+--   locked    temporarily blocked by an admin, e.g. while a compromise is investigated
+--   deleted   deletion requested; signing in again during the grace period restores the account
+-- updated_at follows every change except a sign-in merely moving last_login_at. verified_at is
+-- unknown for accounts verified before this migration and stays NULL for them.
+CREATE TYPE account_status AS ENUM ('pending', 'active', 'disabled', 'locked', 'deleted');
+
+ALTER TABLE users
+    ADD COLUMN status account_status NOT NULL DEFAULT 'pending',
+    ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN last_login_at timestamptz,
+    ADD COLUMN verified_at timestamptz;
+
+UPDATE users
+SET status = CASE
+    WHEN deleted_at IS NOT NULL THEN 'deleted'
+    WHEN disabled_at IS NOT NULL THEN 'disabled'
+    WHEN isverified THEN 'active'
+    ELSE 'pending'
+END::account_status;
+
+CREATE FUNCTION users_touch_updated_at() RETURNS trigger AS $$
+BEGIN
+    IF to_jsonb(NEW) - 'last_login_at' - 'updated_at' IS DISTINCT FROM to_jsonb(OLD) - 'last_login_at' - 'updated_at' THEN
+        NEW.updated_at := now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_touch_updated_at
+    BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION users_touch_updated_at();
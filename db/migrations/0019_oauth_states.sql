@@ -0,0 +1,9 @@
+-- Sign-ins through external identity providers that have been started but not finished. id is the
+-- OAuth state parameter; a state is deleted when its callback arrives, so it works once.
+CREATE TABLE oauth_states (
+    id            text PRIMARY KEY,
+    provider      text NOT NULL,
+    nonce         text NOT NULL,
+    code_verifier text NOT NULL,
+    expires_at    timestamptz NOT NULL
+);
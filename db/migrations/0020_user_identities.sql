@@ -0,0 +1,21 @@
+-- Accounts at external identity providers that users sign in with. (provider, subject) identifies
+-- the account at the provider; email is the address it last reported, kept for display.
+CREATE TABLE user_identities (
+    id           bigserial PRIMARY KEY,
+    user_id      bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider     text NOT NULL,
+    subject      text NOT NULL,
+    email        text NOT NULL DEFAULT '',
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    last_used_at timestamptz,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+-- A sign-in started by a signed-in user links the identity to them instead.
+ALTER TABLE oauth_states ADD COLUMN user_id bigint REFERENCES users(id) ON DELETE CASCADE;
+
+-- Accounts created through a provider before this migration have a random password nobody knows
+-- and no identity here, as the provider's subject was not recorded: they sign in by magic link or
+-- password reset and link the provider again. Accounts created from now on have no password.
@@ -0,0 +1,7 @@
+-- SAML sign-ins that have been started but not finished. id is the ID of the AuthnRequest, which the
+-- IdP's response must answer; a request is deleted when its response arrives, so a response works once.
+CREATE TABLE saml_requests (
+    id         text PRIMARY KEY,
+    connection text NOT NULL,
+    expires_at timestamptz NOT NULL
+);
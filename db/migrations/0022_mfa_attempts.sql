@@ -0,0 +1,26 @@
+-- Wrong second-factor codes in a row are counted per user; too many lock the user's second factors
+-- until mfa_locked_until, so that a 6-digit code cannot be guessed.
+ALTER TABLE users
+    ADD COLUMN mfa_failed_attempts integer NOT NULL DEFAULT 0,
+    ADD COLUMN mfa_locked_until timestamptz;
+
+-- MFA challenges issued by sign-ins that wait for a second factor. id is the jti of the challenge
+-- token; a challenge is deleted when a second factor completes it, so a token signs in once.
+CREATE TABLE mfa_challenges (
+    id         text PRIMARY KEY,
+    user_id    bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX mfa_challenges_user_id_idx ON mfa_challenges (user_id);
+
+-- Counting wrong codes does not change the user as far as updated_at is concerned.
+CREATE OR REPLACE FUNCTION users_touch_updated_at() RETURNS trigger AS $$
+BEGIN
+    IF to_jsonb(NEW) - 'last_login_at' - 'updated_at' - 'mfa_failed_attempts' - 'mfa_locked_until'
+        IS DISTINCT FROM to_jsonb(OLD) - 'last_login_at' - 'updated_at' - 'mfa_failed_attempts' - 'mfa_locked_until' THEN
+        NEW.updated_at := now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -1,13 +1,490 @@
 -- name: GetUserByEmail :one
 SELECT * FROM users
-WHERE email = $1 LIMIT 1;
+WHERE lower(email) = lower(@email::text) LIMIT 1;
 
 -- name: UpdateUser :exec
 UPDATE users
-SET isverified = TRUE
-WHERE email = $1;
+SET isverified = TRUE, verified_at = COALESCE(verified_at, now()),
+    status = CASE WHEN status = 'pending' THEN 'active' ELSE status END
+WHERE lower(email) = lower(@email::text);
+
+-- name: GetUserByUsername :one
+SELECT * FROM users
+WHERE lower(username) = lower(@username::text) LIMIT 1;
 
 -- name: CreateUser :one
-INSERT INTO users (name, email, password, isverified, otp)
-VALUES ($1, $2, $3, false, $4)
+INSERT INTO users (name, email, password, isverified, otp, phone, otp_channel, locale, username, attributes)
+VALUES ($1, $2, $3, false, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: UpdateUserOTP :exec
+UPDATE users
+SET otp = @otp
+WHERE lower(email) = lower(@email::text);
+
+-- name: ListUsersWithPlaintextOTP :many
+SELECT email, otp FROM users
+WHERE otp <> '' AND otp NOT LIKE 'hmac-sha256$%';
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1 LIMIT 1;
+
+-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_last_counter = 0
+WHERE id = $1;
+
+-- name: EnableUserMFA :exec
+UPDATE users
+SET mfa_enabled = TRUE
+WHERE id = $1;
+
+-- name: ConsumeTOTPCounter :one
+-- Records the time step of a used TOTP code, unless that step or a later one has been used already,
+-- so that two requests racing with the same code cannot both succeed.
+UPDATE users
+SET totp_last_counter = @counter
+WHERE id = @id AND totp_last_counter < @counter
+RETURNING id;
+
+-- name: RecordMFAFailure :one
+-- Counts a wrong second-factor code. The max_attempts-th one in a row locks second factors until
+-- locked_until and starts the count again.
+UPDATE users
+SET mfa_failed_attempts = CASE WHEN mfa_failed_attempts + 1 >= @max_attempts::int THEN 0 ELSE mfa_failed_attempts + 1 END,
+    mfa_locked_until = CASE WHEN mfa_failed_attempts + 1 >= @max_attempts::int THEN @locked_until ELSE mfa_locked_until END
+WHERE id = @id
+RETURNING mfa_locked_until;
+
+-- name: ResetMFAFailures :exec
+UPDATE users
+SET mfa_failed_attempts = 0, mfa_locked_until = NULL
+WHERE id = $1 AND (mfa_failed_attempts <> 0 OR mfa_locked_until IS NOT NULL);
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (id, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: GetMFAChallenge :one
+SELECT * FROM mfa_challenges
+WHERE id = $1 AND user_id = $2 AND expires_at > now();
+
+-- name: SpendMFAChallenge :one
+DELETE FROM mfa_challenges
+WHERE id = $1 AND user_id = $2 AND expires_at > now()
+RETURNING id;
+
+-- name: DeleteExpiredMFAChallenges :exec
+DELETE FROM mfa_challenges
+WHERE expires_at < now();
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :one
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING id;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT count(*) FROM mfa_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (user_id, credential_id, name, credential, sign_count)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListWebAuthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: CountWebAuthnCredentials :one
+SELECT count(*) FROM webauthn_credentials
+WHERE user_id = $1;
+
+-- name: GetWebAuthnCredentialByCredentialID :one
+SELECT * FROM webauthn_credentials
+WHERE credential_id = $1 LIMIT 1;
+
+-- name: UpdateWebAuthnCredentialUsage :exec
+UPDATE webauthn_credentials
+SET credential = $2, sign_count = $3, last_used_at = now()
+WHERE id = $1;
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateWebAuthnSession :exec
+INSERT INTO webauthn_sessions (id, user_id, purpose, data, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: TakeWebAuthnSession :one
+DELETE FROM webauthn_sessions
+WHERE id = $1 AND purpose = $2
+RETURNING *;
+
+-- name: DeleteExpiredWebAuthnSessions :exec
+DELETE FROM webauthn_sessions
+WHERE expires_at < now();
+
+-- name: CreateOAuthState :exec
+INSERT INTO oauth_states (id, provider, nonce, code_verifier, expires_at, user_id)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: TakeOAuthState :one
+DELETE FROM oauth_states
+WHERE id = $1 AND provider = $2 AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteExpiredOAuthStates :exec
+DELETE FROM oauth_states
+WHERE expires_at < now();
+
+-- name: CreateSAMLRequest :exec
+INSERT INTO saml_requests (id, connection, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: TakeSAMLRequest :one
+DELETE FROM saml_requests
+WHERE id = $1 AND connection = $2 AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteExpiredSAMLRequests :exec
+DELETE FROM saml_requests
+WHERE expires_at < now();
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email, last_used_at)
+VALUES ($1, $2, $3, $4, now())
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE provider = $1 AND subject = $2;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities
+SET email = $2, last_used_at = now()
+WHERE id = $1;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: CountUserIdentities :one
+SELECT count(*) FROM user_identities
+WHERE user_id = $1;
+
+-- name: DeleteUserIdentity :one
+DELETE FROM user_identities
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: LockUserForUpdate :one
+-- Serializes changes to a user's sign-in methods.
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: CreateMagicLink :exec
+INSERT INTO magic_links (id, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: GetMagicLink :one
+SELECT user_id FROM magic_links
+WHERE id = $1 AND used_at IS NULL AND expires_at > now();
+
+-- name: UseMagicLink :one
+UPDATE magic_links
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING user_id;
+
+-- name: DeleteExpiredMagicLinks :exec
+DELETE FROM magic_links
+WHERE expires_at < now();
+
+-- name: SetUserPhone :exec
+UPDATE users
+SET phone = $2, phone_verified = false, phone_otp = $3, phone_otp_expires_at = $4,
+    otp_channel = 'email', sms_mfa_enabled = false
+WHERE id = $1;
+
+-- name: SetUserPhoneOTP :exec
+UPDATE users
+SET phone_otp = $2, phone_otp_expires_at = $3
+WHERE id = $1;
+
+-- name: VerifyUserPhone :exec
+UPDATE users
+SET phone_verified = TRUE, phone_otp = '', phone_otp_expires_at = NULL
+WHERE id = $1;
+
+-- name: ConsumeUserPhoneOTP :one
+-- Spends the code sent to the user's phone if it matches and has not expired, so that concurrent
+-- requests cannot both use it.
+UPDATE users
+SET phone_otp = '', phone_otp_expires_at = NULL
+WHERE id = $1 AND phone_otp = $2 AND phone_otp <> '' AND phone_otp_expires_at > now()
+RETURNING id;
+
+-- name: UpdateUserMessagingPreferences :exec
+UPDATE users
+SET otp_channel = $2, sms_mfa_enabled = $3
+WHERE id = $1;
+
+-- name: CreateOutboxMessage :one
+INSERT INTO outbox_messages (channel, recipient, subject, body_text, body_html)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: ClaimOutboxMessage :one
+UPDATE outbox_messages
+SET status = 'sending', attempts = attempts + 1, next_attempt_at = sqlc.arg(lease_until)
+WHERE id = (
+    SELECT id FROM outbox_messages
+    WHERE status IN ('pending', 'sending') AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxMessageSent :exec
+UPDATE outbox_messages
+SET status = 'sent', sent_at = now(), last_error = '', body_text = '', body_html = ''
+WHERE id = $1;
+
+-- name: RescheduleOutboxMessage :exec
+UPDATE outbox_messages
+SET status = 'pending', next_attempt_at = $2, last_error = $3
+WHERE id = $1;
+
+-- name: DeadLetterOutboxMessage :exec
+UPDATE outbox_messages
+SET status = 'dead', last_error = $2
+WHERE id = $1;
+
+-- name: ListOutboxMessages :many
+SELECT * FROM outbox_messages
+WHERE status = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: RetryOutboxMessage :one
+-- Only messages that still have their bodies can be sent again.
+UPDATE outbox_messages
+SET status = 'pending', attempts = 0, next_attempt_at = now()
+WHERE id = $1 AND status = 'dead' AND (body_text <> '' OR body_html <> '')
+RETURNING *;
+
+-- name: PurgeOutboxBodies :execrows
+-- Blanks the bodies of dead-lettered messages queued before the cutoff. Sent messages lose theirs
+-- as soon as they are delivered.
+UPDATE outbox_messages
+SET body_text = '', body_html = ''
+WHERE status = 'dead' AND created_at < @cutoff AND (body_text <> '' OR body_html <> '');
+
+-- name: UpsertEmailChange :exec
+INSERT INTO email_changes (user_id, new_email, code_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+SET new_email = EXCLUDED.new_email, code_hash = EXCLUDED.code_hash, attempts = 0,
+    expires_at = EXCLUDED.expires_at, created_at = now();
+
+-- name: GetEmailChange :one
+SELECT * FROM email_changes
+WHERE user_id = $1;
+
+-- name: ConsumeEmailChange :one
+-- Spends the pending change if the code matches, has not expired and has not been guessed at
+-- max_attempts times, so that concurrent requests cannot both use it or get past the limit.
+DELETE FROM email_changes
+WHERE user_id = @user_id AND code_hash = @code_hash AND attempts < @max_attempts::int AND expires_at > now()
+RETURNING *;
+
+-- name: IncrementEmailChangeAttempts :one
+UPDATE email_changes
+SET attempts = attempts + 1
+WHERE user_id = $1
+RETURNING attempts;
+
+-- name: DeleteEmailChange :exec
+DELETE FROM email_changes
+WHERE user_id = $1;
+
+-- name: UpdateUserEmail :exec
+UPDATE users
+SET email = $2, verified_at = now()
+WHERE id = $1;
+
+-- name: ListUsersAtEmailDomains :many
+SELECT id, email FROM users
+WHERE lower(split_part(email, '@', 2)) = ANY(@domains::text[])
+ORDER BY id;
+
+-- name: RewriteUserEmail :exec
+UPDATE users
+SET email = $2
+WHERE id = $1;
+
+-- name: RevokeUserSessions :exec
+UPDATE users
+SET sessions_revoked_at = now()
+WHERE id = $1;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET name = $2, display_name = $3, locale = $4, timezone = $5, attributes = $6
+WHERE id = $1
 RETURNING *;
+
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (user_id, actor_id, action, ip, user_agent, details)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ScrubAuditEvents :exec
+-- Erases what identifies the given users from the audit log before their accounts are deleted: the
+-- details of events about them, and the IP address and user agent of events about or by them. The
+-- action and its time are kept.
+UPDATE audit_events
+SET ip = '', user_agent = '',
+    details = CASE WHEN user_id = ANY(@user_ids::bigint[]) THEN '{}'::jsonb ELSE details END
+WHERE user_id = ANY(@user_ids::bigint[]) OR actor_id = ANY(@user_ids::bigint[]);
+
+-- name: ListAuditEventsByUser :many
+SELECT * FROM audit_events
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: ScheduleUserDeletion :exec
+UPDATE users
+SET deleted_at = now(), deletion_scheduled_for = $2, sessions_revoked_at = now(), status = 'deleted'
+WHERE id = $1;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET deleted_at = NULL, deletion_scheduled_for = NULL,
+    status = CASE WHEN isverified THEN 'active' ELSE 'pending' END::account_status
+WHERE id = $1;
+
+-- name: ListUsersDueForPurge :many
+SELECT id FROM users
+WHERE deletion_scheduled_for <= now();
+
+-- name: PurgeDeletedUsers :many
+DELETE FROM users
+WHERE id = ANY(@ids::bigint[]) AND deletion_scheduled_for <= now()
+RETURNING id;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE (sqlc.narg(verified)::boolean IS NULL OR isverified = sqlc.narg(verified))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before))
+  AND (sqlc.narg(email_contains)::text IS NULL OR email ILIKE '%' || sqlc.narg(email_contains) || '%')
+  AND (sqlc.narg(status)::account_status IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(cursor_id)::bigint IS NULL OR CASE
+        WHEN @sort_by::text = 'created_at' AND NOT @descending::boolean THEN (created_at, id) > (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id))
+        WHEN @sort_by = 'created_at' THEN (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id))
+        WHEN @sort_by = 'email' AND NOT @descending THEN (email, id) > (sqlc.narg(cursor_email)::text, sqlc.narg(cursor_id))
+        WHEN @sort_by = 'email' THEN (email, id) < (sqlc.narg(cursor_email), sqlc.narg(cursor_id))
+        WHEN NOT @descending THEN id > sqlc.narg(cursor_id)
+        ELSE id < sqlc.narg(cursor_id)
+      END)
+ORDER BY
+  CASE WHEN @sort_by = 'created_at' AND NOT @descending THEN created_at END ASC,
+  CASE WHEN @sort_by = 'created_at' AND @descending THEN created_at END DESC,
+  CASE WHEN @sort_by = 'email' AND NOT @descending THEN email END ASC,
+  CASE WHEN @sort_by = 'email' AND @descending THEN email END DESC,
+  CASE WHEN NOT @descending THEN id END ASC,
+  CASE WHEN @descending THEN id END DESC
+LIMIT @page_size;
+
+-- name: VerifyUserByID :one
+UPDATE users
+SET isverified = TRUE, verified_at = COALESCE(verified_at, now()),
+    status = CASE WHEN status = 'pending' THEN 'active' ELSE status END
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserStatus :one
+-- Moves an account between active (pending while unverified), disabled and locked. Disabling or
+-- locking it also ends its sessions.
+UPDATE users
+SET status = CASE WHEN @status::account_status = 'active' AND NOT isverified THEN 'pending' ELSE @status END,
+    disabled_at = CASE WHEN @status = 'disabled' THEN now() END,
+    sessions_revoked_at = CASE WHEN @status IN ('disabled', 'locked') THEN now() ELSE sessions_revoked_at END
+WHERE id = @id
+RETURNING *;
+
+-- name: RecordUserLogin :exec
+UPDATE users
+SET last_login_at = now()
+WHERE id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: ImportUser :one
+INSERT INTO users (name, email, password, isverified, otp, phone, locale, timezone, display_name, username, attributes, created_at, status)
+VALUES (@name, @email, @password, @isverified, '', @phone, @locale, @timezone, @display_name, @username, @attributes, COALESCE(sqlc.narg(created_at)::timestamptz, now()),
+    COALESCE(sqlc.narg(status)::account_status, CASE WHEN @isverified THEN 'active' ELSE 'pending' END::account_status))
+RETURNING *;
+
+-- name: ListUsersAfter :many
+SELECT * FROM users
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: CreateInvitation :one
+INSERT INTO invitations (email, name, locale, token_hash, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListInvitations :many
+SELECT * FROM invitations
+WHERE CASE @status::text
+    WHEN 'pending' THEN accepted_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+    WHEN 'expired' THEN accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= now()
+    WHEN 'accepted' THEN accepted_at IS NOT NULL
+    WHEN 'revoked' THEN revoked_at IS NOT NULL
+    ELSE TRUE
+  END
+ORDER BY id DESC
+LIMIT $1 OFFSET $2;
+
+-- name: RenewInvitation :one
+UPDATE invitations
+SET token_hash = $2, expires_at = $3
+WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeInvitation :one
+UPDATE invitations
+SET revoked_at = now()
+WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+RETURNING *;
+
+-- name: GetOpenInvitationByTokenHash :one
+SELECT * FROM invitations
+WHERE token_hash = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+FOR UPDATE;
+
+-- name: AcceptInvitation :exec
+UPDATE invitations
+SET accepted_at = now(), accepted_user_id = $2
+WHERE id = $1;
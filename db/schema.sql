@@ -1,9 +1,193 @@
+CREATE TYPE account_status AS ENUM ('pending', 'active', 'disabled', 'locked', 'deleted');
+
 CREATE TABLE users (
     id         bigserial PRIMARY KEY,
     name       text NOT NULL,
-    email VARCHAR(255) UNIQUE NOT NULL,
+    email VARCHAR(255) NOT NULL,
     password   text NOT NULL,
     isverified BOOLEAN NOT NULL DEFAULT false,
-    otp        text NOT NULL
+    otp        text NOT NULL,
+    mfa_enabled BOOLEAN NOT NULL DEFAULT false,
+    totp_secret text NOT NULL DEFAULT '',
+    totp_last_counter bigint NOT NULL DEFAULT 0,
+    phone      text NOT NULL DEFAULT '',
+    phone_verified BOOLEAN NOT NULL DEFAULT false,
+    phone_otp  text NOT NULL DEFAULT '',
+    phone_otp_expires_at timestamptz,
+    otp_channel text NOT NULL DEFAULT 'email',
+    sms_mfa_enabled BOOLEAN NOT NULL DEFAULT false,
+    locale     text NOT NULL DEFAULT 'en',
+    role       text NOT NULL DEFAULT 'user',
+    sessions_revoked_at timestamptz,
+    display_name text NOT NULL DEFAULT '',
+    timezone   text NOT NULL DEFAULT 'UTC',
+    deleted_at timestamptz,
+    deletion_scheduled_for timestamptz,
+    disabled_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    username   text,
+    attributes jsonb NOT NULL DEFAULT '{}',
+    status     account_status NOT NULL DEFAULT 'pending',
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    last_login_at timestamptz,
+    verified_at timestamptz,
+    mfa_failed_attempts integer NOT NULL DEFAULT 0,
+    mfa_locked_until timestamptz,
+    CONSTRAINT valid_phone CHECK (phone = '' OR phone ~ '^\+[1-9][0-9]{1,14}$'),
+    CONSTRAINT valid_otp_channel CHECK (otp_channel IN ('email', 'sms')),
+    CONSTRAINT valid_role CHECK (role IN ('user', 'admin')),
     CONSTRAINT valid_email CHECK (email ~ '^[a-zA-Z0-9.!#$%&''*+/=?^_`{|}~-]+@[a-zA-Z0-9-]+(?:\.[a-zA-Z0-9-]+)*$')
 );
+
+CREATE FUNCTION users_touch_updated_at() RETURNS trigger AS $$
+BEGIN
+    IF to_jsonb(NEW) - 'last_login_at' - 'updated_at' - 'mfa_failed_attempts' - 'mfa_locked_until'
+        IS DISTINCT FROM to_jsonb(OLD) - 'last_login_at' - 'updated_at' - 'mfa_failed_attempts' - 'mfa_locked_until' THEN
+        NEW.updated_at := now();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_touch_updated_at
+    BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION users_touch_updated_at();
+
+CREATE UNIQUE INDEX users_email_lower_key ON users (lower(email));
+
+CREATE UNIQUE INDEX users_username_lower_key ON users (lower(username));
+
+CREATE INDEX users_created_at_idx ON users (created_at, id);
+
+CREATE INDEX users_deletion_scheduled_for_idx ON users (deletion_scheduled_for) WHERE deletion_scheduled_for IS NOT NULL;
+
+CREATE TABLE mfa_recovery_codes (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash  text NOT NULL,
+    used_at    timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX mfa_recovery_codes_user_id_idx ON mfa_recovery_codes (user_id);
+
+CREATE TABLE webauthn_credentials (
+    id            bigserial PRIMARY KEY,
+    user_id       bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id bytea UNIQUE NOT NULL,
+    name          text NOT NULL DEFAULT '',
+    credential    jsonb NOT NULL,
+    sign_count    bigint NOT NULL DEFAULT 0,
+    created_at    timestamptz NOT NULL DEFAULT now(),
+    last_used_at  timestamptz
+);
+
+CREATE INDEX webauthn_credentials_user_id_idx ON webauthn_credentials (user_id);
+
+CREATE TABLE webauthn_sessions (
+    id         text PRIMARY KEY,
+    user_id    bigint REFERENCES users(id) ON DELETE CASCADE,
+    purpose    text NOT NULL,
+    data       jsonb NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE TABLE oauth_states (
+    id            text PRIMARY KEY,
+    provider      text NOT NULL,
+    nonce         text NOT NULL,
+    code_verifier text NOT NULL,
+    expires_at    timestamptz NOT NULL,
+    user_id       bigint REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_identities (
+    id           bigserial PRIMARY KEY,
+    user_id      bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider     text NOT NULL,
+    subject      text NOT NULL,
+    email        text NOT NULL DEFAULT '',
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    last_used_at timestamptz,
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+CREATE TABLE mfa_challenges (
+    id         text PRIMARY KEY,
+    user_id    bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX mfa_challenges_user_id_idx ON mfa_challenges (user_id);
+
+CREATE TABLE saml_requests (
+    id         text PRIMARY KEY,
+    connection text NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE TABLE magic_links (
+    id         text PRIMARY KEY,
+    user_id    bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at timestamptz NOT NULL,
+    used_at    timestamptz
+);
+
+CREATE TABLE outbox_messages (
+    id              bigserial PRIMARY KEY,
+    channel         text NOT NULL,
+    recipient       text NOT NULL,
+    subject         text NOT NULL DEFAULT '',
+    body_text       text NOT NULL DEFAULT '',
+    body_html       text NOT NULL DEFAULT '',
+    status          text NOT NULL DEFAULT 'pending',
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error      text NOT NULL DEFAULT '',
+    created_at      timestamptz NOT NULL DEFAULT now(),
+    sent_at         timestamptz,
+    CONSTRAINT valid_outbox_channel CHECK (channel IN ('email', 'sms')),
+    CONSTRAINT valid_outbox_status CHECK (status IN ('pending', 'sending', 'sent', 'dead'))
+);
+
+CREATE INDEX outbox_messages_due_idx ON outbox_messages (next_attempt_at) WHERE status IN ('pending', 'sending');
+
+CREATE TABLE email_changes (
+    user_id    bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    new_email  VARCHAR(255) NOT NULL,
+    code_hash  text NOT NULL,
+    attempts   integer NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE audit_events (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint REFERENCES users(id) ON DELETE SET NULL,
+    actor_id   bigint REFERENCES users(id) ON DELETE SET NULL,
+    action     text NOT NULL,
+    ip         text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    details    jsonb NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (user_id, id);
+
+CREATE TABLE invitations (
+    id               bigserial PRIMARY KEY,
+    email            text NOT NULL,
+    name             text NOT NULL DEFAULT '',
+    locale           text NOT NULL DEFAULT 'en',
+    token_hash       text NOT NULL UNIQUE,
+    invited_by       bigint REFERENCES users(id) ON DELETE SET NULL,
+    expires_at       timestamptz NOT NULL,
+    accepted_at      timestamptz,
+    accepted_user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at       timestamptz,
+    created_at       timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX invitations_open_email_key ON invitations (lower(email)) WHERE accepted_at IS NULL AND revoked_at IS NULL;
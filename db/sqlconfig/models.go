@@ -4,13 +4,203 @@
 
 package db
 
-import ()
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type AccountStatus string
+
+const (
+	AccountStatusPending  AccountStatus = "pending"
+	AccountStatusActive   AccountStatus = "active"
+	AccountStatusDisabled AccountStatus = "disabled"
+	AccountStatusLocked   AccountStatus = "locked"
+	AccountStatusDeleted  AccountStatus = "deleted"
+)
+
+func (e *AccountStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AccountStatus(s)
+	case string:
+		*e = AccountStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AccountStatus: %T", src)
+	}
+	return nil
+}
+
+type NullAccountStatus struct {
+	AccountStatus AccountStatus
+	Valid         bool // Valid is true if AccountStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAccountStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.AccountStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AccountStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAccountStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AccountStatus), nil
+}
+
+type AuditEvent struct {
+	ID        int64
+	UserID    pgtype.Int8
+	ActorID   pgtype.Int8
+	Action    string
+	Ip        string
+	UserAgent string
+	Details   []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type EmailChange struct {
+	UserID    int64
+	NewEmail  string
+	CodeHash  string
+	Attempts  int32
+	ExpiresAt pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+type Invitation struct {
+	ID             int64
+	Email          string
+	Name           string
+	Locale         string
+	TokenHash      string
+	InvitedBy      pgtype.Int8
+	ExpiresAt      pgtype.Timestamptz
+	AcceptedAt     pgtype.Timestamptz
+	AcceptedUserID pgtype.Int8
+	RevokedAt      pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+}
+
+type MagicLink struct {
+	ID        string
+	UserID    int64
+	ExpiresAt pgtype.Timestamptz
+	UsedAt    pgtype.Timestamptz
+}
+
+type MfaChallenge struct {
+	ID        string
+	UserID    int64
+	ExpiresAt pgtype.Timestamptz
+}
+
+type MfaRecoveryCode struct {
+	ID        int64
+	UserID    int64
+	CodeHash  string
+	UsedAt    pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+type OauthState struct {
+	ID           string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    pgtype.Timestamptz
+	UserID       pgtype.Int8
+}
+
+type OutboxMessage struct {
+	ID            int64
+	Channel       string
+	Recipient     string
+	Subject       string
+	BodyText      string
+	BodyHtml      string
+	Status        string
+	Attempts      int32
+	NextAttemptAt pgtype.Timestamptz
+	LastError     string
+	CreatedAt     pgtype.Timestamptz
+	SentAt        pgtype.Timestamptz
+}
+
+type SamlRequest struct {
+	ID         string
+	Connection string
+	ExpiresAt  pgtype.Timestamptz
+}
 
 type User struct {
+	ID                   int64
+	Name                 string
+	Email                string
+	Password             string
+	Isverified           bool
+	Otp                  string
+	MfaEnabled           bool
+	TotpSecret           string
+	TotpLastCounter      int64
+	Phone                string
+	PhoneVerified        bool
+	PhoneOtp             string
+	PhoneOtpExpiresAt    pgtype.Timestamptz
+	OtpChannel           string
+	SmsMfaEnabled        bool
+	Locale               string
+	Role                 string
+	SessionsRevokedAt    pgtype.Timestamptz
+	DisplayName          string
+	Timezone             string
+	DeletedAt            pgtype.Timestamptz
+	DeletionScheduledFor pgtype.Timestamptz
+	DisabledAt           pgtype.Timestamptz
+	CreatedAt            pgtype.Timestamptz
+	Username             pgtype.Text
+	Attributes           []byte
+	Status               AccountStatus
+	UpdatedAt            pgtype.Timestamptz
+	LastLoginAt          pgtype.Timestamptz
+	VerifiedAt           pgtype.Timestamptz
+	MfaFailedAttempts    int32
+	MfaLockedUntil       pgtype.Timestamptz
+}
+
+type UserIdentity struct {
 	ID         int64
-	Name       string
+	UserID     int64
+	Provider   string
+	Subject    string
 	Email      string
-	Password   string
-	Isverified bool
-	Otp        string
+	CreatedAt  pgtype.Timestamptz
+	LastUsedAt pgtype.Timestamptz
+}
+
+type WebauthnCredential struct {
+	ID           int64
+	UserID       int64
+	CredentialID []byte
+	Name         string
+	Credential   []byte
+	SignCount    int64
+	CreatedAt    pgtype.Timestamptz
+	LastUsedAt   pgtype.Timestamptz
+}
+
+type WebauthnSession struct {
+	ID        string
+	UserID    pgtype.Int8
+	Purpose   string
+	Data      []byte
+	ExpiresAt pgtype.Timestamptz
 }
@@ -7,19 +7,376 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const acceptInvitation = `-- name: AcceptInvitation :exec
+UPDATE invitations
+SET accepted_at = now(), accepted_user_id = $2
+WHERE id = $1
+`
+
+type AcceptInvitationParams struct {
+	ID             int64
+	AcceptedUserID pgtype.Int8
+}
+
+func (q *Queries) AcceptInvitation(ctx context.Context, arg AcceptInvitationParams) error {
+	_, err := q.db.Exec(ctx, acceptInvitation, arg.ID, arg.AcceptedUserID)
+	return err
+}
+
+const cancelUserDeletion = `-- name: CancelUserDeletion :exec
+UPDATE users
+SET deleted_at = NULL, deletion_scheduled_for = NULL,
+    status = CASE WHEN isverified THEN 'active' ELSE 'pending' END::account_status
+WHERE id = $1
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, cancelUserDeletion, id)
+	return err
+}
+
+const claimOutboxMessage = `-- name: ClaimOutboxMessage :one
+UPDATE outbox_messages
+SET status = 'sending', attempts = attempts + 1, next_attempt_at = $1
+WHERE id = (
+    SELECT id FROM outbox_messages
+    WHERE status IN ('pending', 'sending') AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
 )
+RETURNING id, channel, recipient, subject, body_text, body_html, status, attempts, next_attempt_at, last_error, created_at, sent_at
+`
+
+func (q *Queries) ClaimOutboxMessage(ctx context.Context, leaseUntil pgtype.Timestamptz) (OutboxMessage, error) {
+	row := q.db.QueryRow(ctx, claimOutboxMessage, leaseUntil)
+	var i OutboxMessage
+	err := row.Scan(
+		&i.ID,
+		&i.Channel,
+		&i.Recipient,
+		&i.Subject,
+		&i.BodyText,
+		&i.BodyHtml,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+const consumeEmailChange = `-- name: ConsumeEmailChange :one
+DELETE FROM email_changes
+WHERE user_id = $1 AND code_hash = $2 AND attempts < $3::int AND expires_at > now()
+RETURNING user_id, new_email, code_hash, attempts, expires_at, created_at
+`
+
+type ConsumeEmailChangeParams struct {
+	UserID      int64
+	CodeHash    string
+	MaxAttempts int32
+}
+
+// Spends the pending change if the code matches, has not expired and has not been guessed at
+// max_attempts times, so that concurrent requests cannot both use it or get past the limit.
+func (q *Queries) ConsumeEmailChange(ctx context.Context, arg ConsumeEmailChangeParams) (EmailChange, error) {
+	row := q.db.QueryRow(ctx, consumeEmailChange, arg.UserID, arg.CodeHash, arg.MaxAttempts)
+	var i EmailChange
+	err := row.Scan(
+		&i.UserID,
+		&i.NewEmail,
+		&i.CodeHash,
+		&i.Attempts,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const consumeTOTPCounter = `-- name: ConsumeTOTPCounter :one
+UPDATE users
+SET totp_last_counter = $1
+WHERE id = $2 AND totp_last_counter < $1
+RETURNING id
+`
+
+type ConsumeTOTPCounterParams struct {
+	Counter int64
+	ID      int64
+}
+
+// Records the time step of a used TOTP code, unless that step or a later one has been used already,
+// so that two requests racing with the same code cannot both succeed.
+func (q *Queries) ConsumeTOTPCounter(ctx context.Context, arg ConsumeTOTPCounterParams) (int64, error) {
+	row := q.db.QueryRow(ctx, consumeTOTPCounter, arg.Counter, arg.ID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const consumeUserPhoneOTP = `-- name: ConsumeUserPhoneOTP :one
+UPDATE users
+SET phone_otp = '', phone_otp_expires_at = NULL
+WHERE id = $1 AND phone_otp = $2 AND phone_otp <> '' AND phone_otp_expires_at > now()
+RETURNING id
+`
+
+type ConsumeUserPhoneOTPParams struct {
+	ID       int64
+	PhoneOtp string
+}
+
+// Spends the code sent to the user's phone if it matches and has not expired, so that concurrent
+// requests cannot both use it.
+func (q *Queries) ConsumeUserPhoneOTP(ctx context.Context, arg ConsumeUserPhoneOTPParams) (int64, error) {
+	row := q.db.QueryRow(ctx, consumeUserPhoneOTP, arg.ID, arg.PhoneOtp)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const countUnusedRecoveryCodes = `-- name: CountUnusedRecoveryCodes :one
+SELECT count(*) FROM mfa_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL
+`
+
+func (q *Queries) CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnusedRecoveryCodes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserIdentities = `-- name: CountUserIdentities :one
+SELECT count(*) FROM user_identities
+WHERE user_id = $1
+`
+
+func (q *Queries) CountUserIdentities(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserIdentities, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countWebAuthnCredentials = `-- name: CountWebAuthnCredentials :one
+SELECT count(*) FROM webauthn_credentials
+WHERE user_id = $1
+`
+
+func (q *Queries) CountWebAuthnCredentials(ctx context.Context, userID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, countWebAuthnCredentials, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAuditEvent = `-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (user_id, actor_id, action, ip, user_agent, details)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateAuditEventParams struct {
+	UserID    pgtype.Int8
+	ActorID   pgtype.Int8
+	Action    string
+	Ip        string
+	UserAgent string
+	Details   []byte
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) error {
+	_, err := q.db.Exec(ctx, createAuditEvent,
+		arg.UserID,
+		arg.ActorID,
+		arg.Action,
+		arg.Ip,
+		arg.UserAgent,
+		arg.Details,
+	)
+	return err
+}
+
+const createInvitation = `-- name: CreateInvitation :one
+INSERT INTO invitations (email, name, locale, token_hash, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, email, name, locale, token_hash, invited_by, expires_at, accepted_at, accepted_user_id, revoked_at, created_at
+`
+
+type CreateInvitationParams struct {
+	Email     string
+	Name      string
+	Locale    string
+	TokenHash string
+	InvitedBy pgtype.Int8
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) CreateInvitation(ctx context.Context, arg CreateInvitationParams) (Invitation, error) {
+	row := q.db.QueryRow(ctx, createInvitation,
+		arg.Email,
+		arg.Name,
+		arg.Locale,
+		arg.TokenHash,
+		arg.InvitedBy,
+		arg.ExpiresAt,
+	)
+	var i Invitation
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Locale,
+		&i.TokenHash,
+		&i.InvitedBy,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+		&i.AcceptedUserID,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createMFAChallenge = `-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (id, user_id, expires_at)
+VALUES ($1, $2, $3)
+`
+
+type CreateMFAChallengeParams struct {
+	ID        string
+	UserID    int64
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) error {
+	_, err := q.db.Exec(ctx, createMFAChallenge, arg.ID, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
+const createMagicLink = `-- name: CreateMagicLink :exec
+INSERT INTO magic_links (id, user_id, expires_at)
+VALUES ($1, $2, $3)
+`
+
+type CreateMagicLinkParams struct {
+	ID        string
+	UserID    int64
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) CreateMagicLink(ctx context.Context, arg CreateMagicLinkParams) error {
+	_, err := q.db.Exec(ctx, createMagicLink, arg.ID, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
+const createOAuthState = `-- name: CreateOAuthState :exec
+INSERT INTO oauth_states (id, provider, nonce, code_verifier, expires_at, user_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateOAuthStateParams struct {
+	ID           string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    pgtype.Timestamptz
+	UserID       pgtype.Int8
+}
+
+func (q *Queries) CreateOAuthState(ctx context.Context, arg CreateOAuthStateParams) error {
+	_, err := q.db.Exec(ctx, createOAuthState,
+		arg.ID,
+		arg.Provider,
+		arg.Nonce,
+		arg.CodeVerifier,
+		arg.ExpiresAt,
+		arg.UserID,
+	)
+	return err
+}
+
+const createOutboxMessage = `-- name: CreateOutboxMessage :one
+INSERT INTO outbox_messages (channel, recipient, subject, body_text, body_html)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id
+`
+
+type CreateOutboxMessageParams struct {
+	Channel   string
+	Recipient string
+	Subject   string
+	BodyText  string
+	BodyHtml  string
+}
+
+func (q *Queries) CreateOutboxMessage(ctx context.Context, arg CreateOutboxMessageParams) (int64, error) {
+	row := q.db.QueryRow(ctx, createOutboxMessage,
+		arg.Channel,
+		arg.Recipient,
+		arg.Subject,
+		arg.BodyText,
+		arg.BodyHtml,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+VALUES ($1, $2)
+`
+
+type CreateRecoveryCodeParams struct {
+	UserID   int64
+	CodeHash string
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	_, err := q.db.Exec(ctx, createRecoveryCode, arg.UserID, arg.CodeHash)
+	return err
+}
+
+const createSAMLRequest = `-- name: CreateSAMLRequest :exec
+INSERT INTO saml_requests (id, connection, expires_at)
+VALUES ($1, $2, $3)
+`
+
+type CreateSAMLRequestParams struct {
+	ID         string
+	Connection string
+	ExpiresAt  pgtype.Timestamptz
+}
+
+func (q *Queries) CreateSAMLRequest(ctx context.Context, arg CreateSAMLRequestParams) error {
+	_, err := q.db.Exec(ctx, createSAMLRequest, arg.ID, arg.Connection, arg.ExpiresAt)
+	return err
+}
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (name, email, password, isverified, otp)
-VALUES ($1, $2, $3, false, $4)
-RETURNING id, name, email, password, isverified, otp
+INSERT INTO users (name, email, password, isverified, otp, phone, otp_channel, locale, username, attributes)
+VALUES ($1, $2, $3, false, $4, $5, $6, $7, $8, $9)
+RETURNING id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until
 `
 
 type CreateUserParams struct {
-	Name     string
-	Email    string
-	Password string
-	Otp      string
+	Name       string
+	Email      string
+	Password   string
+	Otp        string
+	Phone      string
+	OtpChannel string
+	Locale     string
+	Username   pgtype.Text
+	Attributes []byte
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -28,6 +385,11 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.Email,
 		arg.Password,
 		arg.Otp,
+		arg.Phone,
+		arg.OtpChannel,
+		arg.Locale,
+		arg.Username,
+		arg.Attributes,
 	)
 	var i User
 	err := row.Scan(
@@ -37,36 +399,1822 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Password,
 		&i.Isverified,
 		&i.Otp,
+		&i.MfaEnabled,
+		&i.TotpSecret,
+		&i.TotpLastCounter,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.PhoneOtp,
+		&i.PhoneOtpExpiresAt,
+		&i.OtpChannel,
+		&i.SmsMfaEnabled,
+		&i.Locale,
+		&i.Role,
+		&i.SessionsRevokedAt,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DeletedAt,
+		&i.DeletionScheduledFor,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.Username,
+		&i.Attributes,
+		&i.Status,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.VerifiedAt,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
 	)
 	return i, err
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, name, email, password, isverified, otp FROM users
-WHERE email = $1 LIMIT 1
+const createUserIdentity = `-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email, last_used_at)
+VALUES ($1, $2, $3, $4, now())
+RETURNING id, user_id, provider, subject, email, created_at, last_used_at
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRow(ctx, getUserByEmail, email)
-	var i User
+type CreateUserIdentityParams struct {
+	UserID   int64
+	Provider string
+	Subject  string
+	Email    string
+}
+
+func (q *Queries) CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRow(ctx, createUserIdentity,
+		arg.UserID,
+		arg.Provider,
+		arg.Subject,
+		arg.Email,
+	)
+	var i UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const createWebAuthnCredential = `-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (user_id, credential_id, name, credential, sign_count)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, credential_id, name, credential, sign_count, created_at, last_used_at
+`
+
+type CreateWebAuthnCredentialParams struct {
+	UserID       int64
+	CredentialID []byte
+	Name         string
+	Credential   []byte
+	SignCount    int64
+}
+
+func (q *Queries) CreateWebAuthnCredential(ctx context.Context, arg CreateWebAuthnCredentialParams) (WebauthnCredential, error) {
+	row := q.db.QueryRow(ctx, createWebAuthnCredential,
+		arg.UserID,
+		arg.CredentialID,
+		arg.Name,
+		arg.Credential,
+		arg.SignCount,
+	)
+	var i WebauthnCredential
 	err := row.Scan(
 		&i.ID,
+		&i.UserID,
+		&i.CredentialID,
 		&i.Name,
+		&i.Credential,
+		&i.SignCount,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const createWebAuthnSession = `-- name: CreateWebAuthnSession :exec
+INSERT INTO webauthn_sessions (id, user_id, purpose, data, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateWebAuthnSessionParams struct {
+	ID        string
+	UserID    pgtype.Int8
+	Purpose   string
+	Data      []byte
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) CreateWebAuthnSession(ctx context.Context, arg CreateWebAuthnSessionParams) error {
+	_, err := q.db.Exec(ctx, createWebAuthnSession,
+		arg.ID,
+		arg.UserID,
+		arg.Purpose,
+		arg.Data,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const deadLetterOutboxMessage = `-- name: DeadLetterOutboxMessage :exec
+UPDATE outbox_messages
+SET status = 'dead', last_error = $2
+WHERE id = $1
+`
+
+type DeadLetterOutboxMessageParams struct {
+	ID        int64
+	LastError string
+}
+
+func (q *Queries) DeadLetterOutboxMessage(ctx context.Context, arg DeadLetterOutboxMessageParams) error {
+	_, err := q.db.Exec(ctx, deadLetterOutboxMessage, arg.ID, arg.LastError)
+	return err
+}
+
+const deleteEmailChange = `-- name: DeleteEmailChange :exec
+DELETE FROM email_changes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteEmailChange(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, deleteEmailChange, userID)
+	return err
+}
+
+const deleteExpiredMFAChallenges = `-- name: DeleteExpiredMFAChallenges :exec
+DELETE FROM mfa_challenges
+WHERE expires_at < now()
+`
+
+func (q *Queries) DeleteExpiredMFAChallenges(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredMFAChallenges)
+	return err
+}
+
+const deleteExpiredMagicLinks = `-- name: DeleteExpiredMagicLinks :exec
+DELETE FROM magic_links
+WHERE expires_at < now()
+`
+
+func (q *Queries) DeleteExpiredMagicLinks(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredMagicLinks)
+	return err
+}
+
+const deleteExpiredOAuthStates = `-- name: DeleteExpiredOAuthStates :exec
+DELETE FROM oauth_states
+WHERE expires_at < now()
+`
+
+func (q *Queries) DeleteExpiredOAuthStates(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredOAuthStates)
+	return err
+}
+
+const deleteExpiredSAMLRequests = `-- name: DeleteExpiredSAMLRequests :exec
+DELETE FROM saml_requests
+WHERE expires_at < now()
+`
+
+func (q *Queries) DeleteExpiredSAMLRequests(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredSAMLRequests)
+	return err
+}
+
+const deleteExpiredWebAuthnSessions = `-- name: DeleteExpiredWebAuthnSessions :exec
+DELETE FROM webauthn_sessions
+WHERE expires_at < now()
+`
+
+func (q *Queries) DeleteExpiredWebAuthnSessions(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredWebAuthnSessions)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, userID int64) error {
+	_, err := q.db.Exec(ctx, deleteRecoveryCodes, userID)
+	return err
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserIdentity = `-- name: DeleteUserIdentity :one
+DELETE FROM user_identities
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, provider, subject, email, created_at, last_used_at
+`
+
+type DeleteUserIdentityParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) DeleteUserIdentity(ctx context.Context, arg DeleteUserIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRow(ctx, deleteUserIdentity, arg.ID, arg.UserID)
+	var i UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
 		&i.Email,
-		&i.Password,
-		&i.Isverified,
-		&i.Otp,
+		&i.CreatedAt,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :exec
+const deleteWebAuthnCredential = `-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteWebAuthnCredentialParams struct {
+	ID     int64
+	UserID int64
+}
+
+func (q *Queries) DeleteWebAuthnCredential(ctx context.Context, arg DeleteWebAuthnCredentialParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteWebAuthnCredential, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const enableUserMFA = `-- name: EnableUserMFA :exec
 UPDATE users
-SET isverified = TRUE
-WHERE email = $1
+SET mfa_enabled = TRUE
+WHERE id = $1
 `
 
-func (q *Queries) UpdateUser(ctx context.Context, email string) error {
-	_, err := q.db.Exec(ctx, updateUser, email)
+func (q *Queries) EnableUserMFA(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, enableUserMFA, id)
+	return err
+}
+
+const getEmailChange = `-- name: GetEmailChange :one
+SELECT user_id, new_email, code_hash, attempts, expires_at, created_at FROM email_changes
+WHERE user_id = $1
+`
+
+func (q *Queries) GetEmailChange(ctx context.Context, userID int64) (EmailChange, error) {
+	row := q.db.QueryRow(ctx, getEmailChange, userID)
+	var i EmailChange
+	err := row.Scan(
+		&i.UserID,
+		&i.NewEmail,
+		&i.CodeHash,
+		&i.Attempts,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getMFAChallenge = `-- name: GetMFAChallenge :one
+SELECT id, user_id, expires_at FROM mfa_challenges
+WHERE id = $1 AND user_id = $2 AND expires_at > now()
+`
+
+type GetMFAChallengeParams struct {
+	ID     string
+	UserID int64
+}
+
+func (q *Queries) GetMFAChallenge(ctx context.Context, arg GetMFAChallengeParams) (MfaChallenge, error) {
+	row := q.db.QueryRow(ctx, getMFAChallenge, arg.ID, arg.UserID)
+	var i MfaChallenge
+	err := row.Scan(&i.ID, &i.UserID, &i.ExpiresAt)
+	return i, err
+}
+
+const getMagicLink = `-- name: GetMagicLink :one
+SELECT user_id FROM magic_links
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+`
+
+func (q *Queries) GetMagicLink(ctx context.Context, id string) (int64, error) {
+	row := q.db.QueryRow(ctx, getMagicLink, id)
+	var user_id int64
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const getOpenInvitationByTokenHash = `-- name: GetOpenInvitationByTokenHash :one
+SELECT id, email, name, locale, token_hash, invited_by, expires_at, accepted_at, accepted_user_id, revoked_at, created_at FROM invitations
+WHERE token_hash = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+FOR UPDATE
+`
+
+func (q *Queries) GetOpenInvitationByTokenHash(ctx context.Context, tokenHash string) (Invitation, error) {
+	row := q.db.QueryRow(ctx, getOpenInvitationByTokenHash, tokenHash)
+	var i Invitation
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Locale,
+		&i.TokenHash,
+		&i.InvitedBy,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+		&i.AcceptedUserID,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until FROM users
+WHERE lower(email) = lower($1::text) LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Password,
+		&i.Isverified,
+		&i.Otp,
+		&i.MfaEnabled,
+		&i.TotpSecret,
+		&i.TotpLastCounter,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.PhoneOtp,
+		&i.PhoneOtpExpiresAt,
+		&i.OtpChannel,
+		&i.SmsMfaEnabled,
+		&i.Locale,
+		&i.Role,
+		&i.SessionsRevokedAt,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DeletedAt,
+		&i.DeletionScheduledFor,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.Username,
+		&i.Attributes,
+		&i.Status,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.VerifiedAt,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until FROM users
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Password,
+		&i.Isverified,
+		&i.Otp,
+		&i.MfaEnabled,
+		&i.TotpSecret,
+		&i.TotpLastCounter,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.PhoneOtp,
+		&i.PhoneOtpExpiresAt,
+		&i.OtpChannel,
+		&i.SmsMfaEnabled,
+		&i.Locale,
+		&i.Role,
+		&i.SessionsRevokedAt,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DeletedAt,
+		&i.DeletionScheduledFor,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.Username,
+		&i.Attributes,
+		&i.Status,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.VerifiedAt,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const getUserByUsername = `-- name: GetUserByUsername :one
+SELECT id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until FROM users
+WHERE lower(username) = lower($1::text) LIMIT 1
+`
+
+func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByUsername, username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Password,
+		&i.Isverified,
+		&i.Otp,
+		&i.MfaEnabled,
+		&i.TotpSecret,
+		&i.TotpLastCounter,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.PhoneOtp,
+		&i.PhoneOtpExpiresAt,
+		&i.OtpChannel,
+		&i.SmsMfaEnabled,
+		&i.Locale,
+		&i.Role,
+		&i.SessionsRevokedAt,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DeletedAt,
+		&i.DeletionScheduledFor,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.Username,
+		&i.Attributes,
+		&i.Status,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.VerifiedAt,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const getUserIdentity = `-- name: GetUserIdentity :one
+SELECT id, user_id, provider, subject, email, created_at, last_used_at FROM user_identities
+WHERE provider = $1 AND subject = $2
+`
+
+type GetUserIdentityParams struct {
+	Provider string
+	Subject  string
+}
+
+func (q *Queries) GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRow(ctx, getUserIdentity, arg.Provider, arg.Subject)
+	var i UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const getWebAuthnCredentialByCredentialID = `-- name: GetWebAuthnCredentialByCredentialID :one
+SELECT id, user_id, credential_id, name, credential, sign_count, created_at, last_used_at FROM webauthn_credentials
+WHERE credential_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetWebAuthnCredentialByCredentialID(ctx context.Context, credentialID []byte) (WebauthnCredential, error) {
+	row := q.db.QueryRow(ctx, getWebAuthnCredentialByCredentialID, credentialID)
+	var i WebauthnCredential
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CredentialID,
+		&i.Name,
+		&i.Credential,
+		&i.SignCount,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const importUser = `-- name: ImportUser :one
+INSERT INTO users (name, email, password, isverified, otp, phone, locale, timezone, display_name, username, attributes, created_at, status)
+VALUES ($1, $2, $3, $4, '', $5, $6, $7, $8, $9, $10, COALESCE($11::timestamptz, now()),
+    COALESCE($12::account_status, CASE WHEN $4 THEN 'active' ELSE 'pending' END::account_status))
+RETURNING id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until
+`
+
+type ImportUserParams struct {
+	Name        string
+	Email       string
+	Password    string
+	Isverified  bool
+	Phone       string
+	Locale      string
+	Timezone    string
+	DisplayName string
+	Username    pgtype.Text
+	Attributes  []byte
+	CreatedAt   pgtype.Timestamptz
+	Status      NullAccountStatus
+}
+
+func (q *Queries) ImportUser(ctx context.Context, arg ImportUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, importUser,
+		arg.Name,
+		arg.Email,
+		arg.Password,
+		arg.Isverified,
+		arg.Phone,
+		arg.Locale,
+		arg.Timezone,
+		arg.DisplayName,
+		arg.Username,
+		arg.Attributes,
+		arg.CreatedAt,
+		arg.Status,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Password,
+		&i.Isverified,
+		&i.Otp,
+		&i.MfaEnabled,
+		&i.TotpSecret,
+		&i.TotpLastCounter,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.PhoneOtp,
+		&i.PhoneOtpExpiresAt,
+		&i.OtpChannel,
+		&i.SmsMfaEnabled,
+		&i.Locale,
+		&i.Role,
+		&i.SessionsRevokedAt,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DeletedAt,
+		&i.DeletionScheduledFor,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.Username,
+		&i.Attributes,
+		&i.Status,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.VerifiedAt,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const incrementEmailChangeAttempts = `-- name: IncrementEmailChangeAttempts :one
+UPDATE email_changes
+SET attempts = attempts + 1
+WHERE user_id = $1
+RETURNING attempts
+`
+
+func (q *Queries) IncrementEmailChangeAttempts(ctx context.Context, userID int64) (int32, error) {
+	row := q.db.QueryRow(ctx, incrementEmailChangeAttempts, userID)
+	var attempts int32
+	err := row.Scan(&attempts)
+	return attempts, err
+}
+
+const listAuditEventsByUser = `-- name: ListAuditEventsByUser :many
+SELECT id, user_id, actor_id, action, ip, user_agent, details, created_at FROM audit_events
+WHERE user_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListAuditEventsByUser(ctx context.Context, userID pgtype.Int8) ([]AuditEvent, error) {
+	rows, err := q.db.Query(ctx, listAuditEventsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditEvent
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ActorID,
+			&i.Action,
+			&i.Ip,
+			&i.UserAgent,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvitations = `-- name: ListInvitations :many
+SELECT id, email, name, locale, token_hash, invited_by, expires_at, accepted_at, accepted_user_id, revoked_at, created_at FROM invitations
+WHERE CASE $3::text
+    WHEN 'pending' THEN accepted_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+    WHEN 'expired' THEN accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= now()
+    WHEN 'accepted' THEN accepted_at IS NOT NULL
+    WHEN 'revoked' THEN revoked_at IS NOT NULL
+    ELSE TRUE
+  END
+ORDER BY id DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListInvitationsParams struct {
+	Limit  int32
+	Offset int32
+	Status string
+}
+
+func (q *Queries) ListInvitations(ctx context.Context, arg ListInvitationsParams) ([]Invitation, error) {
+	rows, err := q.db.Query(ctx, listInvitations, arg.Limit, arg.Offset, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Invitation
+	for rows.Next() {
+		var i Invitation
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Name,
+			&i.Locale,
+			&i.TokenHash,
+			&i.InvitedBy,
+			&i.ExpiresAt,
+			&i.AcceptedAt,
+			&i.AcceptedUserID,
+			&i.RevokedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOutboxMessages = `-- name: ListOutboxMessages :many
+SELECT id, channel, recipient, subject, body_text, body_html, status, attempts, next_attempt_at, last_error, created_at, sent_at FROM outbox_messages
+WHERE status = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListOutboxMessagesParams struct {
+	Status string
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListOutboxMessages(ctx context.Context, arg ListOutboxMessagesParams) ([]OutboxMessage, error) {
+	rows, err := q.db.Query(ctx, listOutboxMessages, arg.Status, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OutboxMessage
+	for rows.Next() {
+		var i OutboxMessage
+		if err := rows.Scan(
+			&i.ID,
+			&i.Channel,
+			&i.Recipient,
+			&i.Subject,
+			&i.BodyText,
+			&i.BodyHtml,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserIdentities = `-- name: ListUserIdentities :many
+SELECT id, user_id, provider, subject, email, created_at, last_used_at FROM user_identities
+WHERE user_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListUserIdentities(ctx context.Context, userID int64) ([]UserIdentity, error) {
+	rows, err := q.db.Query(ctx, listUserIdentities, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserIdentity
+	for rows.Next() {
+		var i UserIdentity
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Provider,
+			&i.Subject,
+			&i.Email,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until FROM users
+WHERE ($1::boolean IS NULL OR isverified = $1)
+  AND ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
+  AND ($4::text IS NULL OR email ILIKE '%' || $4 || '%')
+  AND ($5::account_status IS NULL OR status = $5)
+  AND ($6::bigint IS NULL OR CASE
+        WHEN $7::text = 'created_at' AND NOT $8::boolean THEN (created_at, id) > ($9::timestamptz, $6)
+        WHEN $7 = 'created_at' THEN (created_at, id) < ($9, $6)
+        WHEN $7 = 'email' AND NOT $8 THEN (email, id) > ($10::text, $6)
+        WHEN $7 = 'email' THEN (email, id) < ($10, $6)
+        WHEN NOT $8 THEN id > $6
+        ELSE id < $6
+      END)
+ORDER BY
+  CASE WHEN $7 = 'created_at' AND NOT $8 THEN created_at END ASC,
+  CASE WHEN $7 = 'created_at' AND $8 THEN created_at END DESC,
+  CASE WHEN $7 = 'email' AND NOT $8 THEN email END ASC,
+  CASE WHEN $7 = 'email' AND $8 THEN email END DESC,
+  CASE WHEN NOT $8 THEN id END ASC,
+  CASE WHEN $8 THEN id END DESC
+LIMIT $11
+`
+
+type ListUsersParams struct {
+	Verified        pgtype.Bool
+	CreatedAfter    pgtype.Timestamptz
+	CreatedBefore   pgtype.Timestamptz
+	EmailContains   pgtype.Text
+	Status          NullAccountStatus
+	CursorID        pgtype.Int8
+	SortBy          string
+	Descending      bool
+	CursorCreatedAt pgtype.Timestamptz
+	CursorEmail     pgtype.Text
+	PageSize        int32
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsers,
+		arg.Verified,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.EmailContains,
+		arg.Status,
+		arg.CursorID,
+		arg.SortBy,
+		arg.Descending,
+		arg.CursorCreatedAt,
+		arg.CursorEmail,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Password,
+			&i.Isverified,
+			&i.Otp,
+			&i.MfaEnabled,
+			&i.TotpSecret,
+			&i.TotpLastCounter,
+			&i.Phone,
+			&i.PhoneVerified,
+			&i.PhoneOtp,
+			&i.PhoneOtpExpiresAt,
+			&i.OtpChannel,
+			&i.SmsMfaEnabled,
+			&i.Locale,
+			&i.Role,
+			&i.SessionsRevokedAt,
+			&i.DisplayName,
+			&i.Timezone,
+			&i.DeletedAt,
+			&i.DeletionScheduledFor,
+			&i.DisabledAt,
+			&i.CreatedAt,
+			&i.Username,
+			&i.Attributes,
+			&i.Status,
+			&i.UpdatedAt,
+			&i.LastLoginAt,
+			&i.VerifiedAt,
+			&i.MfaFailedAttempts,
+			&i.MfaLockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersAfter = `-- name: ListUsersAfter :many
+SELECT id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until FROM users
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListUsersAfterParams struct {
+	ID    int64
+	Limit int32
+}
+
+func (q *Queries) ListUsersAfter(ctx context.Context, arg ListUsersAfterParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsersAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Password,
+			&i.Isverified,
+			&i.Otp,
+			&i.MfaEnabled,
+			&i.TotpSecret,
+			&i.TotpLastCounter,
+			&i.Phone,
+			&i.PhoneVerified,
+			&i.PhoneOtp,
+			&i.PhoneOtpExpiresAt,
+			&i.OtpChannel,
+			&i.SmsMfaEnabled,
+			&i.Locale,
+			&i.Role,
+			&i.SessionsRevokedAt,
+			&i.DisplayName,
+			&i.Timezone,
+			&i.DeletedAt,
+			&i.DeletionScheduledFor,
+			&i.DisabledAt,
+			&i.CreatedAt,
+			&i.Username,
+			&i.Attributes,
+			&i.Status,
+			&i.UpdatedAt,
+			&i.LastLoginAt,
+			&i.VerifiedAt,
+			&i.MfaFailedAttempts,
+			&i.MfaLockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersAtEmailDomains = `-- name: ListUsersAtEmailDomains :many
+SELECT id, email FROM users
+WHERE lower(split_part(email, '@', 2)) = ANY($1::text[])
+ORDER BY id
+`
+
+type ListUsersAtEmailDomainsRow struct {
+	ID    int64
+	Email string
+}
+
+func (q *Queries) ListUsersAtEmailDomains(ctx context.Context, domains []string) ([]ListUsersAtEmailDomainsRow, error) {
+	rows, err := q.db.Query(ctx, listUsersAtEmailDomains, domains)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersAtEmailDomainsRow
+	for rows.Next() {
+		var i ListUsersAtEmailDomainsRow
+		if err := rows.Scan(&i.ID, &i.Email); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersDueForPurge = `-- name: ListUsersDueForPurge :many
+SELECT id FROM users
+WHERE deletion_scheduled_for <= now()
+`
+
+func (q *Queries) ListUsersDueForPurge(ctx context.Context) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listUsersDueForPurge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersWithPlaintextOTP = `-- name: ListUsersWithPlaintextOTP :many
+SELECT email, otp FROM users
+WHERE otp <> '' AND otp NOT LIKE 'hmac-sha256$%'
+`
+
+type ListUsersWithPlaintextOTPRow struct {
+	Email string
+	Otp   string
+}
+
+func (q *Queries) ListUsersWithPlaintextOTP(ctx context.Context) ([]ListUsersWithPlaintextOTPRow, error) {
+	rows, err := q.db.Query(ctx, listUsersWithPlaintextOTP)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersWithPlaintextOTPRow
+	for rows.Next() {
+		var i ListUsersWithPlaintextOTPRow
+		if err := rows.Scan(&i.Email, &i.Otp); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebAuthnCredentials = `-- name: ListWebAuthnCredentials :many
+SELECT id, user_id, credential_id, name, credential, sign_count, created_at, last_used_at FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListWebAuthnCredentials(ctx context.Context, userID int64) ([]WebauthnCredential, error) {
+	rows, err := q.db.Query(ctx, listWebAuthnCredentials, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebauthnCredential
+	for rows.Next() {
+		var i WebauthnCredential
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.CredentialID,
+			&i.Name,
+			&i.Credential,
+			&i.SignCount,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserForUpdate = `-- name: LockUserForUpdate :one
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+// Serializes changes to a user's sign-in methods.
+func (q *Queries) LockUserForUpdate(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRow(ctx, lockUserForUpdate, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const markOutboxMessageSent = `-- name: MarkOutboxMessageSent :exec
+UPDATE outbox_messages
+SET status = 'sent', sent_at = now(), last_error = '', body_text = '', body_html = ''
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxMessageSent(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markOutboxMessageSent, id)
+	return err
+}
+
+const purgeDeletedUsers = `-- name: PurgeDeletedUsers :many
+DELETE FROM users
+WHERE id = ANY($1::bigint[]) AND deletion_scheduled_for <= now()
+RETURNING id
+`
+
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, ids []int64) ([]int64, error) {
+	rows, err := q.db.Query(ctx, purgeDeletedUsers, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeOutboxBodies = `-- name: PurgeOutboxBodies :execrows
+UPDATE outbox_messages
+SET body_text = '', body_html = ''
+WHERE status = 'dead' AND created_at < $1 AND (body_text <> '' OR body_html <> '')
+`
+
+// Blanks the bodies of dead-lettered messages queued before the cutoff. Sent messages lose theirs
+// as soon as they are delivered.
+func (q *Queries) PurgeOutboxBodies(ctx context.Context, cutoff pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeOutboxBodies, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordMFAFailure = `-- name: RecordMFAFailure :one
+UPDATE users
+SET mfa_failed_attempts = CASE WHEN mfa_failed_attempts + 1 >= $1::int THEN 0 ELSE mfa_failed_attempts + 1 END,
+    mfa_locked_until = CASE WHEN mfa_failed_attempts + 1 >= $1::int THEN $2 ELSE mfa_locked_until END
+WHERE id = $3
+RETURNING mfa_locked_until
+`
+
+type RecordMFAFailureParams struct {
+	MaxAttempts int32
+	LockedUntil pgtype.Timestamptz
+	ID          int64
+}
+
+// Counts a wrong second-factor code. The max_attempts-th one in a row locks second factors until
+// locked_until and starts the count again.
+func (q *Queries) RecordMFAFailure(ctx context.Context, arg RecordMFAFailureParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, recordMFAFailure, arg.MaxAttempts, arg.LockedUntil, arg.ID)
+	var mfa_locked_until pgtype.Timestamptz
+	err := row.Scan(&mfa_locked_until)
+	return mfa_locked_until, err
+}
+
+const recordUserLogin = `-- name: RecordUserLogin :exec
+UPDATE users
+SET last_login_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) RecordUserLogin(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, recordUserLogin, id)
+	return err
+}
+
+const renewInvitation = `-- name: RenewInvitation :one
+UPDATE invitations
+SET token_hash = $2, expires_at = $3
+WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+RETURNING id, email, name, locale, token_hash, invited_by, expires_at, accepted_at, accepted_user_id, revoked_at, created_at
+`
+
+type RenewInvitationParams struct {
+	ID        int64
+	TokenHash string
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) RenewInvitation(ctx context.Context, arg RenewInvitationParams) (Invitation, error) {
+	row := q.db.QueryRow(ctx, renewInvitation, arg.ID, arg.TokenHash, arg.ExpiresAt)
+	var i Invitation
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Locale,
+		&i.TokenHash,
+		&i.InvitedBy,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+		&i.AcceptedUserID,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const rescheduleOutboxMessage = `-- name: RescheduleOutboxMessage :exec
+UPDATE outbox_messages
+SET status = 'pending', next_attempt_at = $2, last_error = $3
+WHERE id = $1
+`
+
+type RescheduleOutboxMessageParams struct {
+	ID            int64
+	NextAttemptAt pgtype.Timestamptz
+	LastError     string
+}
+
+func (q *Queries) RescheduleOutboxMessage(ctx context.Context, arg RescheduleOutboxMessageParams) error {
+	_, err := q.db.Exec(ctx, rescheduleOutboxMessage, arg.ID, arg.NextAttemptAt, arg.LastError)
+	return err
+}
+
+const resetMFAFailures = `-- name: ResetMFAFailures :exec
+UPDATE users
+SET mfa_failed_attempts = 0, mfa_locked_until = NULL
+WHERE id = $1 AND (mfa_failed_attempts <> 0 OR mfa_locked_until IS NOT NULL)
+`
+
+func (q *Queries) ResetMFAFailures(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, resetMFAFailures, id)
+	return err
+}
+
+const retryOutboxMessage = `-- name: RetryOutboxMessage :one
+UPDATE outbox_messages
+SET status = 'pending', attempts = 0, next_attempt_at = now()
+WHERE id = $1 AND status = 'dead' AND (body_text <> '' OR body_html <> '')
+RETURNING id, channel, recipient, subject, body_text, body_html, status, attempts, next_attempt_at, last_error, created_at, sent_at
+`
+
+// Only messages that still have their bodies can be sent again.
+func (q *Queries) RetryOutboxMessage(ctx context.Context, id int64) (OutboxMessage, error) {
+	row := q.db.QueryRow(ctx, retryOutboxMessage, id)
+	var i OutboxMessage
+	err := row.Scan(
+		&i.ID,
+		&i.Channel,
+		&i.Recipient,
+		&i.Subject,
+		&i.BodyText,
+		&i.BodyHtml,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+const revokeInvitation = `-- name: RevokeInvitation :one
+UPDATE invitations
+SET revoked_at = now()
+WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+RETURNING id, email, name, locale, token_hash, invited_by, expires_at, accepted_at, accepted_user_id, revoked_at, created_at
+`
+
+func (q *Queries) RevokeInvitation(ctx context.Context, id int64) (Invitation, error) {
+	row := q.db.QueryRow(ctx, revokeInvitation, id)
+	var i Invitation
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Locale,
+		&i.TokenHash,
+		&i.InvitedBy,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+		&i.AcceptedUserID,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :exec
+UPDATE users
+SET sessions_revoked_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, revokeUserSessions, id)
+	return err
+}
+
+const rewriteUserEmail = `-- name: RewriteUserEmail :exec
+UPDATE users
+SET email = $2
+WHERE id = $1
+`
+
+type RewriteUserEmailParams struct {
+	ID    int64
+	Email string
+}
+
+func (q *Queries) RewriteUserEmail(ctx context.Context, arg RewriteUserEmailParams) error {
+	_, err := q.db.Exec(ctx, rewriteUserEmail, arg.ID, arg.Email)
+	return err
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :exec
+UPDATE users
+SET deleted_at = now(), deletion_scheduled_for = $2, sessions_revoked_at = now(), status = 'deleted'
+WHERE id = $1
+`
+
+type ScheduleUserDeletionParams struct {
+	ID                   int64
+	DeletionScheduledFor pgtype.Timestamptz
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) error {
+	_, err := q.db.Exec(ctx, scheduleUserDeletion, arg.ID, arg.DeletionScheduledFor)
+	return err
+}
+
+const scrubAuditEvents = `-- name: ScrubAuditEvents :exec
+UPDATE audit_events
+SET ip = '', user_agent = '',
+    details = CASE WHEN user_id = ANY($1::bigint[]) THEN '{}'::jsonb ELSE details END
+WHERE user_id = ANY($1::bigint[]) OR actor_id = ANY($1::bigint[])
+`
+
+// Erases what identifies the given users from the audit log before their accounts are deleted: the
+// details of events about them, and the IP address and user agent of events about or by them. The
+// action and its time are kept.
+func (q *Queries) ScrubAuditEvents(ctx context.Context, userIds []int64) error {
+	_, err := q.db.Exec(ctx, scrubAuditEvents, userIds)
+	return err
+}
+
+const setUserPhone = `-- name: SetUserPhone :exec
+UPDATE users
+SET phone = $2, phone_verified = false, phone_otp = $3, phone_otp_expires_at = $4,
+    otp_channel = 'email', sms_mfa_enabled = false
+WHERE id = $1
+`
+
+type SetUserPhoneParams struct {
+	ID                int64
+	Phone             string
+	PhoneOtp          string
+	PhoneOtpExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) SetUserPhone(ctx context.Context, arg SetUserPhoneParams) error {
+	_, err := q.db.Exec(ctx, setUserPhone,
+		arg.ID,
+		arg.Phone,
+		arg.PhoneOtp,
+		arg.PhoneOtpExpiresAt,
+	)
+	return err
+}
+
+const setUserPhoneOTP = `-- name: SetUserPhoneOTP :exec
+UPDATE users
+SET phone_otp = $2, phone_otp_expires_at = $3
+WHERE id = $1
+`
+
+type SetUserPhoneOTPParams struct {
+	ID                int64
+	PhoneOtp          string
+	PhoneOtpExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) SetUserPhoneOTP(ctx context.Context, arg SetUserPhoneOTPParams) error {
+	_, err := q.db.Exec(ctx, setUserPhoneOTP, arg.ID, arg.PhoneOtp, arg.PhoneOtpExpiresAt)
+	return err
+}
+
+const setUserStatus = `-- name: SetUserStatus :one
+UPDATE users
+SET status = CASE WHEN $1::account_status = 'active' AND NOT isverified THEN 'pending' ELSE $1 END,
+    disabled_at = CASE WHEN $1 = 'disabled' THEN now() END,
+    sessions_revoked_at = CASE WHEN $1 IN ('disabled', 'locked') THEN now() ELSE sessions_revoked_at END
+WHERE id = $2
+RETURNING id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until
+`
+
+type SetUserStatusParams struct {
+	Status AccountStatus
+	ID     int64
+}
+
+// Moves an account between active (pending while unverified), disabled and locked. Disabling or
+// locking it also ends its sessions.
+func (q *Queries) SetUserStatus(ctx context.Context, arg SetUserStatusParams) (User, error) {
+	row := q.db.QueryRow(ctx, setUserStatus, arg.Status, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Password,
+		&i.Isverified,
+		&i.Otp,
+		&i.MfaEnabled,
+		&i.TotpSecret,
+		&i.TotpLastCounter,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.PhoneOtp,
+		&i.PhoneOtpExpiresAt,
+		&i.OtpChannel,
+		&i.SmsMfaEnabled,
+		&i.Locale,
+		&i.Role,
+		&i.SessionsRevokedAt,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DeletedAt,
+		&i.DeletionScheduledFor,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.Username,
+		&i.Attributes,
+		&i.Status,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.VerifiedAt,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_last_counter = 0
+WHERE id = $1
+`
+
+type SetUserTOTPSecretParams struct {
+	ID         int64
+	TotpSecret string
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error {
+	_, err := q.db.Exec(ctx, setUserTOTPSecret, arg.ID, arg.TotpSecret)
+	return err
+}
+
+const spendMFAChallenge = `-- name: SpendMFAChallenge :one
+DELETE FROM mfa_challenges
+WHERE id = $1 AND user_id = $2 AND expires_at > now()
+RETURNING id
+`
+
+type SpendMFAChallengeParams struct {
+	ID     string
+	UserID int64
+}
+
+func (q *Queries) SpendMFAChallenge(ctx context.Context, arg SpendMFAChallengeParams) (string, error) {
+	row := q.db.QueryRow(ctx, spendMFAChallenge, arg.ID, arg.UserID)
+	var id string
+	err := row.Scan(&id)
+	return id, err
+}
+
+const takeOAuthState = `-- name: TakeOAuthState :one
+DELETE FROM oauth_states
+WHERE id = $1 AND provider = $2 AND expires_at > now()
+RETURNING id, provider, nonce, code_verifier, expires_at, user_id
+`
+
+type TakeOAuthStateParams struct {
+	ID       string
+	Provider string
+}
+
+func (q *Queries) TakeOAuthState(ctx context.Context, arg TakeOAuthStateParams) (OauthState, error) {
+	row := q.db.QueryRow(ctx, takeOAuthState, arg.ID, arg.Provider)
+	var i OauthState
+	err := row.Scan(
+		&i.ID,
+		&i.Provider,
+		&i.Nonce,
+		&i.CodeVerifier,
+		&i.ExpiresAt,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const takeSAMLRequest = `-- name: TakeSAMLRequest :one
+DELETE FROM saml_requests
+WHERE id = $1 AND connection = $2 AND expires_at > now()
+RETURNING id, connection, expires_at
+`
+
+type TakeSAMLRequestParams struct {
+	ID         string
+	Connection string
+}
+
+func (q *Queries) TakeSAMLRequest(ctx context.Context, arg TakeSAMLRequestParams) (SamlRequest, error) {
+	row := q.db.QueryRow(ctx, takeSAMLRequest, arg.ID, arg.Connection)
+	var i SamlRequest
+	err := row.Scan(&i.ID, &i.Connection, &i.ExpiresAt)
+	return i, err
+}
+
+const takeWebAuthnSession = `-- name: TakeWebAuthnSession :one
+DELETE FROM webauthn_sessions
+WHERE id = $1 AND purpose = $2
+RETURNING id, user_id, purpose, data, expires_at
+`
+
+type TakeWebAuthnSessionParams struct {
+	ID      string
+	Purpose string
+}
+
+func (q *Queries) TakeWebAuthnSession(ctx context.Context, arg TakeWebAuthnSessionParams) (WebauthnSession, error) {
+	row := q.db.QueryRow(ctx, takeWebAuthnSession, arg.ID, arg.Purpose)
+	var i WebauthnSession
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Purpose,
+		&i.Data,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const touchUserIdentity = `-- name: TouchUserIdentity :exec
+UPDATE user_identities
+SET email = $2, last_used_at = now()
+WHERE id = $1
+`
+
+type TouchUserIdentityParams struct {
+	ID    int64
+	Email string
+}
+
+func (q *Queries) TouchUserIdentity(ctx context.Context, arg TouchUserIdentityParams) error {
+	_, err := q.db.Exec(ctx, touchUserIdentity, arg.ID, arg.Email)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :exec
+UPDATE users
+SET isverified = TRUE, verified_at = COALESCE(verified_at, now()),
+    status = CASE WHEN status = 'pending' THEN 'active' ELSE status END
+WHERE lower(email) = lower($1::text)
+`
+
+func (q *Queries) UpdateUser(ctx context.Context, email string) error {
+	_, err := q.db.Exec(ctx, updateUser, email)
+	return err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :exec
+UPDATE users
+SET email = $2, verified_at = now()
+WHERE id = $1
+`
+
+type UpdateUserEmailParams struct {
+	ID    int64
+	Email string
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
+	_, err := q.db.Exec(ctx, updateUserEmail, arg.ID, arg.Email)
+	return err
+}
+
+const updateUserMessagingPreferences = `-- name: UpdateUserMessagingPreferences :exec
+UPDATE users
+SET otp_channel = $2, sms_mfa_enabled = $3
+WHERE id = $1
+`
+
+type UpdateUserMessagingPreferencesParams struct {
+	ID            int64
+	OtpChannel    string
+	SmsMfaEnabled bool
+}
+
+func (q *Queries) UpdateUserMessagingPreferences(ctx context.Context, arg UpdateUserMessagingPreferencesParams) error {
+	_, err := q.db.Exec(ctx, updateUserMessagingPreferences, arg.ID, arg.OtpChannel, arg.SmsMfaEnabled)
+	return err
+}
+
+const updateUserOTP = `-- name: UpdateUserOTP :exec
+UPDATE users
+SET otp = $1
+WHERE lower(email) = lower($2::text)
+`
+
+type UpdateUserOTPParams struct {
+	Otp   string
+	Email string
+}
+
+func (q *Queries) UpdateUserOTP(ctx context.Context, arg UpdateUserOTPParams) error {
+	_, err := q.db.Exec(ctx, updateUserOTP, arg.Otp, arg.Email)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET name = $2, display_name = $3, locale = $4, timezone = $5, attributes = $6
+WHERE id = $1
+RETURNING id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until
+`
+
+type UpdateUserProfileParams struct {
+	ID          int64
+	Name        string
+	DisplayName string
+	Locale      string
+	Timezone    string
+	Attributes  []byte
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserProfile,
+		arg.ID,
+		arg.Name,
+		arg.DisplayName,
+		arg.Locale,
+		arg.Timezone,
+		arg.Attributes,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Password,
+		&i.Isverified,
+		&i.Otp,
+		&i.MfaEnabled,
+		&i.TotpSecret,
+		&i.TotpLastCounter,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.PhoneOtp,
+		&i.PhoneOtpExpiresAt,
+		&i.OtpChannel,
+		&i.SmsMfaEnabled,
+		&i.Locale,
+		&i.Role,
+		&i.SessionsRevokedAt,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DeletedAt,
+		&i.DeletionScheduledFor,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.Username,
+		&i.Attributes,
+		&i.Status,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.VerifiedAt,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const updateWebAuthnCredentialUsage = `-- name: UpdateWebAuthnCredentialUsage :exec
+UPDATE webauthn_credentials
+SET credential = $2, sign_count = $3, last_used_at = now()
+WHERE id = $1
+`
+
+type UpdateWebAuthnCredentialUsageParams struct {
+	ID         int64
+	Credential []byte
+	SignCount  int64
+}
+
+func (q *Queries) UpdateWebAuthnCredentialUsage(ctx context.Context, arg UpdateWebAuthnCredentialUsageParams) error {
+	_, err := q.db.Exec(ctx, updateWebAuthnCredentialUsage, arg.ID, arg.Credential, arg.SignCount)
+	return err
+}
+
+const upsertEmailChange = `-- name: UpsertEmailChange :exec
+INSERT INTO email_changes (user_id, new_email, code_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+SET new_email = EXCLUDED.new_email, code_hash = EXCLUDED.code_hash, attempts = 0,
+    expires_at = EXCLUDED.expires_at, created_at = now()
+`
+
+type UpsertEmailChangeParams struct {
+	UserID    int64
+	NewEmail  string
+	CodeHash  string
+	ExpiresAt pgtype.Timestamptz
+}
+
+func (q *Queries) UpsertEmailChange(ctx context.Context, arg UpsertEmailChangeParams) error {
+	_, err := q.db.Exec(ctx, upsertEmailChange,
+		arg.UserID,
+		arg.NewEmail,
+		arg.CodeHash,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const useMagicLink = `-- name: UseMagicLink :one
+UPDATE magic_links
+SET used_at = now()
+WHERE id = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING user_id
+`
+
+func (q *Queries) UseMagicLink(ctx context.Context, id string) (int64, error) {
+	row := q.db.QueryRow(ctx, useMagicLink, id)
+	var user_id int64
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :one
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING id
+`
+
+type UseRecoveryCodeParams struct {
+	UserID   int64
+	CodeHash string
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	row := q.db.QueryRow(ctx, useRecoveryCode, arg.UserID, arg.CodeHash)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const verifyUserByID = `-- name: VerifyUserByID :one
+UPDATE users
+SET isverified = TRUE, verified_at = COALESCE(verified_at, now()),
+    status = CASE WHEN status = 'pending' THEN 'active' ELSE status END
+WHERE id = $1
+RETURNING id, name, email, password, isverified, otp, mfa_enabled, totp_secret, totp_last_counter, phone, phone_verified, phone_otp, phone_otp_expires_at, otp_channel, sms_mfa_enabled, locale, role, sessions_revoked_at, display_name, timezone, deleted_at, deletion_scheduled_for, disabled_at, created_at, username, attributes, status, updated_at, last_login_at, verified_at, mfa_failed_attempts, mfa_locked_until
+`
+
+func (q *Queries) VerifyUserByID(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, verifyUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Password,
+		&i.Isverified,
+		&i.Otp,
+		&i.MfaEnabled,
+		&i.TotpSecret,
+		&i.TotpLastCounter,
+		&i.Phone,
+		&i.PhoneVerified,
+		&i.PhoneOtp,
+		&i.PhoneOtpExpiresAt,
+		&i.OtpChannel,
+		&i.SmsMfaEnabled,
+		&i.Locale,
+		&i.Role,
+		&i.SessionsRevokedAt,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DeletedAt,
+		&i.DeletionScheduledFor,
+		&i.DisabledAt,
+		&i.CreatedAt,
+		&i.Username,
+		&i.Attributes,
+		&i.Status,
+		&i.UpdatedAt,
+		&i.LastLoginAt,
+		&i.VerifiedAt,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const verifyUserPhone = `-- name: VerifyUserPhone :exec
+UPDATE users
+SET phone_verified = TRUE, phone_otp = '', phone_otp_expires_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) VerifyUserPhone(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, verifyUserPhone, id)
 	return err
 }
@@ -15,9 +15,2790 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/invitations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists invitations by state, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Invitation list route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pending, expired, accepted, revoked or all (default pending)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of invitations to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "invitations",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Emails an invitation with a single-use link. Accepting it creates a verified account without an OTP.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Invitation create route",
+                "parameters": [
+                    {
+                        "description": "Invitee's email, and optionally their name and locale",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.InvitationCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "invitation",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Invalid Email, Invalid locale",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already registered, An open invitation already exists for this email",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/invitations/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancels an invitation that has not been accepted, so that its link stops working.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Invitation revoke route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Invitation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "invitation",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Open invitation not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/invitations/{id}/resend": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Emails a new link for an invitation that has not been accepted or revoked, restarting its lifetime. Earlier links stop working.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Invitation resend route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Invitation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "invitation",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Open invitation not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/outbox": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists queued email and SMS messages by delivery status, newest first. Message bodies are not included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Outbox list route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "pending, sending, sent or dead (default dead)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of messages to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "messages",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/outbox/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Puts a dead-lettered message back in the queue with a fresh set of delivery attempts. Messages can be retried until their bodies are purged, OUTBOX_BODY_RETENTION after they were queued.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Outbox retry route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Message queued for delivery",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead-lettered message not found, or its body has been purged",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists users matching the filters, one page at a time. Pass next_cursor from a response as cursor to get the following page with the same filters and order.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "User list route",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only verified (true) or unverified (false) users",
+                        "name": "verified",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only users with this status: pending, active, disabled, locked or deleted",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only users created at or after this RFC 3339 time",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only users created before this RFC 3339 time",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only users whose email contains this text, ignoring case",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id, created_at or email (default created_at)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc or desc (default desc)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, at most 200 (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "users, next_cursor",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter, Invalid cursor",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns a user with their account status.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "User detail route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a user and their data immediately, without the grace period of self-service deletion. Their audit events are kept with the IP addresses, user agents and details removed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "User delete route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User deleted",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Admins cannot delete their own account",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disables a user: their sessions end and they cannot sign in until the account is enabled again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "User disable route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Admins cannot disable their own account",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Account deleted",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/enable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lets a disabled or locked user sign in again. Users who have not verified their email address yet return to pending.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "User enable route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Account deleted",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/lock": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Locks a user out temporarily, for example while a compromise is investigated: their sessions end and they cannot sign in until the account is enabled again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "User lock route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Admins cannot lock their own account",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Account deleted",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ends all of a user's sessions. They can sign in again straight away.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "User sign-out route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sessions revoked",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/verify": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Marks a user's email address as verified without an OTP.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "User verification route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/email": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Starts changing the signed-in user's email address. A confirmation code is sent to the new address and a notice to the current one; the address only changes once the code is confirmed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Email change request route",
+                "parameters": [
+                    {
+                        "description": "New email address",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.EmailChange"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Confirmation code has been sent to the new email address",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Invalid Email, This is already your email address",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already registered",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/email/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Switches the signed-in user's email address to the pending one after checking the code sent to it. With revoke_sessions set, every other access token of the user stops working and a new token is returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Email change confirmation route",
+                "parameters": [
+                    {
+                        "description": "Code from the email and whether to sign out other sessions",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.EmailChangeConfirm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email address changed, with a token when sessions were revoked",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, No email change pending",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Invalid or expired code",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already registered",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/identities": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the identity provider accounts linked to the signed-in user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Identity list route",
+                "responses": {
+                    "200": {
+                        "description": "identities",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/identities/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Unlinks an identity provider account from the signed-in user. The last way to sign in cannot be removed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Identity unlink route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Identity ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Identity unlinked",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Identity not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "This is the last way to sign in to the account",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/invitations/accept": {
+            "post": {
+                "description": "Creates a verified account for the invited email address and signs it in, without an OTP. The token comes from the invitation link.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Invitation accept route",
+                "parameters": [
+                    {
+                        "description": "Token from the invitation link, password, and optionally name, username and attributes",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.InvitationAccept"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Invalid username, This username is reserved, Invalid attributes",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired invitation",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already registered, Username already taken",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "422": {
+                        "description": "Please provide with sufficient credentials",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
         "/auth/login": {
             "post": {
-                "description": "Allows users to login into their account.",
+                "description": "Allows users to login into their account. With the right password, an unverified account is sent a new verification code instead.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Login route",
+                "parameters": [
+                    {
+                        "description": "User's email or username and password",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Login"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response, or mfa_required with a challenge_token and the available mfa_methods",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Please provide with sufficient credentials",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid Credentials",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User is not registered",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "422": {
+                        "description": "Email already registered, please verify your email address",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/magic-link": {
+            "post": {
+                "description": "Emails a single-use, short-lived sign-in link. The email is sent in the background, so the response is the same, and as fast, whether or not the email is registered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Magic link request route",
+                "parameters": [
+                    {
+                        "description": "User's email",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MagicLink"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "If the email is registered, a sign-in link has been sent to it.",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Invalid Email",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Magic-link login is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/magic-link/callback": {
+            "get": {
+                "description": "Checks the token from a magic link without using it. Mail scanners and link previews open links too, so the page behind the link asks the user to confirm and then posts the token to the confirm route.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Magic link check route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Token from the emailed link",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Link is valid, confirm to sign in",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired link",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Magic-link login is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Exchanges the token from a magic link for the same response as login, using up the link. Using a link also verifies the email address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Magic link confirm route",
+                "parameters": [
+                    {
+                        "description": "Token from the emailed link",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MagicLinkConfirm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response, or mfa_required with a challenge_token and the available mfa_methods",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired link",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Magic-link login is disabled",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the signed-in user's MFA recovery codes with a new set. All previous codes stop working.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Recovery code regeneration route",
+                "responses": {
+                    "200": {
+                        "description": "recovery_codes",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Two-factor authentication is not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/sms/send": {
+            "post": {
+                "description": "Sends a one-time code by SMS to the user of an MFA challenge token returned by login.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "SMS second factor send route",
+                "parameters": [
+                    {
+                        "description": "Challenge token",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SMSMFASend"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Code sent",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, SMS codes are not enabled, Text messages are not available on this server",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid, expired or already used challenge token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many wrong codes, second factors are locked for a while",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/sms/verify": {
+            "post": {
+                "description": "Exchanges an MFA challenge token and the code sent by SMS for an access token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "SMS second factor verification route",
+                "parameters": [
+                    {
+                        "description": "Challenge token and code",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SMSMFAVerify"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid, expired or already used challenge token, Invalid or expired code",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many wrong codes, second factors are locked for a while",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Confirms TOTP enrollment with a first code from the authenticator app and enables two-factor login.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "TOTP confirmation route",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TOTPConfirm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication enabled, with recovery_codes",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, TOTP enrollment has not been started",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid code",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/totp/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generates a new TOTP secret for the signed-in user and returns its otpauth:// URI and a QR code PNG (base64). Two-factor login is enabled only after the first code is confirmed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "TOTP enrollment route",
+                "responses": {
+                    "200": {
+                        "description": "otpauth_uri, secret and qr_code",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication is already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/verify": {
+            "post": {
+                "description": "Exchanges the challenge token returned by login and a second-factor code for an access token. A recovery code may be given instead of a TOTP code; each one works once, as does the challenge token. Five wrong codes in a row lock the user's second factors for 15 minutes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "MFA verification route",
+                "parameters": [
+                    {
+                        "description": "Challenge token and either a TOTP code or a recovery code",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MFAVerify"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid, expired or already used challenge token, Invalid code, Invalid recovery code",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many wrong codes, second factors are locked for a while",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/webauthn/begin": {
+            "post": {
+                "description": "Starts a passkey assertion for the user of an MFA challenge token returned by login.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Passkey second factor start route",
+                "parameters": [
+                    {
+                        "description": "Challenge token",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WebAuthnMFABegin"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "options and session_id",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, No passkeys registered",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired challenge token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/mfa/webauthn/finish": {
+            "post": {
+                "description": "Verifies the passkey assertion for an MFA challenge and returns the access token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mfa"
+                ],
+                "summary": "Passkey second factor finish route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID from the begin route",
+                        "name": "session_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Challenge token returned by login",
+                        "name": "challenge_token",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "PublicKeyCredential from navigator.credentials.get()",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or expired passkey session",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid, expired or already used challenge token, Passkey verification failed",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/otp": {
+            "post": {
+                "description": "Allows users to validate OTP and complete the registration process.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Validation route",
+                "parameters": [
+                    {
+                        "description": "User's email address and otp",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OTP"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response, User already verified. Please login.",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Invalid Email",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid OTP",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "User does not exist. Please register to generate OTP.",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "422": {
+                        "description": "Please provide with sufficient credentials",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/phone": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sets the signed-in user's phone number (E.164, e.g. +14155550123) and sends a verification code to it by SMS. Changing the number resets SMS delivery preferences.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "phone"
+                ],
+                "summary": "Phone number route",
+                "parameters": [
+                    {
+                        "description": "Phone number",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Phone"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Verification code sent",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Invalid phone number, Text messages are not available on this server",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/phone/preferences": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Chooses whether OTPs are delivered by email or SMS, and whether SMS codes can be used as a second factor. SMS requires a verified phone number.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "phone"
+                ],
+                "summary": "Messaging preferences route",
+                "parameters": [
+                    {
+                        "description": "otp_channel (email or sms) and sms_mfa",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MessagingPreferences"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Preferences updated",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Please verify your phone number first, Text messages are not available on this server",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/phone/verify": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Verifies the signed-in user's phone number with the code sent by SMS.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "phone"
+                ],
+                "summary": "Phone verification route",
+                "parameters": [
+                    {
+                        "description": "Code from the SMS",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PhoneVerify"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Phone number verified",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, No phone number pending verification",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired code",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many wrong codes, second factors are locked for a while",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/providers": {
+            "get": {
+                "description": "Lists the external identity providers users can sign in with.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Identity provider list route",
+                "responses": {
+                    "200": {
+                        "description": "providers, each with a name and display_name",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/providers/{provider}/callback": {
+            "get": {
+                "description": "Finishes a sign-in started at the provider sign-in route and answers like login. A new identity with a verified email address gets a new account; when OIDC_AUTO_LINK is on, it is linked to an existing account with that address instead. Finishing a sign-in started at the link route links the identity to the signed-in user and answers with it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Identity provider callback route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code from the provider",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State from the provider",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response, or mfa_required with a challenge_token and the available mfa_methods",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "201": {
+                        "description": "identity, when linking",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid email, Invalid attributes",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired sign-in, Sign-in cancelled, Sign-in failed",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "The provider has not verified the email address, Account disabled or locked",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown identity provider",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "An account with this email exists but the identity is not linked to it, An unverified account with this email exists, Identity linked to another account",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/providers/{provider}/link": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Starts linking an account at an identity provider to the signed-in user. Send the browser to url; the provider's callback route then links the identity. The browser must keep the cookie set by this response.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Identity link route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "url",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown identity provider",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/providers/{provider}/login": {
+            "get": {
+                "description": "Redirects the browser to the provider's sign-in page. The provider sends it back to the callback route.",
+                "tags": [
+                    "user"
+                ],
+                "summary": "Identity provider sign-in route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Unknown identity provider",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reauthenticate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Proves the signed-in user's identity again, as strongly as signing in: with their password when the account has one, and with a second factor whenever one is enabled. method picks the second factor: \"totp\" or \"sms\" with a code, or \"webauthn\" with the session_id of the passkey reauthentication route and the credential from navigator.credentials.get(). Wrong passwords count towards the same lockout as wrong codes. Returns an elevated token whose auth_time is now. Sensitive routes answer 401 with error \"reauthentication_required\" until this is done.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Reauthentication route",
+                "parameters": [
+                    {
+                        "description": "Password and second factor",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Reauthenticate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Two-factor authentication is not enabled, SMS codes are not enabled, Invalid or expired passkey session",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid Credentials, A second factor is required, Invalid code, Passkey verification failed",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many wrong passwords or codes, reauthentication is locked for a while",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reauthenticate/sms": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sends a one-time code by SMS to the signed-in user, to reauthenticate with method \"sms\".",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Reauthentication SMS route",
+                "responses": {
+                    "200": {
+                        "description": "Code sent",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "SMS codes are not enabled, Text messages are not available on this server",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many wrong codes, second factors are locked for a while",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reauthenticate/webauthn": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Starts a passkey assertion for the signed-in user, to reauthenticate with method \"webauthn\". Pass options to navigator.credentials.get() and send the result to the reauthentication route with the session_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Reauthentication passkey route",
+                "responses": {
+                    "200": {
+                        "description": "options and session_id",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "No passkeys registered",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Allows users to create a new account.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Register route",
+                "parameters": [
+                    {
+                        "description": "User name, email, password, and optionally a phone number, otp_channel (email or sms) and locale (defaults to Accept-Language)",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Register"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Successful response",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON data, Invalid Email, Invalid phone number, Invalid username, This username is reserved, Text messages are not available on this server",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid Credentials",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "User already exists, Username already taken",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "422": {
+                        "description": "Please provide with sufficient credentials",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error, Error in inserting the document",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/saml/{connection}/acs": {
+            "post": {
+                "description": "Finishes a sign-in started at the SAML sign-in route and answers like login. The response must be signed by the identity provider, answer the request, and be addressed to this service within its validity period. Asserted email addresses must be at one of the connection's allowed_domains. Attributes fill the new account of a user the connection provisions; with link_by_email, the identity is linked to an existing account with the same email address instead.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "SAML Assertion Consumer Service route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Connection name",
+                        "name": "connection",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Base64-encoded SAML response",
+                        "name": "SAMLResponse",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful response, or mfa_required with a challenge_token and the available mfa_methods",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid email, Invalid attributes",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired sign-in, Invalid SAML response",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "403": {
+                        "description": "No email address asserted, Email domain not allowed for the connection, The connection does not provision new users, Account disabled or locked",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown SAML connection",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "An account with this email exists but the identity is not linked to it, An unverified account with this email exists, Identity linked to another account",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/saml/{connection}/login": {
+            "get": {
+                "description": "Redirects the browser to the identity provider of a connection with an AuthnRequest. The provider posts its response to the Assertion Consumer Service route.",
+                "tags": [
+                    "user"
+                ],
+                "summary": "SAML sign-in route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Connection name",
+                        "name": "connection",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Unknown SAML connection",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/saml/{connection}/metadata": {
+            "get": {
+                "description": "Returns the SAML 2.0 service provider metadata of a connection, to register with its identity provider.",
+                "produces": [
+                    "text/xml"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "SAML service provider metadata route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Connection name",
+                        "name": "connection",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "EntityDescriptor",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown SAML connection",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/webauthn/credentials": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the signed-in user's passkeys.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webauthn"
+                ],
+                "summary": "Passkey list route",
+                "responses": {
+                    "200": {
+                        "description": "credentials",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/webauthn/credentials/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes one of the signed-in user's passkeys.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webauthn"
+                ],
+                "summary": "Passkey removal route",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Credential ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Passkey removed",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "404": {
+                        "description": "Passkey not found",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "This is the last way to sign in to the account",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/webauthn/login/begin": {
+            "post": {
+                "description": "Starts a passwordless sign-in with a discoverable passkey. Pass options to navigator.credentials.get() and send the result to the finish route with the session_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webauthn"
+                ],
+                "summary": "Passkey login start route",
+                "responses": {
+                    "200": {
+                        "description": "options and session_id",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/webauthn/login/finish": {
+            "post": {
+                "description": "Verifies the assertion returned by navigator.credentials.get() and signs the user in.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,17 +2806,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "webauthn"
                 ],
-                "summary": "Login route",
+                "summary": "Passkey login finish route",
                 "parameters": [
                     {
-                        "description": "User's email and password",
+                        "type": "string",
+                        "description": "Session ID from the begin route",
+                        "name": "session_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "PublicKeyCredential from navigator.credentials.get()",
                         "name": "Body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.Login"
+                            "type": "object"
                         }
                     }
                 ],
@@ -47,25 +2835,192 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Please provide with sufficient credentials",
+                        "description": "Invalid or expired passkey session",
                         "schema": {
                             "$ref": "#/definitions/responses.ErrorResponse_doc"
                         }
                     },
                     "401": {
-                        "description": "Invalid Credentials",
+                        "description": "Passkey verification failed",
                         "schema": {
                             "$ref": "#/definitions/responses.ErrorResponse_doc"
                         }
                     },
-                    "404": {
-                        "description": "User is not registered",
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/webauthn/register/begin": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Starts registering a passkey for the signed-in user. Pass options to navigator.credentials.create() and send the result to the finish route with the session_id.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webauthn"
+                ],
+                "summary": "Passkey registration start route",
+                "responses": {
+                    "200": {
+                        "description": "options and session_id",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/webauthn/register/finish": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Verifies the attestation returned by navigator.credentials.create() and stores the new passkey.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webauthn"
+                ],
+                "summary": "Passkey registration finish route",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID from the begin route",
+                        "name": "session_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name to show for the passkey",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "description": "PublicKeyCredential from navigator.credentials.create()",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Passkey registered",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or expired passkey session",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Passkey verification failed, Reauthentication required",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "409": {
+                        "description": "Passkey already registered",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            }
+        },
+        "/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the signed-in user's profile.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Current user route",
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/responses.UserResponse_doc"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Schedules the signed-in user's account for permanent deletion after a grace period (30 days by default) and signs it out everywhere. Signing in again before then cancels the deletion. A confirmation email is sent.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Account deletion route",
+                "responses": {
+                    "202": {
+                        "description": "deletion_scheduled_for",
                         "schema": {
-                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                            "$ref": "#/definitions/responses.UserResponse_doc"
                         }
                     },
-                    "422": {
-                        "description": "Email already registered, please verify your email address",
+                    "401": {
+                        "description": "Invalid or expired token, Reauthentication required",
                         "schema": {
                             "$ref": "#/definitions/responses.ErrorResponse_doc"
                         }
@@ -77,11 +3032,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/auth/otp": {
-            "post": {
-                "description": "Allows users to validate OTP and complete the registration process.",
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the signed-in user's name, display name, locale, timezone or editable custom attributes. Fields left out are unchanged; email and verification status cannot be changed here.",
                 "consumes": [
                     "application/json"
                 ],
@@ -91,51 +3049,39 @@ const docTemplate = `{
                 "tags": [
                     "user"
                 ],
-                "summary": "Validation route",
+                "summary": "Current user update route",
                 "parameters": [
                     {
-                        "description": "User's email address and otp",
+                        "description": "Fields to change",
                         "name": "Body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.OTP"
+                            "$ref": "#/definitions/model.ProfileUpdate"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful response, User already verified. Please login.",
+                        "description": "user",
                         "schema": {
                             "$ref": "#/definitions/responses.UserResponse_doc"
                         }
                     },
                     "400": {
-                        "description": "Invalid JSON data, Invalid Email",
+                        "description": "Invalid JSON data, Invalid profile, Invalid locale, Invalid timezone",
                         "schema": {
                             "$ref": "#/definitions/responses.ErrorResponse_doc"
                         }
                     },
                     "401": {
-                        "description": "Invalid OTP",
-                        "schema": {
-                            "$ref": "#/definitions/responses.ErrorResponse_doc"
-                        }
-                    },
-                    "404": {
-                        "description": "User does not exist. Please register to generate OTP.",
-                        "schema": {
-                            "$ref": "#/definitions/responses.ErrorResponse_doc"
-                        }
-                    },
-                    "422": {
-                        "description": "Please provide with sufficient credentials",
+                        "description": "Invalid or expired token",
                         "schema": {
                             "$ref": "#/definitions/responses.ErrorResponse_doc"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/responses.ErrorResponse_doc"
                         }
@@ -143,63 +3089,37 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Allows users to create a new account.",
-                "consumes": [
-                    "application/json"
+        "/me/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Downloads everything stored about the signed-in user as one JSON document: profile, second factors, passkeys, pending email change, sessions (sign-ins and revocations) and audit events. Secrets such as password and code hashes are left out.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "user"
                 ],
-                "summary": "Register route",
-                "parameters": [
-                    {
-                        "description": "User name, email, password",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.Register"
-                        }
-                    }
-                ],
+                "summary": "Personal data export route",
                 "responses": {
-                    "201": {
-                        "description": "Successful response",
-                        "schema": {
-                            "$ref": "#/definitions/responses.UserResponse_doc"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid JSON data, Invalid Email",
+                    "200": {
+                        "description": "JSON archive",
                         "schema": {
-                            "$ref": "#/definitions/responses.ErrorResponse_doc"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "401": {
-                        "description": "Invalid Credentials",
-                        "schema": {
-                            "$ref": "#/definitions/responses.ErrorResponse_doc"
-                        }
-                    },
-                    "409": {
-                        "description": "User already exists",
-                        "schema": {
-                            "$ref": "#/definitions/responses.ErrorResponse_doc"
-                        }
-                    },
-                    "422": {
-                        "description": "Please provide with sufficient credentials",
+                        "description": "Invalid or expired token, Reauthentication required",
                         "schema": {
                             "$ref": "#/definitions/responses.ErrorResponse_doc"
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error, Error in inserting the document",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/responses.ErrorResponse_doc"
                         }
@@ -209,21 +3129,151 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "model.EmailChange": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.EmailChangeConfirm": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "revoke_sessions": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.InvitationAccept": {
+            "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
+            "properties": {
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "model.InvitationCreate": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "locale": {
+                    "type": "string",
+                    "maxLength": 35
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
         "model.Login": {
             "type": "object",
             "required": [
-                "email",
                 "password"
             ],
             "properties": {
                 "email": {
                     "type": "string"
                 },
+                "identifier": {
+                    "type": "string"
+                },
                 "password": {
                     "type": "string"
                 }
             }
         },
+        "model.MFAVerify": {
+            "type": "object",
+            "required": [
+                "challenge_token"
+            ],
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MagicLink": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MagicLinkConfirm": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MessagingPreferences": {
+            "type": "object",
+            "required": [
+                "otp_channel"
+            ],
+            "properties": {
+                "otp_channel": {
+                    "type": "string",
+                    "enum": [
+                        "email",
+                        "sms"
+                    ]
+                },
+                "sms_mfa": {
+                    "type": "boolean"
+                }
+            }
+        },
         "model.OTP": {
             "type": "object",
             "required": [
@@ -239,6 +3289,82 @@ const docTemplate = `{
                 }
             }
         },
+        "model.Phone": {
+            "type": "object",
+            "required": [
+                "phone"
+            ],
+            "properties": {
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PhoneVerify": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ProfileUpdate": {
+            "type": "object",
+            "properties": {
+                "attributes": {
+                    "description": "Attributes changes the editable custom attributes named in it; null removes one.",
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "display_name": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "locale": {
+                    "type": "string",
+                    "maxLength": 35,
+                    "minLength": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1
+                },
+                "timezone": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "minLength": 1
+                }
+            }
+        },
+        "model.Reauthenticate": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "credential": {
+                    "type": "object"
+                },
+                "method": {
+                    "type": "string",
+                    "enum": [
+                        "totp",
+                        "sms",
+                        "webauthn"
+                    ]
+                },
+                "password": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                }
+            }
+        },
         "model.Register": {
             "type": "object",
             "required": [
@@ -247,20 +3373,94 @@ const docTemplate = `{
                 "password"
             ],
             "properties": {
+                "attributes": {
+                    "description": "Attributes holds the custom fields defined by USER_ATTRIBUTES_SCHEMA.",
+                    "type": "object",
+                    "additionalProperties": true
+                },
                 "email": {
                     "type": "string"
                 },
+                "locale": {
+                    "type": "string",
+                    "maxLength": 35
+                },
                 "name": {
                     "type": "string"
                 },
+                "otp_channel": {
+                    "type": "string",
+                    "enum": [
+                        "email",
+                        "sms"
+                    ]
+                },
                 "password": {
                     "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "model.SMSMFASend": {
+            "type": "object",
+            "required": [
+                "challenge_token"
+            ],
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.SMSMFAVerify": {
+            "type": "object",
+            "required": [
+                "challenge_token",
+                "code"
+            ],
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.TOTPConfirm": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebAuthnMFABegin": {
+            "type": "object",
+            "required": [
+                "challenge_token"
+            ],
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
                 }
             }
         },
         "responses.ErrorResponse_doc": {
             "type": "object",
             "properties": {
+                "code": {
+                    "type": "string"
+                },
                 "message": {
                     "type": "string"
                 }
@@ -278,6 +3478,14 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "\"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,81 @@
+// Package i18n translates the error codes returned by the API into messages in the client's language.
+package i18n
+
+import (
+	"Gin/Basics/mailer"
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.json
+var files embed.FS
+
+var (
+	//* locale -> code -> message, loaded from locales/<locale>.json
+	catalogs  = map[string]map[string]string{}
+	matcher   language.Matcher
+	supported []string
+)
+
+func init() {
+	entries, err := files.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range entries {
+		src, err := files.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(src, &catalog); err != nil {
+			panic("i18n: " + entry.Name() + ": " + err.Error())
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+
+	//* The default locale goes first: the matcher falls back to the first tag
+	supported = append(supported, mailer.DefaultLocale)
+	for locale := range catalogs {
+		if locale != mailer.DefaultLocale {
+			supported = append(supported, locale)
+		}
+	}
+	sort.Strings(supported[1:])
+
+	tags := make([]language.Tag, len(supported))
+	for i, locale := range supported {
+		tags[i] = language.Make(locale)
+	}
+	matcher = language.NewMatcher(tags)
+}
+
+// Message returns the message for code in locale, falling back through its parents (e.g. "es-MX"
+// to "es") to the default locale, and to code itself if no catalog has it.
+func Message(locale string, code string) string {
+	for _, candidate := range mailer.LocaleChain(locale) {
+		if message, ok := catalogs[candidate][code]; ok {
+			return message
+		}
+	}
+
+	return code
+}
+
+// MatchAcceptLanguage returns the supported locale that best fits an Accept-Language header.
+func MatchAcceptLanguage(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return mailer.DefaultLocale
+	}
+
+	_, index, _ := matcher.Match(tags...)
+
+	return supported[index]
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const defaultOutboxBodyRetention = 24 * time.Hour
+
+// OutboxPurger blanks the bodies of dead-lettered outbox messages once they are older than
+// Retention. Bodies can hold one-time codes and sign-in links, and are kept only so that an admin
+// can retry delivery.
+type OutboxPurger struct {
+	Queries   *db.Queries
+	Interval  time.Duration
+	Retention time.Duration
+	// Alert, when set, is told about failed purges.
+	Alert func(error)
+}
+
+// Start purges once right away and then every Interval until ctx is cancelled.
+func (p *OutboxPurger) Start(ctx context.Context) {
+	go func() {
+		interval := p.Interval
+		if interval <= 0 {
+			interval = defaultPurgeInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := p.PurgeOnce(ctx); err != nil {
+				log.Println("jobs:", err)
+				if p.Alert != nil {
+					p.Alert(fmt.Errorf("jobs: purging outbox message bodies: %w", err))
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PurgeOnce blanks the bodies that are due and returns how many messages there were.
+func (p *OutboxPurger) PurgeOnce(ctx context.Context) (int64, error) {
+	retention := p.Retention
+	if retention <= 0 {
+		retention = defaultOutboxBodyRetention
+	}
+
+	purged, err := p.Queries.PurgeOutboxBodies(ctx, pgtype.Timestamptz{Time: time.Now().Add(-retention), Valid: true})
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		log.Printf("jobs: purged the bodies of %d dead-lettered outbox messages\n", purged)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,69 @@
+// Package jobs holds periodic maintenance that runs in the background of the API server.
+package jobs
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const defaultPurgeInterval = time.Hour
+
+// AccountPurger hard-deletes accounts whose deletion grace period has ended. Everything that
+// references an account, such as its passkeys, goes with it, except audit events, which are
+// scrubbed and kept.
+type AccountPurger struct {
+	Queries  *db.Queries
+	Interval time.Duration
+	// Alert, when set, is told about failed purges.
+	Alert func(error)
+}
+
+// Start purges once right away and then every Interval until ctx is cancelled.
+func (p *AccountPurger) Start(ctx context.Context) {
+	go func() {
+		interval := p.Interval
+		if interval <= 0 {
+			interval = defaultPurgeInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := p.PurgeOnce(ctx); err != nil {
+				log.Println("jobs:", err)
+				if p.Alert != nil {
+					p.Alert(fmt.Errorf("jobs: purging deleted accounts: %w", err))
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// PurgeOnce deletes the accounts that are due and returns how many there were.
+func (p *AccountPurger) PurgeOnce(ctx context.Context) (int, error) {
+	due, err := p.Queries.ListUsersDueForPurge(ctx)
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+	if err := p.Queries.ScrubAuditEvents(ctx, due); err != nil {
+		return 0, err
+	}
+	ids, err := p.Queries.PurgeDeletedUsers(ctx, due)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		log.Printf("jobs: purged %d deleted accounts\n", len(ids))
+	}
+
+	return len(ids), nil
+}
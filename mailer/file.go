@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileMailer is a development transport. With Dir set, each message is written there as an .eml file;
+// otherwise messages are printed to Out (os.Stdout when nil).
+type FileMailer struct {
+	Dir  string
+	Out  io.Writer
+	From string
+
+	mu sync.Mutex
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	raw := msg.Bytes(m.From)
+
+	if m.Dir != "" {
+		if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+			return err
+		}
+		suffix := make([]byte, 4)
+		rand.Read(suffix)
+		name := time.Now().UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+
+		return os.WriteFile(filepath.Join(m.Dir, name), raw, 0o644)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := m.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	_, err := fmt.Fprintf(out, "----- email -----\n%s\n----- end email -----\n", raw)
+
+	return err
+}
@@ -0,0 +1,97 @@
+// Package mailer sends transactional email through a pluggable transport.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Mailer delivers a message. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Message is an email to one or more recipients. At least one of HTML and Text must be set;
+// with both, it is sent as multipart/alternative.
+type Message struct {
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Bytes renders the message as an RFC 5322 email sent by from.
+func (m Message) Bytes(from string) []byte {
+	var buf bytes.Buffer
+
+	writeHeader(&buf, "From", from)
+	writeHeader(&buf, "To", strings.Join(m.To, ", "))
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID(from))
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	switch {
+	case m.HTML != "" && m.Text != "":
+		//* Both bodies: let the client pick, plain text first as RFC 2046 asks
+		parts := multipart.NewWriter(&buf)
+		writeHeader(&buf, "Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+		buf.WriteString("\r\n")
+		writePart(parts, "text/plain", m.Text)
+		writePart(parts, "text/html", m.HTML)
+		parts.Close()
+	case m.HTML != "":
+		writeHeader(&buf, "Content-Type", `text/html; charset="utf-8"`)
+		writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		writeQuotedPrintable(&buf, m.HTML)
+	default:
+		writeHeader(&buf, "Content-Type", `text/plain; charset="utf-8"`)
+		writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		writeQuotedPrintable(&buf, m.Text)
+	}
+
+	return buf.Bytes()
+}
+
+func writePart(parts *multipart.Writer, contentType string, body string) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+`; charset="utf-8"`)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	part, _ := parts.CreatePart(header)
+	writeQuotedPrintable(part, body)
+}
+
+func writeQuotedPrintable(w io.Writer, body string) {
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(body))
+	qp.Close()
+}
+
+func writeHeader(buf *bytes.Buffer, name string, value string) {
+	//* Header values must not be able to start new headers
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	buf.WriteString(name + ": " + value + "\r\n")
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">"
+}
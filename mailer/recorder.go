@@ -0,0 +1,34 @@
+package mailer
+
+import "sync"
+
+// Recorder keeps sent messages in memory instead of delivering them, for tests.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (r *Recorder) Send(msg Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages = append(r.messages, msg)
+
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far.
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Message(nil), r.messages...)
+}
+
+// Reset forgets all recorded messages.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages = nil
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSMTPMailerTimesOutOnStalledRelay(t *testing.T) {
+	//* A relay that accepts the connection and never sends its greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	m := &SMTPMailer{Host: "127.0.0.1", Port: addr.Port, TLS: TLSNone, From: "noreply@example.com", Timeout: 200 * time.Millisecond}
+
+	start := time.Now()
+	err = m.Send(Message{To: []string{"ada@example.com"}, Subject: "Hi", Text: "Hello"})
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Send error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Send took %v, want it bounded by Timeout", elapsed)
+	}
+}
@@ -0,0 +1,155 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// DefaultLocale is the last entry of every locale fallback chain.
+const DefaultLocale = "en"
+
+//go:embed templates
+var embedded embed.FS
+
+// Templates renders localized transactional emails. Every email name has three files per locale:
+//
+//	<locale>/<name>.subject.txt  subject line (text/template)
+//	<locale>/<name>.txt          plain-text body (text/template)
+//	<locale>/<name>.html         HTML body (html/template, so interpolated values are escaped)
+//
+// Files in Dir, when set, take precedence over the built-in ones, so operators can override any
+// template without recompiling. Parsed templates are cached; restart to pick up changes.
+type Templates struct {
+	Dir string
+
+	mu    sync.Mutex
+	cache map[string]interface{}
+}
+
+// NewTemplates returns templates that are overridden by files in dir, if dir is not empty.
+func NewTemplates(dir string) *Templates {
+	return &Templates{Dir: dir}
+}
+
+// Render builds the message name for locale, falling back through its parents (e.g. "pt-BR" to "pt")
+// to DefaultLocale for any file that is missing. The recipients are left to the caller.
+func (t *Templates) Render(name string, locale string, data interface{}) (Message, error) {
+	var msg Message
+	var err error
+
+	if msg.Subject, err = t.renderText(name+".subject.txt", locale, data); err != nil {
+		return msg, err
+	}
+	msg.Subject = strings.TrimSpace(msg.Subject)
+	if msg.Text, err = t.renderText(name+".txt", locale, data); err != nil {
+		return msg, err
+	}
+	if msg.HTML, err = t.renderHTML(name+".html", locale, data); err != nil {
+		return msg, err
+	}
+
+	return msg, nil
+}
+
+// LocaleChain returns the locales tried for locale, most specific first, ending with DefaultLocale.
+func LocaleChain(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+
+	var chain []string
+	for locale != "" {
+		chain = append(chain, locale)
+		cut := strings.LastIndex(locale, "-")
+		if cut < 0 {
+			break
+		}
+		locale = locale[:cut]
+	}
+
+	if len(chain) == 0 || chain[len(chain)-1] != DefaultLocale {
+		chain = append(chain, DefaultLocale)
+	}
+
+	return chain
+}
+
+func (t *Templates) renderText(file string, locale string, data interface{}) (string, error) {
+	tmpl, err := t.load(file, locale, func(name string, src []byte) (interface{}, error) {
+		return texttemplate.New(name).Option("missingkey=error").Parse(string(src))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.(*texttemplate.Template).Execute(&buf, data)
+
+	return buf.String(), err
+}
+
+func (t *Templates) renderHTML(file string, locale string, data interface{}) (string, error) {
+	tmpl, err := t.load(file, locale, func(name string, src []byte) (interface{}, error) {
+		return htmltemplate.New(name).Option("missingkey=error").Parse(string(src))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.(*htmltemplate.Template).Execute(&buf, data)
+
+	return buf.String(), err
+}
+
+// load returns the parsed template for the first locale in the chain that has file.
+func (t *Templates) load(file string, locale string, parse func(name string, src []byte) (interface{}, error)) (interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cache == nil {
+		t.cache = map[string]interface{}{}
+	}
+	key := locale + "/" + file
+	if tmpl, ok := t.cache[key]; ok {
+		return tmpl, nil
+	}
+
+	var lastErr error
+	for _, candidate := range LocaleChain(locale) {
+		src, err := t.read(path.Join(candidate, file))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		tmpl, err := parse(path.Join(candidate, file), src)
+		if err != nil {
+			return nil, err
+		}
+		t.cache[key] = tmpl
+
+		return tmpl, nil
+	}
+
+	return nil, lastErr
+}
+
+func (t *Templates) read(name string) ([]byte, error) {
+	if t.Dir != "" {
+		src, err := os.ReadFile(path.Join(t.Dir, name))
+		if err == nil {
+			return src, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return fs.ReadFile(embedded, path.Join("templates", name))
+}
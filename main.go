@@ -3,8 +3,17 @@ package main
 import (
 	"Gin/Basics/configs"
 	controller "Gin/Basics/controllers"
+	db "Gin/Basics/db/sqlconfig"
 	docs "Gin/Basics/docs"
+	"Gin/Basics/jobs"
+	model "Gin/Basics/models"
+	"Gin/Basics/notify"
+	"Gin/Basics/outbox"
 	"Gin/Basics/routes"
+	"context"
+	"log"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
@@ -17,6 +26,11 @@ import (
 
 //	@BasePath	/api/
 
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+//	@description				"Bearer <token>"
+
 func main() {
 
 	prod := configs.RELEASE_MODE()
@@ -29,9 +43,58 @@ func main() {
 	api := router.Group("/api/v1")
 	//* Passing the router to all user(auth) routes.
 	routes.UserRoute(api)
+	routes.AdminRoute(api)
+
+	//* Loading the OTP pepper and length, custom attribute schema, identity providers and SAML connections now, so that broken ones stop the server at startup
+	configs.OTPPepper()
+	configs.OTPLength()
+	configs.AttributeSchema()
+	configs.OIDCProviders()
+	configs.SAMLConnections()
+	if !notify.SMSEnabled() {
+		log.Println("SMS_PROVIDER_URL is not set; phone numbers and SMS codes are turned off")
+	}
+
+	//* Sending digests of server errors to the admins
+	configs.Alerts().Start(context.Background())
 
 	//* Connecting to DB
-	configs.ConnectDB()
+	if conn := configs.ConnectDB(); conn != nil {
+		//* Hashing OTPs that are still stored in plaintext
+		if migrateErr := model.MigrateLegacyOTPs(context.Background(), db.New(conn)); migrateErr != nil {
+			log.Println(migrateErr)
+		}
+
+		//* Rewriting stored addresses to the form EMAIL_PROVIDER_RULES looks them up by
+		if migrateErr := model.MigrateProviderEmails(context.Background(), db.New(conn)); migrateErr != nil {
+			log.Println(migrateErr)
+			configs.Alerts().Report(migrateErr)
+		}
+
+		//* Delivering queued emails and text messages in the background
+		workers, workersErr := strconv.Atoi(configs.OUTBOX_WORKERS())
+		if workersErr != nil || workers < 1 {
+			workers = 4
+		}
+		maxAttempts, _ := strconv.Atoi(configs.OUTBOX_MAX_ATTEMPTS())
+		worker := &outbox.Worker{
+			Queries:     db.New(conn),
+			Mailer:      configs.Mailer(),
+			SMS:         notify.SMS(),
+			MaxAttempts: maxAttempts,
+			Alert:       configs.Alerts().Report,
+		}
+		worker.Start(context.Background(), workers)
+
+		//* Hard-deleting accounts whose deletion grace period has ended
+		purger := &jobs.AccountPurger{Queries: db.New(conn), Alert: configs.Alerts().Report}
+		purger.Start(context.Background())
+
+		//* Dropping the codes and links held by dead-lettered messages once they can no longer be retried
+		retention, _ := time.ParseDuration(configs.OUTBOX_BODY_RETENTION())
+		outboxPurger := &jobs.OutboxPurger{Queries: db.New(conn), Retention: retention, Alert: configs.Alerts().Report}
+		outboxPurger.Start(context.Background())
+	}
 
 	router.GET("/", controller.BaseRoute)
 	router.GET("/api/v1/docs/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
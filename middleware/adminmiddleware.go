@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleAdmin is the users.role value of administrators.
+const RoleAdmin = "admin"
+
+// RequireAdmin rejects requests from users who are not administrators. It must run after Authenticate.
+func RequireAdmin() gin.HandlerFunc {
+	return func(r *gin.Context) {
+		if CurrentUser(r).Role != RoleAdmin {
+			abortWithError(r, http.StatusForbidden, "admin_required")
+			return
+		}
+
+		r.Next()
+	}
+}
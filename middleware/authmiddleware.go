@@ -1,2 +1,91 @@
 package middleware
 
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/i18n"
+	"Gin/Basics/responses"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate requires a valid access token in the Authorization header ("Bearer <token>").
+// The token's user is loaded and stored in the context under "user", and its claims under "claims".
+func Authenticate() gin.HandlerFunc {
+	return func(r *gin.Context) {
+		header := r.GetHeader("Authorization")
+		tokenStr, found := strings.CutPrefix(header, "Bearer ")
+		if !found || tokenStr == "" {
+			abortWithError(r, http.StatusUnauthorized, "missing_authorization")
+			return
+		}
+
+		claims, err := auth.ValidateJWT(tokenStr)
+		if err != nil || claims["typ"] != auth.TokenTypeAccess {
+			abortWithError(r, http.StatusUnauthorized, "invalid_token")
+			return
+		}
+
+		userID, err := auth.UserIDFromClaims(claims)
+		if err != nil {
+			abortWithError(r, http.StatusUnauthorized, "invalid_token")
+			return
+		}
+
+		user, err := db.New(configs.CONN).GetUserByID(r.Request.Context(), userID)
+		if err != nil {
+			abortWithError(r, http.StatusUnauthorized, "invalid_token")
+			return
+		}
+
+		if statusCode, code, blocked := AccountStatusError(user.Status); blocked {
+			abortWithError(r, statusCode, code)
+			return
+		}
+
+		//* Rejecting tokens issued before the user signed out everywhere; iat has whole seconds only
+		if user.SessionsRevokedAt.Valid && auth.IssuedAtFromClaims(claims).Before(user.SessionsRevokedAt.Time.Truncate(time.Second)) {
+			abortWithError(r, http.StatusUnauthorized, "invalid_token")
+			return
+		}
+
+		r.Set("user", user)
+		r.Set("claims", claims)
+		r.Next()
+	}
+}
+
+// AccountStatusError returns the HTTP status and error code for an account whose status keeps it
+// from using the API, and false for active accounts.
+func AccountStatusError(status db.AccountStatus) (int, string, bool) {
+	switch status {
+	case db.AccountStatusPending:
+		return http.StatusForbidden, "account_pending", true
+	case db.AccountStatusDisabled:
+		return http.StatusForbidden, "account_disabled", true
+	case db.AccountStatusLocked:
+		return http.StatusLocked, "account_locked", true
+	case db.AccountStatusDeleted:
+		//* Accounts pending deletion are signed out until they sign in again
+		return http.StatusUnauthorized, "account_deleted", true
+	}
+
+	return 0, "", false
+}
+
+// CurrentUser returns the user stored by Authenticate.
+func CurrentUser(r *gin.Context) db.User {
+	return r.MustGet("user").(db.User)
+}
+
+// abortWithError stops the request with the message for code in the client's language.
+func abortWithError(r *gin.Context, statusCode int, code string) {
+	r.AbortWithStatusJSON(statusCode, responses.UserResponse{
+		Message: i18n.Message(Locale(r), code),
+		Code:    code,
+	})
+}
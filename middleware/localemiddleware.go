@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale returns the language to answer a request in: the signed-in user's preference when
+// Authenticate has run, otherwise the best match for the Accept-Language header.
+func Locale(r *gin.Context) string {
+	if user, ok := r.Get("user"); ok {
+		if locale := user.(db.User).Locale; locale != "" {
+			return locale
+		}
+	}
+
+	return i18n.MatchAcceptLanguage(r.GetHeader("Accept-Language"))
+}
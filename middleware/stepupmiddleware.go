@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"Gin/Basics/auth"
+	"Gin/Basics/i18n"
+	"Gin/Basics/responses"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+// ReauthenticationRequired is the machine-readable error code returned by RequireRecentAuth.
+const ReauthenticationRequired = "reauthentication_required"
+
+// RequireRecentAuth rejects requests whose access token was issued more than maxAge after the user
+// last actively authenticated. Clients should send the user through /auth/reauthenticate and retry
+// with the new token. It must run after Authenticate.
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(r *gin.Context) {
+		claims := r.MustGet("claims").(jwt.MapClaims)
+
+		authTime, ok := auth.AuthTimeFromClaims(claims)
+		if !ok || time.Since(authTime) > maxAge {
+			seconds := int(maxAge.Seconds())
+			//* Step-up challenge as described in RFC 9470
+			r.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_user_authentication", error_description="A more recent authentication is required", max_age=%d`, seconds))
+			r.AbortWithStatusJSON(http.StatusUnauthorized, responses.UserResponse{
+				Message: i18n.Message(Locale(r), ReauthenticationRequired),
+				Code:    ReauthenticationRequired,
+				Data:    map[string]interface{}{"error": ReauthenticationRequired, "max_age": seconds},
+			})
+			return
+		}
+
+		r.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"time"
+)
+
+const DefaultAccountDeletionGrace = 30 * 24 * time.Hour
+
+// QueueAccountDeletionScheduled confirms a deletion request to the user, giving the moment of
+// deletion in their timezone.
+func QueueAccountDeletionScheduled(ctx context.Context, queries *db.Queries, user db.User, deleteAt time.Time) error {
+	if zone, err := time.LoadLocation(user.Timezone); err == nil {
+		deleteAt = deleteAt.In(zone)
+	}
+
+	return queueTemplate(ctx, queries, user.Email, user.Locale, "account_deletion_scheduled", map[string]interface{}{"Date": deleteAt.Format("2006-01-02 15:04 MST")})
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Orders accepted by GET /admin/users. Every order breaks ties on the user id.
+const (
+	UserSortID        = "id"
+	UserSortCreatedAt = "created_at"
+	UserSortEmail     = "email"
+
+	DefaultUserPageSize = 50
+	MaxUserPageSize     = 200
+)
+
+// UserCursor marks the last user of a page of GET /admin/users. It carries the order it was made
+// for, so a cursor cannot be replayed against a different sort.
+type UserCursor struct {
+	Sort       string    `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	ID         int64     `json:"i"`
+	CreatedAt  time.Time `json:"c"`
+	Email      string    `json:"e,omitempty"`
+}
+
+// Encode returns the cursor as an opaque URL-safe string.
+func (cursor UserCursor) Encode() string {
+	encoded, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeUserCursor parses a cursor made by UserCursor.Encode.
+func DecodeUserCursor(value string) (UserCursor, error) {
+	var cursor UserCursor
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return cursor, err
+	}
+	if cursor.ID <= 0 {
+		return cursor, errors.New("cursor without id")
+	}
+
+	return cursor, nil
+}
+
+// EscapeLike escapes the LIKE wildcards in s so that it matches literally.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"encoding/json"
+)
+
+// DecodeAttributes returns the custom attributes stored in users.attributes.
+func DecodeAttributes(raw []byte) map[string]interface{} {
+	attrs := map[string]interface{}{}
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &attrs)
+	}
+
+	return attrs
+}
+
+// AttributeClaims returns the attributes of user that the schema copies into access tokens.
+func AttributeClaims(user db.User) map[string]interface{} {
+	return configs.AttributeSchema().Claims(DecodeAttributes(user.Attributes))
+}
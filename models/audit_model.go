@@ -0,0 +1,35 @@
+package model
+
+// Actions recorded in audit_events.
+const (
+	AuditRegistered               = "user.registered"
+	AuditLogin                    = "login"
+	AuditProfileUpdated           = "profile.updated"
+	AuditEmailChangeRequested     = "email.change_requested"
+	AuditEmailChanged             = "email.changed"
+	AuditSessionsRevoked          = "sessions.revoked"
+	AuditMFAEnabled               = "mfa.enabled"
+	AuditRecoveryCodesGenerated   = "mfa.recovery_codes_generated"
+	AuditPasskeyRegistered        = "passkey.registered"
+	AuditPasskeyRemoved           = "passkey.removed"
+	AuditIdentityLinked           = "identity.linked"
+	AuditIdentityUnlinked         = "identity.unlinked"
+	AuditPhoneChanged             = "phone.changed"
+	AuditPhoneVerified            = "phone.verified"
+	AuditAccountDeletionRequested = "account.deletion_requested"
+	AuditAccountDeletionCancelled = "account.deletion_cancelled"
+	AuditAccountExported          = "account.exported"
+
+	AuditAdminUsersListed     = "admin.users_listed"
+	AuditAdminUserViewed      = "admin.user_viewed"
+	AuditAdminUserVerified    = "admin.user_verified"
+	AuditAdminUserDisabled    = "admin.user_disabled"
+	AuditAdminUserEnabled     = "admin.user_enabled"
+	AuditAdminUserLocked      = "admin.user_locked"
+	AuditAdminSessionsRevoked = "admin.sessions_revoked"
+	AuditAdminUserDeleted     = "admin.user_deleted"
+
+	AuditAdminInvitationCreated = "admin.invitation_created"
+	AuditAdminInvitationResent  = "admin.invitation_resent"
+	AuditAdminInvitationRevoked = "admin.invitation_revoked"
+)
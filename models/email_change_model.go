@@ -0,0 +1,51 @@
+package model
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	EmailChangeLifetime = 30 * time.Minute
+	//* A pending change is dropped after this many wrong codes
+	EmailChangeMaxAttempts = 5
+)
+
+type EmailChange struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type EmailChangeConfirm struct {
+	Code           string `json:"code" validate:"required"`
+	RevokeSessions bool   `json:"revoke_sessions"`
+}
+
+// QueueEmailChangeCode queues the confirmation code for a change of address to newEmail.
+func QueueEmailChangeCode(ctx context.Context, queries *db.Queries, newEmail string, locale string, code string) error {
+	return queueTemplate(ctx, queries, newEmail, locale, "email_change_code", map[string]interface{}{"Code": code})
+}
+
+// QueueEmailChangeRequested warns the current address that a change to newEmail was requested.
+func QueueEmailChangeRequested(ctx context.Context, queries *db.Queries, email string, locale string, newEmail string) error {
+	return queueTemplate(ctx, queries, email, locale, "email_change_requested", map[string]interface{}{"NewEmail": MaskEmail(newEmail)})
+}
+
+// QueueEmailChanged tells the previous address that the account now uses newEmail.
+func QueueEmailChanged(ctx context.Context, queries *db.Queries, email string, locale string, newEmail string) error {
+	return queueTemplate(ctx, queries, email, locale, "email_changed", map[string]interface{}{"NewEmail": MaskEmail(newEmail)})
+}
+
+// MaskEmail hides most of the local part of an address, e.g. "jane.doe@example.com" becomes
+// "j*******@example.com", so that notices to an old address do not leak the new one in full.
+func MaskEmail(email string) string {
+	local, domain, found := strings.Cut(email, "@")
+	if !found || local == "" {
+		return email
+	}
+
+	runes := []rune(local)
+
+	return string(runes[0]) + strings.Repeat("*", len(runes)-1) + "@" + domain
+}
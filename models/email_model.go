@@ -0,0 +1,148 @@
+package model
+
+import (
+	"Gin/Basics/configs"
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidEmail = errors.New("invalid email address")
+
+// emailProvider describes how a mail provider treats the local part of its addresses.
+type emailProvider struct {
+	domain     string
+	ignoreDots bool
+	plusTags   bool
+}
+
+// emailProviders maps each domain a provider answers to onto its rules, under the domain addresses
+// are stored with.
+var emailProviders = map[string]emailProvider{
+	"gmail.com":      {domain: "gmail.com", ignoreDots: true, plusTags: true},
+	"googlemail.com": {domain: "gmail.com", ignoreDots: true, plusTags: true},
+	"outlook.com":    {domain: "outlook.com", plusTags: true},
+	"hotmail.com":    {domain: "hotmail.com", plusTags: true},
+	"icloud.com":     {domain: "icloud.com", plusTags: true},
+	"fastmail.com":   {domain: "fastmail.com", plusTags: true},
+	"proton.me":      {domain: "proton.me", ignoreDots: true, plusTags: true},
+	"protonmail.com": {domain: "protonmail.com", ignoreDots: true, plusTags: true},
+}
+
+// NormalizeEmail returns email in the form accounts are stored and looked up by: trimmed, with the
+// domain lowercased and in its ASCII (punycode) form, so "Bob@Bücher.DE" becomes
+// "Bob@xn--bcher-kva.de". Lookups ignore case on top of that. With EMAIL_PROVIDER_RULES enabled,
+// addresses at the providers above also lose case, dots and +tags where the provider ignores them.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", ErrInvalidEmail
+	}
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+
+	domain, err := idna.Lookup.ToASCII(domain)
+	if err != nil || domain == "" {
+		return "", ErrInvalidEmail
+	}
+	domain = strings.ToLower(domain)
+
+	if configs.EMAIL_PROVIDER_RULES() == "true" {
+		local, domain = applyProviderRules(local, domain)
+		if local == "" {
+			return "", ErrInvalidEmail
+		}
+	}
+
+	return local + "@" + domain, nil
+}
+
+func applyProviderRules(local string, domain string) (string, string) {
+	provider, ok := emailProviders[domain]
+	if !ok {
+		return local, domain
+	}
+
+	local = strings.ToLower(local)
+	if provider.plusTags {
+		local, _, _ = strings.Cut(local, "+")
+	}
+	if provider.ignoreDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+
+	return local, provider.domain
+}
+
+// MigrateProviderEmails rewrites the stored addresses at the providers above to the form
+// NormalizeEmail gives them once EMAIL_PROVIDER_RULES is enabled, so that existing accounts are still
+// found. Accounts whose addresses would then be the same, such as "j.doe@gmail.com" and
+// "jdoe@gmail.com", are logged and left alone, and an error reports how many addresses collide so
+// that an admin can decide which account to keep (e.g. with DELETE /admin/users/{id}).
+func MigrateProviderEmails(ctx context.Context, queries *db.Queries) error {
+	if configs.EMAIL_PROVIDER_RULES() != "true" {
+		return nil
+	}
+
+	domains := make([]string, 0, len(emailProviders))
+	for domain := range emailProviders {
+		domains = append(domains, domain)
+	}
+	rows, err := queries.ListUsersAtEmailDomains(ctx, domains)
+	if err != nil {
+		return err
+	}
+
+	//* Grouping the accounts by the address they normalize to
+	groups := map[string][]db.ListUsersAtEmailDomainsRow{}
+	var order []string
+	for _, row := range rows {
+		normalized, err := NormalizeEmail(row.Email)
+		if err != nil {
+			continue
+		}
+		key := strings.ToLower(normalized)
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	rewritten, collisions := 0, 0
+	for _, key := range order {
+		group := groups[key]
+		if len(group) > 1 {
+			ids := make([]int64, len(group))
+			spellings := make([]string, len(group))
+			for i, row := range group {
+				ids[i], spellings[i] = row.ID, row.Email
+			}
+			log.Printf("WARNING: email collision: %s is used by users %v as %v\n", key, ids, spellings)
+			collisions++
+			continue
+		}
+
+		normalized, _ := NormalizeEmail(group[0].Email)
+		if normalized == group[0].Email {
+			continue
+		}
+		if err := queries.RewriteUserEmail(ctx, db.RewriteUserEmailParams{ID: group[0].ID, Email: normalized}); err != nil {
+			return err
+		}
+		rewritten++
+	}
+
+	if rewritten > 0 {
+		log.Printf("Normalized %d email address(es) under EMAIL_PROVIDER_RULES\n", rewritten)
+	}
+	if collisions > 0 {
+		return fmt.Errorf("%d email address(es) belong to more than one account under EMAIL_PROVIDER_RULES: keep one account per address listed above, then restart", collisions)
+	}
+
+	return nil
+}
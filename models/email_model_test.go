@@ -0,0 +1,89 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		email         string
+		providerRules bool
+		want          string
+	}{
+		{name: "unchanged", email: "ada@example.com", want: "ada@example.com"},
+		{name: "trimmed", email: "  ada@example.com\t", want: "ada@example.com"},
+		{name: "domain lowercased, local part kept", email: "Ada.Lovelace@Example.COM", want: "Ada.Lovelace@example.com"},
+		{name: "internationalized domain", email: "Bob@Bücher.DE", want: "Bob@xn--bcher-kva.de"},
+		{name: "trailing dot", email: "ada@example.com.", want: "ada@example.com"},
+		{name: "last @ splits", email: `"a@b"@example.com`, want: `"a@b"@example.com`},
+		{name: "Gmail without provider rules", email: "Ada.Lovelace+news@gmail.com", want: "Ada.Lovelace+news@gmail.com"},
+		{name: "Gmail dots and tags", email: "Ada.Lovelace+news@gmail.com", providerRules: true, want: "adalovelace@gmail.com"},
+		{name: "Googlemail folds into Gmail", email: "ada.lovelace@GoogleMail.com", providerRules: true, want: "adalovelace@gmail.com"},
+		{name: "Outlook keeps dots", email: "Ada.Lovelace+news@outlook.com", providerRules: true, want: "ada.lovelace@outlook.com"},
+		{name: "other domains untouched", email: "Ada.Lovelace+news@example.com", providerRules: true, want: "Ada.Lovelace+news@example.com"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.providerRules {
+				t.Setenv("EMAIL_PROVIDER_RULES", "true")
+			} else {
+				t.Setenv("EMAIL_PROVIDER_RULES", "")
+			}
+
+			got, err := NormalizeEmail(tc.email)
+			if err != nil {
+				t.Fatalf("NormalizeEmail(%q): %v", tc.email, err)
+			}
+			if got != tc.want {
+				t.Fatalf("NormalizeEmail(%q) = %q, want %q", tc.email, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmailInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		email         string
+		providerRules bool
+	}{
+		{email: ""},
+		{email: "   "},
+		{email: "ada"},
+		{email: "@example.com"},
+		{email: "ada@"},
+		{email: "ada@."},
+		{email: "ada@exa mple.com"},
+		{email: "ada@-example.com"},
+		{email: "+news@gmail.com", providerRules: true},
+		{email: "...@gmail.com", providerRules: true},
+	} {
+		if tc.providerRules {
+			t.Setenv("EMAIL_PROVIDER_RULES", "true")
+		} else {
+			t.Setenv("EMAIL_PROVIDER_RULES", "")
+		}
+
+		if got, err := NormalizeEmail(tc.email); !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("NormalizeEmail(%q) = %q, %v, want ErrInvalidEmail", tc.email, got, err)
+		}
+	}
+}
+
+func TestApplyProviderRules(t *testing.T) {
+	for _, tc := range []struct {
+		local, domain         string
+		wantLocal, wantDomain string
+	}{
+		{"J.Doe+tag+more", "gmail.com", "jdoe", "gmail.com"},
+		{"j.doe", "googlemail.com", "jdoe", "gmail.com"},
+		{"J.Doe+tag", "proton.me", "jdoe", "proton.me"},
+		{"J.Doe+tag", "icloud.com", "j.doe", "icloud.com"},
+		{"J.Doe+tag", "example.com", "J.Doe+tag", "example.com"},
+	} {
+		local, domain := applyProviderRules(tc.local, tc.domain)
+		if local != tc.wantLocal || domain != tc.wantDomain {
+			t.Errorf("applyProviderRules(%q, %q) = %q, %q, want %q, %q", tc.local, tc.domain, local, domain, tc.wantLocal, tc.wantDomain)
+		}
+	}
+}
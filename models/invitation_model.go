@@ -0,0 +1,68 @@
+package model
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+const DefaultInvitationLifetime = 7 * 24 * time.Hour
+
+// Invitation states, as reported by InvitationStatus and accepted by GET /admin/invitations.
+const (
+	InvitationPending  = "pending"
+	InvitationExpired  = "expired"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+type InvitationCreate struct {
+	Email  string `json:"email" validate:"required"`
+	Name   string `json:"name" validate:"max=255"`
+	Locale string `json:"locale" validate:"omitempty,max=35"`
+}
+
+// InvitationAccept creates the invited account. Name defaults to the one given in the invitation.
+type InvitationAccept struct {
+	Token      string                 `json:"token" validate:"required"`
+	Name       string                 `json:"name" validate:"max=255"`
+	Password   string                 `json:"password" validate:"required"`
+	Username   string                 `json:"username" validate:"omitempty,max=64"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// GenerateInvitationToken returns a new invitation token and the hash stored in its place.
+func GenerateInvitationToken() (token string, hash string, err error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	return token, HashOTP(token), nil
+}
+
+// InvitationStatus returns which of the invitation states invitation is in.
+func InvitationStatus(invitation db.Invitation) string {
+	switch {
+	case invitation.AcceptedAt.Valid:
+		return InvitationAccepted
+	case invitation.RevokedAt.Valid:
+		return InvitationRevoked
+	case !invitation.ExpiresAt.Time.After(time.Now()):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
+
+// QueueInvitation emails the invitation link on behalf of inviter.
+func QueueInvitation(ctx context.Context, queries *db.Queries, invitation db.Invitation, inviter string, link string) error {
+	return queueTemplate(ctx, queries, invitation.Email, invitation.Locale, "invitation", map[string]interface{}{
+		"Inviter": inviter,
+		"Link":    link,
+		"Date":    invitation.ExpiresAt.Time.UTC().Format("2006-01-02 15:04 MST"),
+	})
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"Gin/Basics/mailer"
+
+	"golang.org/x/text/language"
+)
+
+// PreferredLocale returns the locale to store for a user: the explicitly chosen one when it is a valid
+// BCP 47 tag, otherwise the client's first choice in the Accept-Language header, otherwise the default.
+func PreferredLocale(explicit string, acceptLanguage string) string {
+	if explicit != "" {
+		if tag, err := language.Parse(explicit); err == nil {
+			return tag.String()
+		}
+	}
+
+	if tags, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil && len(tags) > 0 {
+		return tags[0].String()
+	}
+
+	return mailer.DefaultLocale
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+)
+
+type MagicLink struct {
+	Email string `json:"email" validate:"required"`
+}
+
+type MagicLinkConfirm struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func QueueMagicLink(ctx context.Context, queries *db.Queries, email string, locale string, link string) error {
+	return queueTemplate(ctx, queries, email, locale, "magic_link", map[string]interface{}{"Link": link})
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	RecoveryCodeCount = 10
+
+	MFAChallengeLifetime = 5 * time.Minute
+	//* Second factors are locked for MFALockout after this many wrong codes in a row
+	MFAMaxAttempts = 5
+	MFALockout     = 15 * time.Minute
+
+	recoveryCodeLength = 10
+	//* Unambiguous characters only: no 0/O or 1/I/L
+	recoveryCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+)
+
+type MFAVerify struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code" validate:"required_without=Code"`
+}
+
+type TOTPConfirm struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type WebAuthnMFABegin struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type SMSMFASend struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type SMSMFAVerify struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type Reauthenticate struct {
+	Password   string          `json:"password"`
+	Method     string          `json:"method" validate:"omitempty,oneof=totp sms webauthn"`
+	Code       string          `json:"code"`
+	SessionID  string          `json:"session_id"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object"`
+}
+
+// GenerateRecoveryCodes returns RecoveryCodeCount new plaintext recovery codes formatted as XXXXX-XXXXX.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code, err := GenerateCode(recoveryCodeLength, recoveryCodeAlphabet)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code. Dashes, spaces and case are ignored
+// so that codes can be typed the way they were written down.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	return HashOTP(normalized)
+}
+
+func QueueRecoveryCodeUsed(ctx context.Context, queries *db.Queries, email string, locale string, remaining int64) error {
+	return queueTemplate(ctx, queries, email, locale, "recovery_code_used", map[string]interface{}{"Remaining": remaining})
+}
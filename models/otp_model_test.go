@@ -0,0 +1,100 @@
+package model
+
+import (
+	"Gin/Basics/configs"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// usePepper loads a test OTP_PEPPER. The pepper is read once per process, so every test gets the same one.
+func usePepper(t *testing.T) {
+	t.Helper()
+	t.Setenv("OTP_PEPPER", strings.Repeat("p", 32))
+	configs.OTPPepper()
+}
+
+func TestGenerateCode(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		length   int
+		alphabet string
+	}{
+		{name: "digits", length: 6, alphabet: "0123456789"},
+		{name: "long", length: 12, alphabet: "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"},
+		{name: "multi-byte", length: 8, alphabet: "αβγδ"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				code, err := GenerateCode(tc.length, tc.alphabet)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if utf8.RuneCountInString(code) != tc.length {
+					t.Fatalf("code %q has %d characters, want %d", code, utf8.RuneCountInString(code), tc.length)
+				}
+				for _, symbol := range code {
+					if !strings.ContainsRune(tc.alphabet, symbol) {
+						t.Fatalf("code %q has %q, which is not in the alphabet", code, symbol)
+					}
+				}
+			}
+		})
+	}
+
+	for _, alphabet := range []string{"", "7"} {
+		if _, err := GenerateCode(6, alphabet); !errors.Is(err, ErrInvalidOTPAlphabet) {
+			t.Errorf("GenerateCode(6, %q) error = %v, want ErrInvalidOTPAlphabet", alphabet, err)
+		}
+	}
+}
+
+func TestHashOTPRoundTrip(t *testing.T) {
+	usePepper(t)
+
+	stored := HashOTP("123456")
+	if !strings.HasPrefix(stored, otpHashPrefix) || strings.Contains(stored, "123456") {
+		t.Fatalf("HashOTP = %q, want a prefixed hash without the code", stored)
+	}
+	if IsLegacyOTP(stored) {
+		t.Fatal("a hashed OTP was taken for a legacy one")
+	}
+	if HashOTP("123456") != stored {
+		t.Fatal("HashOTP is not deterministic")
+	}
+	if !CheckOTP("123456", stored) {
+		t.Fatal("the code did not match its hash")
+	}
+	for _, provided := range []string{"123457", "", stored} {
+		if CheckOTP(provided, stored) {
+			t.Errorf("CheckOTP(%q) matched the hash of another code", provided)
+		}
+	}
+}
+
+func TestCheckOTPLegacy(t *testing.T) {
+	usePepper(t)
+
+	if !IsLegacyOTP("123456") {
+		t.Fatal("a plaintext OTP was not taken for a legacy one")
+	}
+	if !CheckOTP("123456", "123456") {
+		t.Fatal("a legacy code did not match")
+	}
+	for _, provided := range []string{"123457", "12345", ""} {
+		if CheckOTP(provided, "123456") {
+			t.Errorf("CheckOTP(%q) matched legacy code 123456", provided)
+		}
+	}
+}
+
+func TestCheckOTPEmptyStored(t *testing.T) {
+	usePepper(t)
+
+	for _, provided := range []string{"", "123456"} {
+		if CheckOTP(provided, "") {
+			t.Errorf("CheckOTP(%q) matched a user without a code", provided)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/outbox"
+	"context"
+	"time"
+)
+
+const (
+	OTPChannelEmail = "email"
+	OTPChannelSMS   = "sms"
+
+	PhoneOTPLifetime = 10 * time.Minute
+)
+
+type Phone struct {
+	Phone string `json:"phone" validate:"required,e164"`
+}
+
+type PhoneVerify struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MessagingPreferences struct {
+	OTPChannel string `json:"otp_channel" validate:"required,oneof=email sms"`
+	SMSMFA     bool   `json:"sms_mfa"`
+}
+
+func QueueOTPSMS(ctx context.Context, queries *db.Queries, phone string, otp string) error {
+	return outbox.QueueSMS(ctx, queries, phone, "Your verification code is "+otp+". Ignore if you did not request it.")
+}
+
+// DeliverOTP queues otp for the user's preferred channel, falling back to email when no phone is set.
+// Pass queries bound to the transaction that stores the OTP.
+func DeliverOTP(ctx context.Context, queries *db.Queries, user db.User, otp string) error {
+	if user.OtpChannel == OTPChannelSMS && user.Phone != "" {
+		return QueueOTPSMS(ctx, queries, user.Phone, otp)
+	}
+
+	return QueueOTP(ctx, queries, user.Email, user.Locale, otp)
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"strings"
+	"time"
+	//* Bundling the zone database so timezones validate on hosts without one
+	_ "time/tzdata"
+
+	"golang.org/x/text/language"
+)
+
+// ProfileUpdate holds the fields PATCH /me may change; fields left out keep their value. Email and
+// verification status are deliberately absent and have their own flows.
+type ProfileUpdate struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=255"`
+	DisplayName *string `json:"display_name" validate:"omitempty,max=255"`
+	Locale      *string `json:"locale" validate:"omitempty,min=1,max=35"`
+	Timezone    *string `json:"timezone" validate:"omitempty,min=1,max=64"`
+	// Attributes changes the editable custom attributes named in it; null removes one.
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// CanonicalLocale returns locale as a canonical BCP 47 tag, e.g. "es-mx" becomes "es-MX".
+func CanonicalLocale(locale string) (string, bool) {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil {
+		return "", false
+	}
+
+	return tag.String(), true
+}
+
+// ValidTimezone reports whether timezone is an IANA zone name such as "Europe/Madrid".
+func ValidTimezone(timezone string) bool {
+	if timezone == "" || timezone == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(timezone)
+
+	return err == nil
+}
@@ -2,9 +2,9 @@ package model
 
 import (
 	"Gin/Basics/configs"
-	"crypto/rand"
-	"fmt"
-	"net/smtp"
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/outbox"
+	"context"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -16,10 +16,15 @@ type UserResponse struct {
 }
 
 type User struct {
-	Name     string `json:"name" validate:"required"`
-	Email    string `json:"email" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	OTP      string `json:"otp"`
+	Name       string                 `json:"name" validate:"required"`
+	Email      string                 `json:"email" validate:"required"`
+	Username   string                 `json:"username" validate:"omitempty,max=64"`
+	Password   string                 `json:"password" validate:"required"`
+	Phone      string                 `json:"phone" validate:"omitempty,e164"`
+	OTPChannel string                 `json:"otp_channel" validate:"omitempty,oneof=email sms"`
+	Locale     string                 `json:"locale" validate:"omitempty,max=35"`
+	Attributes map[string]interface{} `json:"attributes"`
+	OTP        string                 `json:"otp"`
 }
 
 type OTP struct {
@@ -27,14 +32,23 @@ type OTP struct {
 	OTP   string `json:"otp" validate:"required"`
 }
 type Register struct {
-	Name     string `json:"name" validate:"required"`
-	Email    string `json:"email" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Name       string `json:"name" validate:"required"`
+	Email      string `json:"email" validate:"required"`
+	Username   string `json:"username" validate:"omitempty,max=64"`
+	Password   string `json:"password" validate:"required"`
+	Phone      string `json:"phone" validate:"omitempty,e164"`
+	OTPChannel string `json:"otp_channel" validate:"omitempty,oneof=email sms"`
+	Locale     string `json:"locale" validate:"omitempty,max=35"`
+	// Attributes holds the custom fields defined by USER_ATTRIBUTES_SCHEMA.
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
+// Login takes the user's email or username as identifier. Email is still accepted on its own for
+// clients written before usernames existed.
 type Login struct {
-	Email    string `json:"email" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Identifier string `json:"identifier" validate:"required_without=Email"`
+	Email      string `json:"email" validate:"required_without=Identifier"`
+	Password   string `json:"password" validate:"required"`
 }
 
 func (user *User) HashPassword(password string) error {
@@ -54,44 +68,18 @@ func CheckPassword(providedPassword string, userPassword string) error {
 	return nil
 }
 
-func (user *User) GenerateOTP() error {
-	randomBytes := make([]byte, 2)
-	_, err := rand.Read(randomBytes)
+// QueueOTP queues the email carrying otp. Pass queries bound to the transaction that stores the OTP.
+func QueueOTP(ctx context.Context, queries *db.Queries, email string, locale string, otp string) error {
+	return queueTemplate(ctx, queries, email, locale, "otp", map[string]interface{}{"OTP": otp})
+}
+
+// queueTemplate renders the email template name in locale and adds it to the outbox for email.
+func queueTemplate(ctx context.Context, queries *db.Queries, email string, locale string, name string, data interface{}) error {
+	msg, err := configs.Templates().Render(name, locale, data)
 	if err != nil {
 		return err
 	}
+	msg.To = []string{email}
 
-	otp := fmt.Sprintf("%06d", int(randomBytes[0])<<8|int(randomBytes[1])%1000000)
-	user.OTP = otp
-
-	return nil
-}
-
-func SendOTP(email string, otp string) error {
-
-	auth := smtp.PlainAuth("", configs.EMAIL(), configs.PASSWORD(), "smtp.gmail.com")
-
-	to := []string{email}
-
-	message := []byte(
-		"To:" + email + "\r\n" +
-			"Subject: OTP for Registration\r\n" +
-			"MIME-Version: 1.0\r\n" +
-			"Content-Type: text/html; charset=\"utf-8\"\r\n\r\n" +
-			"<html>" +
-			"<head>" +
-			"<title>OTP for Registration</title>" +
-			"</head>" +
-			"<body style=\"font-family: Arial, sans-serif;\">" +
-			"<div style=\"padding: 20px;\">" +
-			"<h1 style=\"color: #333;\">Welcome to our Service!</h1>" +
-			"<p style=\"font-size: 16px;\">Your OTP for registration is: <strong>" + otp + "</strong></p>" +
-			"<p>Ignore if you are not registered.</p>" +
-			"</div>" +
-			"</body>" +
-			"</html>")
-
-	err := smtp.SendMail("smtp.gmail.com:587", auth, configs.EMAIL(), to, message)
-
-	return err
+	return outbox.QueueEmail(ctx, queries, msg)
 }
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"Gin/Basics/configs"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const DefaultUsernamePattern = `^[a-z][a-z0-9._-]{2,29}$`
+
+var (
+	ErrInvalidUsername  = errors.New("invalid username")
+	ErrReservedUsername = errors.New("reserved username")
+)
+
+// reservedUsernames cannot be registered because they could pass for the service itself.
+var reservedUsernames = []string{
+	"abuse", "admin", "administrator", "api", "auth", "help", "hostmaster", "info", "mail",
+	"me", "moderator", "noreply", "no-reply", "postmaster", "root", "security", "staff",
+	"support", "system", "webmaster",
+}
+
+// NormalizeUsername returns username lowercased and trimmed, after checking it against
+// USERNAME_PATTERN and the reserved names.
+func NormalizeUsername(username string) (string, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+
+	pattern := configs.USERNAME_PATTERN()
+	if pattern == "" {
+		pattern = DefaultUsernamePattern
+	}
+	matcher, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	//* An "@" would make the username indistinguishable from an email at login
+	if !matcher.MatchString(username) || strings.Contains(username, "@") {
+		return "", ErrInvalidUsername
+	}
+
+	if slices.Contains(reservedUsernames, username) {
+		return "", ErrReservedUsername
+	}
+	for _, reserved := range strings.Split(configs.USERNAME_RESERVED(), ",") {
+		if strings.ToLower(strings.TrimSpace(reserved)) == username {
+			return "", ErrReservedUsername
+		}
+	}
+
+	return username, nil
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"log"
+	"sync"
+)
+
+// Message is a message recorded by FakeSender.
+type Message struct {
+	To   string
+	Body string
+}
+
+// FakeSender records messages instead of delivering them. With Log set it also prints them,
+// which makes it usable as the SMS transport during local development.
+type FakeSender struct {
+	Log bool
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (s *FakeSender) Send(to string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, Message{To: to, Body: message})
+	if s.Log {
+		log.Printf("SMS to %s: %s\n", to, message)
+	}
+
+	return nil
+}
+
+// Messages returns a copy of the messages sent so far.
+func (s *FakeSender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
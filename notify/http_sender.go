@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPSender is a generic adapter for SMS providers with a JSON HTTP API. Each message is POSTed to URL as
+//
+//	{"from": From, "to": to, "text": message}
+//
+// with Token sent as a bearer token when set. Any 2xx response counts as accepted.
+type HTTPSender struct {
+	URL    string
+	Token  string
+	From   string
+	Client *http.Client
+}
+
+func (s *HTTPSender) Send(to string, message string) error {
+	payload, err := json.Marshal(map[string]string{
+		"from": s.From,
+		"to":   to,
+		"text": message,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("sms provider responded %d: %s", res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"Gin/Basics/configs"
+	"log"
+	"sync"
+)
+
+// MessageSender delivers a short plain-text message, such as a verification code, to a recipient.
+type MessageSender interface {
+	Send(to string, message string) error
+}
+
+var (
+	smsSender     MessageSender
+	smsSenderOnce sync.Once
+)
+
+// SMS returns the sender used for text messages, which posts to SMS_PROVIDER_URL. With SMS_FAKE set
+// to "true" it is a FakeSender that logs messages for local development instead. Having neither
+// turns SMS off and SMS returns nil; see SMSEnabled.
+func SMS() MessageSender {
+	smsSenderOnce.Do(func() {
+		switch url := configs.SMS_PROVIDER_URL(); {
+		case url != "":
+			smsSender = &HTTPSender{
+				URL:   url,
+				Token: configs.SMS_PROVIDER_TOKEN(),
+				From:  configs.SMS_FROM(),
+			}
+		case configs.SMS_FAKE() == "true":
+			log.Println("SMS_FAKE is set: text messages are logged, not sent")
+			smsSender = &FakeSender{Log: true}
+		}
+	})
+
+	return smsSender
+}
+
+// SMSEnabled reports whether text messages can be sent. Without it, phone numbers, SMS codes and
+// SMS delivery of OTPs are unavailable.
+func SMSEnabled() bool {
+	return SMS() != nil
+}
+
+// SetSMS replaces the sender returned by SMS, e.g. with a FakeSender in tests.
+func SetSMS(sender MessageSender) {
+	smsSenderOnce.Do(func() {})
+	smsSender = sender
+}
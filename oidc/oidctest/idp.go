@@ -0,0 +1,194 @@
+// Package oidctest runs an OpenID Connect provider in memory for tests: it publishes a discovery
+// document and a key set, and its token endpoint checks PKCE before issuing a signed ID token.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// KeyID is the ID of the provider's signing key.
+const KeyID = "test-key"
+
+// IdP is a mock OpenID Connect provider. The zero value is not usable; call NewIdP.
+type IdP struct {
+	Server   *httptest.Server
+	ClientID string
+	Key      *rsa.PrivateKey
+	// Issuer is the issuer named by the discovery document, by default the server's URL.
+	Issuer string
+	// Claims, when set, changes the claims of the ID tokens the token endpoint issues.
+	Claims func(claims jwt.MapClaims)
+
+	mu             sync.Mutex
+	authorizations map[string]authorization
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+	subject   string
+	email     string
+}
+
+// NewIdP starts a provider for the client clientID, stopped when the test ends.
+func NewIdP(t testing.TB, clientID string) *IdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &IdP{ClientID: clientID, Key: key, authorizations: map[string]authorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	idp.Issuer = idp.Server.URL
+	t.Cleanup(idp.Server.Close)
+
+	return idp
+}
+
+// URL is the issuer URL to configure the provider with.
+func (i *IdP) URL() string {
+	return i.Server.URL
+}
+
+// Authorize plays the user signing in as subject on the page at authURL, as returned by
+// AuthCodeURL, and returns the code and state the provider redirects back with.
+func (i *IdP) Authorize(t testing.TB, authURL string, subject string, email string) (code string, state string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+
+	code = base64.RawURLEncoding.EncodeToString(big.NewInt(time.Now().UnixNano()).Bytes())
+	i.mu.Lock()
+	i.authorizations[code] = authorization{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		subject:   subject,
+		email:     email,
+	}
+	i.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+// Sign signs claims as an ID token with the provider's key.
+func (i *IdP) Sign(t testing.TB, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	signed, err := token.SignedString(i.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+// IDTokenClaims returns valid ID token claims for subject, issued for the client with nonce.
+func (i *IdP) IDTokenClaims(subject string, email string, nonce string) jwt.MapClaims {
+	now := time.Now()
+
+	return jwt.MapClaims{
+		"iss":            i.Server.URL,
+		"aud":            i.ClientID,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func (i *IdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 i.Issuer,
+		"authorization_endpoint": i.Server.URL + "/authorize",
+		"token_endpoint":         i.Server.URL + "/token",
+		"jwks_uri":               i.Server.URL + "/jwks",
+	})
+}
+
+func (i *IdP) jwks(w http.ResponseWriter, r *http.Request) {
+	public := i.Key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *IdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	i.mu.Lock()
+	auth, ok := i.authorizations[r.Form.Get("code")]
+	delete(i.authorizations, r.Form.Get("code"))
+	i.mu.Unlock()
+
+	//* RFC 7636: the verifier must hash to the challenge of the authorization request
+	digest := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(digest[:]) != auth.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := i.IDTokenClaims(auth.subject, auth.email, auth.nonce)
+	if i.Claims != nil {
+		i.Claims(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	signed, err := token.SignedString(i.Key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,386 @@
+// Package oidc signs users in through external identity providers: OpenID Connect providers such
+// as Google, whose ID tokens are verified against the provider's published keys, and plain OAuth 2.0
+// providers such as GitHub, whose user info endpoint identifies the user.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Provider types.
+const (
+	TypeOIDC   = "oidc"
+	TypeOAuth2 = "oauth2"
+)
+
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Provider is one configured identity provider. OIDC providers need only Issuer: the endpoints are
+// discovered from its /.well-known/openid-configuration unless given. OAuth 2.0 providers need
+// AuthURL, TokenURL and UserInfoURL.
+type Provider struct {
+	Name        string `json:"-"`
+	Type        string `json:"type"`
+	DisplayName string `json:"display_name"`
+	Issuer      string `json:"issuer"`
+	ClientID    string `json:"client_id"`
+	// ClientSecretEnv names an environment variable to read ClientSecret from, keeping it out of the file.
+	ClientSecret    string `json:"client_secret"`
+	ClientSecretEnv string `json:"client_secret_env"`
+	// RedirectURL is the callback route of the provider, which must be registered with it.
+	RedirectURL string   `json:"redirect_url"`
+	Scopes      []string `json:"scopes"`
+	AuthURL     string   `json:"auth_url"`
+	TokenURL    string   `json:"token_url"`
+	UserInfoURL string   `json:"userinfo_url"`
+	JWKSURL     string   `json:"jwks_url"`
+	// EmailsURL lists the user's addresses GitHub-style, as [{"email", "primary", "verified"}], for
+	// providers whose user info has no verified email.
+	EmailsURL string `json:"emails_url"`
+	// The user info fields of OAuth 2.0 providers, by default the OIDC claim names.
+	SubjectField       string `json:"subject_field"`
+	EmailField         string `json:"email_field"`
+	EmailVerifiedField string `json:"email_verified_field"`
+	NameField          string `json:"name_field"`
+
+	// HTTPClient makes the requests to the provider. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client `json:"-"`
+
+	mu         sync.Mutex
+	discovered bool
+	keys       map[string]interface{}
+	keysAt     time.Time
+}
+
+// Identity is the user as reported by a provider.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Registry holds the configured providers by name. The zero value has none.
+type Registry struct {
+	Providers map[string]*Provider
+}
+
+// Load reads providers from the JSON file at path: an object from provider names to providers, e.g.
+//
+//	{"google": {"type": "oidc", "issuer": "https://accounts.google.com", "client_id": "...",
+//	  "client_secret_env": "GOOGLE_CLIENT_SECRET", "redirect_url": "https://api.example.com/api/v1/auth/providers/google/callback"}}
+//
+// An empty path gives an empty registry.
+func Load(path string) (*Registry, error) {
+	if path == "" {
+		return &Registry{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse reads providers in the format described at Load.
+func Parse(data []byte) (*Registry, error) {
+	registry := &Registry{}
+	if err := json.Unmarshal(data, &registry.Providers); err != nil {
+		return nil, err
+	}
+
+	for name, provider := range registry.Providers {
+		if provider == nil {
+			return nil, fmt.Errorf("provider %q: empty definition", name)
+		}
+		provider.Name = name
+		if provider.ClientSecretEnv != "" {
+			provider.ClientSecret = os.Getenv(provider.ClientSecretEnv)
+		}
+		if provider.ClientID == "" || provider.RedirectURL == "" {
+			return nil, fmt.Errorf("provider %q: client_id and redirect_url are required", name)
+		}
+
+		switch provider.Type {
+		case TypeOIDC:
+			if provider.Issuer == "" {
+				return nil, fmt.Errorf("provider %q: issuer is required", name)
+			}
+			if !slices.Contains(provider.Scopes, "openid") {
+				provider.Scopes = append([]string{"openid", "email", "profile"}, provider.Scopes...)
+			}
+		case TypeOAuth2:
+			if provider.AuthURL == "" || provider.TokenURL == "" || provider.UserInfoURL == "" {
+				return nil, fmt.Errorf("provider %q: auth_url, token_url and userinfo_url are required", name)
+			}
+		default:
+			return nil, fmt.Errorf("provider %q: unknown type %q", name, provider.Type)
+		}
+
+		if provider.SubjectField == "" {
+			provider.SubjectField = "sub"
+		}
+		if provider.EmailField == "" {
+			provider.EmailField = "email"
+		}
+		if provider.EmailVerifiedField == "" {
+			provider.EmailVerifiedField = "email_verified"
+		}
+		if provider.NameField == "" {
+			provider.NameField = "name"
+		}
+		if provider.DisplayName == "" {
+			provider.DisplayName = name
+		}
+	}
+
+	return registry, nil
+}
+
+// Get returns the provider called name.
+func (r *Registry) Get(name string) (*Provider, bool) {
+	provider, ok := r.Providers[name]
+	return provider, ok
+}
+
+// Names returns the names of all providers in order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.Providers))
+	for name := range r.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewAuthRequest returns fresh values for one sign-in: the state that ties the callback to it, the
+// nonce the ID token must carry and the PKCE code verifier.
+func NewAuthRequest() (state string, nonce string, verifier string, err error) {
+	if state, err = randomString(); err != nil {
+		return "", "", "", err
+	}
+	if nonce, err = randomString(); err != nil {
+		return "", "", "", err
+	}
+
+	return state, nonce, oauth2.GenerateVerifier(), nil
+}
+
+// AuthCodeURL returns the provider's sign-in page for the given request values.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	config, err := p.config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	options := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if p.Type == TypeOIDC {
+		options = append(options, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+
+	return config.AuthCodeURL(state, options...), nil
+}
+
+// Exchange redeems the authorization code from the callback and returns the signed-in user. For
+// OIDC providers the ID token must be valid and carry nonce.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	config, err := p.config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(p.clientContext(ctx), code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Type == TypeOIDC {
+		rawIDToken, _ := token.Extra("id_token").(string)
+		if rawIDToken == "" {
+			return nil, fmt.Errorf("%w: missing from the token response", ErrInvalidIDToken)
+		}
+		return p.verifyIDToken(ctx, rawIDToken, nonce)
+	}
+
+	return p.userInfo(ctx, config.Client(p.clientContext(ctx), token))
+}
+
+// config returns the OAuth 2.0 client configuration, discovering the endpoints of OIDC providers first.
+func (p *Provider) config(ctx context.Context) (*oauth2.Config, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  p.RedirectURL,
+		Scopes:       p.Scopes,
+		Endpoint:     oauth2.Endpoint{AuthURL: p.AuthURL, TokenURL: p.TokenURL},
+	}, nil
+}
+
+// discover fills the endpoints of an OIDC provider that were not configured from its discovery document.
+func (p *Provider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Type != TypeOIDC || p.discovered {
+		return nil
+	}
+
+	var document struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := p.getJSON(ctx, p.client(), strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", &document); err != nil {
+		return fmt.Errorf("discovering %s: %w", p.Name, err)
+	}
+	//* OIDC Discovery requires the document to name the issuer it was fetched from
+	if document.Issuer != p.Issuer {
+		return fmt.Errorf("discovering %s: issuer %q does not match %q", p.Name, document.Issuer, p.Issuer)
+	}
+
+	if p.AuthURL == "" {
+		p.AuthURL = document.AuthorizationEndpoint
+	}
+	if p.TokenURL == "" {
+		p.TokenURL = document.TokenEndpoint
+	}
+	if p.UserInfoURL == "" {
+		p.UserInfoURL = document.UserInfoEndpoint
+	}
+	if p.JWKSURL == "" {
+		p.JWKSURL = document.JWKSURI
+	}
+	p.discovered = true
+
+	return nil
+}
+
+// userInfo identifies the user of an OAuth 2.0 provider from its user info endpoint.
+func (p *Provider) userInfo(ctx context.Context, client *http.Client) (*Identity, error) {
+	var info map[string]interface{}
+	if err := p.getJSON(ctx, client, p.UserInfoURL, &info); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider:      p.Name,
+		Subject:       stringField(info[p.SubjectField]),
+		Email:         stringField(info[p.EmailField]),
+		EmailVerified: boolField(info[p.EmailVerifiedField]),
+		Name:          stringField(info[p.NameField]),
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("user info has no %s", p.SubjectField)
+	}
+
+	if p.EmailsURL != "" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err := p.getJSON(ctx, client, p.EmailsURL, &emails); err != nil {
+			return nil, err
+		}
+		for _, email := range emails {
+			if email.Primary {
+				identity.Email = email.Email
+				identity.EmailVerified = email.Verified
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, client *http.Client, url string, target interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+
+	decoder := json.NewDecoder(response.Body)
+	decoder.UseNumber()
+
+	return decoder.Decode(target)
+}
+
+func (p *Provider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+
+	return defaultClient
+}
+
+// clientContext makes the oauth2 package use the provider's HTTP client.
+func (p *Provider) clientContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, p.client())
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+func randomString() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// stringField returns a user info field as text; numeric IDs such as GitHub's are formatted as numbers.
+func stringField(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	}
+
+	return ""
+}
+
+// boolField accepts both booleans and the strings some providers send instead.
+func boolField(value interface{}) bool {
+	switch value := value.(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+
+	return false
+}
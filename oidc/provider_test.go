@@ -0,0 +1,175 @@
+package oidc
+
+import (
+	"Gin/Basics/oidc/oidctest"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const testClientID = "test-client"
+
+// newTestProvider returns an OIDC provider configured against idp.
+func newTestProvider(t *testing.T, idp *oidctest.IdP) *Provider {
+	t.Helper()
+
+	registry, err := Parse([]byte(fmt.Sprintf(`{"mock": {"type": "oidc", "issuer": %q, "client_id": %q,
+		"client_secret": "secret", "redirect_url": "https://api.example.com/callback"}}`, idp.URL(), testClientID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, _ := registry.Get("mock")
+
+	return provider
+}
+
+// signIn runs a sign-in up to the callback and returns the code, the request's verifier and nonce.
+func signIn(t *testing.T, idp *oidctest.IdP, provider *Provider) (code string, verifier string, nonce string) {
+	t.Helper()
+
+	state, nonce, verifier, err := NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, returnedState := idp.Authorize(t, authURL, "subject-1", "ada@example.com")
+	if returnedState != state {
+		t.Fatalf("state = %q, want %q", returnedState, state)
+	}
+
+	return code, verifier, nonce
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewIdP(t, testClientID)
+	idp.Issuer = "https://attacker.example.com"
+	provider := newTestProvider(t, idp)
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("AuthCodeURL error = %v, want an issuer mismatch", err)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := oidctest.NewIdP(t, testClientID)
+	provider := newTestProvider(t, idp)
+
+	code, verifier, nonce := signIn(t, idp, provider)
+	identity, err := provider.Exchange(context.Background(), code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Provider != "mock" || identity.Subject != "subject-1" || identity.Email != "ada@example.com" || !identity.EmailVerified {
+		t.Fatalf("identity = %+v", identity)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp := oidctest.NewIdP(t, testClientID)
+	provider := newTestProvider(t, idp)
+
+	code, _, nonce := signIn(t, idp, provider)
+	_, _, otherVerifier, err := NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, otherVerifier, nonce); err == nil {
+		t.Fatal("expected the token endpoint to refuse a verifier that does not match the challenge")
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	idp := oidctest.NewIdP(t, testClientID)
+	provider := newTestProvider(t, idp)
+
+	code, verifier, _ := signIn(t, idp, provider)
+	_, err := provider.Exchange(context.Background(), code, verifier, "another-nonce")
+	if !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("Exchange error = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	idp := oidctest.NewIdP(t, testClientID)
+	provider := newTestProvider(t, idp)
+	ctx := context.Background()
+	if err := provider.discover(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signWith := func(method jwt.SigningMethod, kid string, key interface{}) func(jwt.MapClaims) string {
+		return func(claims jwt.MapClaims) string {
+			token := jwt.NewWithClaims(method, claims)
+			if kid != "" {
+				token.Header["kid"] = kid
+			}
+			signed, err := token.SignedString(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signed
+		}
+	}
+	valid := func(claims jwt.MapClaims) string { return idp.Sign(t, claims) }
+
+	for _, tc := range []struct {
+		name    string
+		claims  func(jwt.MapClaims)
+		sign    func(jwt.MapClaims) string
+		wantErr bool
+	}{
+		{name: "valid", claims: func(jwt.MapClaims) {}, sign: valid},
+		{name: "wrong issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://attacker.example.com" }, sign: valid, wantErr: true},
+		{name: "wrong audience", claims: func(c jwt.MapClaims) { c["aud"] = "another-client" }, sign: valid, wantErr: true},
+		{name: "several audiences without azp", claims: func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "another-client"} }, sign: valid, wantErr: true},
+		{name: "several audiences with another azp", claims: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "another-client"}
+			c["azp"] = "another-client"
+		}, sign: valid, wantErr: true},
+		{name: "several audiences with azp", claims: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "another-client"}
+			c["azp"] = testClientID
+		}, sign: valid},
+		{name: "expired", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, sign: valid, wantErr: true},
+		{name: "no expiry", claims: func(c jwt.MapClaims) { delete(c, "exp") }, sign: valid, wantErr: true},
+		{name: "no subject", claims: func(c jwt.MapClaims) { delete(c, "sub") }, sign: valid, wantErr: true},
+		{name: "alg none", claims: func(jwt.MapClaims) {}, sign: signWith(jwt.SigningMethodNone, oidctest.KeyID, jwt.UnsafeAllowNoneSignatureType), wantErr: true},
+		{name: "HS256 with the client secret", claims: func(jwt.MapClaims) {}, sign: signWith(jwt.SigningMethodHS256, oidctest.KeyID, []byte("secret")), wantErr: true},
+		{name: "unknown kid", claims: func(jwt.MapClaims) {}, sign: signWith(jwt.SigningMethodRS256, "other-key", otherKey), wantErr: true},
+		{name: "signed with another key", claims: func(jwt.MapClaims) {}, sign: signWith(jwt.SigningMethodRS256, oidctest.KeyID, otherKey), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := idp.IDTokenClaims("subject-1", "ada@example.com", "nonce")
+			tc.claims(claims)
+
+			identity, err := provider.verifyIDToken(ctx, tc.sign(claims), "nonce")
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("verifyIDToken error = %v, want ErrInvalidIDToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyIDToken: %v", err)
+			}
+			if identity.Subject != "subject-1" {
+				t.Fatalf("subject = %q", identity.Subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/mailer"
+	"context"
+)
+
+// Channels a queued message can be delivered over.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Delivery states of a queued message. A message is dead once it has used up its attempts; an
+// admin can put it back in the queue with RetryOutboxMessage.
+const (
+	StatusPending = "pending"
+	StatusSending = "sending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+// QueueEmail adds msg to the outbox, one message per recipient. queries should be bound to the
+// transaction that makes the change the email is about, so that the email goes out if and only if
+// that change is committed.
+func QueueEmail(ctx context.Context, queries *db.Queries, msg mailer.Message) error {
+	for _, to := range msg.To {
+		if _, err := queries.CreateOutboxMessage(ctx, db.CreateOutboxMessageParams{
+			Channel:   ChannelEmail,
+			Recipient: to,
+			Subject:   msg.Subject,
+			BodyText:  msg.Text,
+			BodyHtml:  msg.HTML,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueueSMS adds a text message to the outbox. See QueueEmail for how to pick queries.
+func QueueSMS(ctx context.Context, queries *db.Queries, to string, body string) error {
+	_, err := queries.CreateOutboxMessage(ctx, db.CreateOutboxMessageParams{
+		Channel:   ChannelSMS,
+		Recipient: to,
+		BodyText:  body,
+	})
+
+	return err
+}
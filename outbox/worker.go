@@ -0,0 +1,163 @@
+package outbox
+
+import (
+	db "Gin/Basics/db/sqlconfig"
+	"Gin/Basics/mailer"
+	"Gin/Basics/notify"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	DefaultMaxAttempts = 8
+
+	defaultLease        = 5 * time.Minute
+	defaultPollInterval = time.Second
+
+	backoffBase = 30 * time.Second
+	backoffMax  = time.Hour
+)
+
+// Worker delivers the messages in the outbox. Any number of workers, in this process or others,
+// can run against the same database: each message is claimed by one of them at a time.
+type Worker struct {
+	Queries *db.Queries
+	Mailer  mailer.Mailer
+	SMS     notify.MessageSender
+
+	// MaxAttempts is how many times delivery is tried before the message is dead-lettered.
+	MaxAttempts int
+	// Lease is how long a claimed message is left alone before it is assumed that its worker died.
+	Lease time.Duration
+	// PollInterval is how long an idle worker waits before looking for due messages again.
+	PollInterval time.Duration
+	// Alert, when set, is told about every message that is dead-lettered.
+	Alert func(error)
+}
+
+// Start runs n delivery goroutines until ctx is cancelled.
+func (w *Worker) Start(ctx context.Context, n int) {
+	for i := 0; i < n; i++ {
+		go w.run(ctx)
+	}
+}
+
+func (w *Worker) run(ctx context.Context) {
+	for {
+		claimed, err := w.DeliverNext(ctx)
+		if err != nil {
+			log.Println("outbox:", err)
+		}
+		if claimed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.pollInterval()):
+		}
+	}
+}
+
+// DeliverNext claims the message that has been due the longest and tries to deliver it. A failed
+// delivery is retried later with exponential backoff until MaxAttempts is reached. It reports
+// whether there was a message to deliver.
+func (w *Worker) DeliverNext(ctx context.Context) (bool, error) {
+	msg, err := w.Queries.ClaimOutboxMessage(ctx, timestamptz(time.Now().Add(w.lease())))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	sendErr := w.send(msg)
+	if sendErr == nil {
+		return true, w.Queries.MarkOutboxMessageSent(ctx, msg.ID)
+	}
+
+	if int(msg.Attempts) >= w.maxAttempts() {
+		log.Printf("outbox: giving up on %s message %d to %s after %d attempts: %v\n", msg.Channel, msg.ID, msg.Recipient, msg.Attempts, sendErr)
+		if w.Alert != nil {
+			//* Without the message ID, so that identical failures are grouped into one alert
+			w.Alert(fmt.Errorf("outbox: %s delivery failed after %d attempts: %w", msg.Channel, msg.Attempts, sendErr))
+		}
+		return true, w.Queries.DeadLetterOutboxMessage(ctx, db.DeadLetterOutboxMessageParams{ID: msg.ID, LastError: sendErr.Error()})
+	}
+
+	return true, w.Queries.RescheduleOutboxMessage(ctx, db.RescheduleOutboxMessageParams{
+		ID:            msg.ID,
+		NextAttemptAt: timestamptz(time.Now().Add(Backoff(int(msg.Attempts)))),
+		LastError:     sendErr.Error(),
+	})
+}
+
+func (w *Worker) send(msg db.OutboxMessage) error {
+	switch msg.Channel {
+	case ChannelSMS:
+		if w.SMS == nil {
+			return errors.New("no SMS sender configured")
+		}
+		return w.SMS.Send(msg.Recipient, msg.BodyText)
+	case ChannelEmail:
+		if w.Mailer == nil {
+			return errors.New("no mailer configured")
+		}
+		return w.Mailer.Send(mailer.Message{
+			To:      []string{msg.Recipient},
+			Subject: msg.Subject,
+			Text:    msg.BodyText,
+			HTML:    msg.BodyHtml,
+		})
+	default:
+		return errors.New("unknown channel " + msg.Channel)
+	}
+}
+
+// Backoff returns how long to wait before the next attempt after the given number of failed
+// attempts: 30 seconds, doubling each time up to an hour, minus up to a fifth at random so that
+// messages that failed together are not all retried together.
+func Backoff(attempts int) time.Duration {
+	delay := backoffBase
+	for i := 1; i < attempts && delay < backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > backoffMax {
+		delay = backoffMax
+	}
+
+	return delay - time.Duration(rand.Int63n(int64(delay/5)+1))
+}
+
+func (w *Worker) maxAttempts() int {
+	if w.MaxAttempts > 0 {
+		return w.MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+func (w *Worker) lease() time.Duration {
+	if w.Lease > 0 {
+		return w.Lease
+	}
+	return defaultLease
+}
+
+func (w *Worker) pollInterval() time.Duration {
+	if w.PollInterval > 0 {
+		return w.PollInterval
+	}
+	return defaultPollInterval
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
@@ -2,6 +2,7 @@ package responses
 
 type UserResponse struct {
 	Message string                 `json:"message"`
+	Code    string                 `json:"code,omitempty"`
 	Data    map[string]interface{} `json:"data,omitempty"`
 }
 
@@ -12,4 +13,5 @@ type UserResponse_doc struct {
 
 type ErrorResponse_doc struct {
     Message string `json:"message"`
+    Code    string `json:"code"`
 }
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	controller "Gin/Basics/controllers"
+	"Gin/Basics/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoute(router *gin.RouterGroup) {
+	admin := router.Group("/admin", middleware.Authenticate(), middleware.RequireAdmin())
+
+	admin.GET("/outbox", controller.ListOutboxMessages)
+	admin.POST("/outbox/:id/retry", controller.RetryOutboxMessage)
+
+	admin.GET("/users", controller.ListUsers)
+	admin.GET("/users/:id", controller.GetUser)
+	admin.DELETE("/users/:id", controller.DeleteUser)
+	admin.POST("/users/:id/verify", controller.VerifyUser)
+	admin.POST("/users/:id/disable", controller.DisableUser)
+	admin.POST("/users/:id/lock", controller.LockUser)
+	admin.POST("/users/:id/enable", controller.EnableUser)
+	admin.POST("/users/:id/logout", controller.LogoutUser)
+
+	admin.POST("/invitations", controller.CreateInvitation)
+	admin.GET("/invitations", controller.ListInvitations)
+	admin.POST("/invitations/:id/resend", controller.ResendInvitation)
+	admin.DELETE("/invitations/:id", controller.RevokeInvitation)
+}
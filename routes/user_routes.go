@@ -2,12 +2,60 @@ package routes
 
 import (
 	controller "Gin/Basics/controllers"
+	"Gin/Basics/middleware"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// recentAuth is how long after signing in a user may perform sensitive operations without reauthenticating.
+const recentAuth = 15 * time.Minute
+
 func UserRoute(router *gin.RouterGroup) {
+	router.GET("/me", middleware.Authenticate(), controller.GetProfile)
+	router.PATCH("/me", middleware.Authenticate(), controller.UpdateProfile)
+	router.DELETE("/me", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.DeleteAccount)
+	router.GET("/me/export", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.ExportAccount)
+
 	router.POST("/auth/login", controller.Login)
 	router.POST("/auth/register", controller.Register)
 	router.POST("/auth/otp", controller.ValidateOTP)
+	router.POST("/auth/magic-link", controller.RequestMagicLink)
+	router.GET("/auth/magic-link/callback", controller.MagicLinkCallback)
+	router.POST("/auth/magic-link/callback", controller.ConfirmMagicLink)
+	router.POST("/auth/invitations/accept", controller.AcceptInvitation)
+	router.GET("/auth/providers", controller.ListProviders)
+	router.GET("/auth/providers/:provider/login", controller.ProviderLogin)
+	router.GET("/auth/providers/:provider/callback", controller.ProviderCallback)
+	router.POST("/auth/providers/:provider/link", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.LinkProvider)
+	router.GET("/auth/identities", middleware.Authenticate(), controller.ListIdentities)
+	router.DELETE("/auth/identities/:id", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.UnlinkIdentity)
+	router.GET("/auth/saml/:connection/metadata", controller.SAMLMetadata)
+	router.GET("/auth/saml/:connection/login", controller.SAMLLogin)
+	router.POST("/auth/saml/:connection/acs", controller.SAMLACS)
+	router.POST("/auth/reauthenticate", middleware.Authenticate(), controller.Reauthenticate)
+	router.POST("/auth/reauthenticate/sms", middleware.Authenticate(), controller.SendReauthSMS)
+	router.POST("/auth/reauthenticate/webauthn", middleware.Authenticate(), controller.BeginReauthWebAuthn)
+	router.PUT("/auth/email", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.RequestEmailChange)
+	router.POST("/auth/email/confirm", middleware.Authenticate(), controller.ConfirmEmailChange)
+
+	router.POST("/auth/mfa/verify", controller.VerifyMFA)
+	router.POST("/auth/mfa/totp/enroll", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.EnrollTOTP)
+	router.POST("/auth/mfa/totp/confirm", middleware.Authenticate(), controller.ConfirmTOTP)
+	router.POST("/auth/mfa/recovery-codes", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.RegenerateRecoveryCodes)
+	router.POST("/auth/mfa/webauthn/begin", controller.BeginWebAuthnMFA)
+	router.POST("/auth/mfa/webauthn/finish", controller.FinishWebAuthnMFA)
+	router.POST("/auth/mfa/sms/send", controller.SendSMSMFA)
+	router.POST("/auth/mfa/sms/verify", controller.VerifySMSMFA)
+
+	router.PUT("/auth/phone", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.SetPhone)
+	router.POST("/auth/phone/verify", middleware.Authenticate(), controller.VerifyPhone)
+	router.PUT("/auth/phone/preferences", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.UpdateMessagingPreferences)
+
+	router.POST("/auth/webauthn/register/begin", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.BeginWebAuthnRegistration)
+	router.POST("/auth/webauthn/register/finish", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.FinishWebAuthnRegistration)
+	router.GET("/auth/webauthn/credentials", middleware.Authenticate(), controller.ListWebAuthnCredentials)
+	router.DELETE("/auth/webauthn/credentials/:id", middleware.Authenticate(), middleware.RequireRecentAuth(recentAuth), controller.DeleteWebAuthnCredential)
+	router.POST("/auth/webauthn/login/begin", controller.BeginWebAuthnLogin)
+	router.POST("/auth/webauthn/login/finish", controller.FinishWebAuthnLogin)
 }
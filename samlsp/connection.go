@@ -0,0 +1,469 @@
+// Package samlsp signs users in through SAML 2.0 identity providers, acting as the service provider
+// of enterprise single sign-on connections. Each connection has its own metadata, AuthnRequests are
+// sent with the HTTP-Redirect binding and assertions come back to the Assertion Consumer Service
+// with the HTTP-POST binding.
+package samlsp
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// The attribute names an email address is looked up under when a connection does not name one:
+// the common LDAP-style names, the ADFS/Azure AD claim and the OID of the mail attribute.
+var defaultEmailAttributes = []string{
+	"email",
+	"mail",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	"urn:oid:0.9.2342.19200300.100.1.3",
+}
+
+var ErrInvalidResponse = errors.New("invalid SAML response")
+
+// AttributeMap names the SAML attributes that fill the fields of users. Attributes are matched by
+// Name or FriendlyName.
+type AttributeMap struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Phone    string `json:"phone"`
+	Locale   string `json:"locale"`
+	// Custom maps custom user attributes to the SAML attributes they are taken from.
+	Custom map[string]string `json:"custom"`
+}
+
+// Connection is one configured identity provider. The IdP metadata comes from IDPMetadataFile, or
+// from IDPMetadataURL the first time the connection is used.
+type Connection struct {
+	Name            string `json:"-"`
+	DisplayName     string `json:"display_name"`
+	IDPMetadataURL  string `json:"idp_metadata_url"`
+	IDPMetadataFile string `json:"idp_metadata_file"`
+	// EntityID identifies this service to the IdP. Defaults to the connection's metadata URL.
+	EntityID string `json:"entity_id"`
+	// NameIDFormat is requested from the IdP, e.g. urn:oasis:names:tc:SAML:2.0:nameid-format:persistent.
+	// By default the IdP picks it.
+	NameIDFormat string       `json:"name_id_format"`
+	Attributes   AttributeMap `json:"attributes"`
+	// Provision creates accounts for users who first sign in through the connection. Otherwise only
+	// users with an account can sign in. It requires AllowedDomains.
+	Provision bool `json:"provision"`
+	// LinkByEmail links a new identity to the existing account with the same email address. It
+	// requires AllowedDomains.
+	LinkByEmail bool `json:"link_by_email"`
+	// AllowedDomains are the email domains the IdP may assert addresses at, e.g. the customer's own.
+	// Users asserted at other domains are refused. Empty allows any domain.
+	AllowedDomains []string `json:"allowed_domains"`
+
+	// HTTPClient fetches the IdP metadata. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client `json:"-"`
+
+	mu sync.Mutex
+	sp *saml.ServiceProvider
+}
+
+// Identity is the user as asserted by an IdP. Custom holds the values of AttributeMap.Custom.
+type Identity struct {
+	Connection string
+	Subject    string
+	Email      string
+	Name       string
+	Username   string
+	Phone      string
+	Locale     string
+	Custom     map[string][]string
+}
+
+// Registry holds the configured connections by name. The zero value has none.
+type Registry struct {
+	Connections map[string]*Connection
+}
+
+// ServiceProvider is the identity of this service towards the IdPs: the base URL the connection
+// routes live under, and the key and certificate that sign AuthnRequests and decrypt assertions.
+type ServiceProvider struct {
+	BaseURL     *url.URL
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// LoadServiceProvider reads the PEM-encoded RSA key and certificate at keyPath and certPath.
+// baseURL is the API root, e.g. https://api.example.com/api/v1.
+func LoadServiceProvider(baseURL string, keyPath string, certPath string) (*ServiceProvider, error) {
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if !base.IsAbs() {
+		return nil, fmt.Errorf("base URL %q is not absolute", baseURL)
+	}
+
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s: no PEM certificate", certPath)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceProvider{BaseURL: base, Key: key, Certificate: cert}, nil
+}
+
+func parseKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM private key")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("the private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+
+	return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+}
+
+// Load reads connections from the JSON file at path: an object from connection names to
+// connections, e.g.
+//
+//	{"acme": {"display_name": "Acme Corp", "idp_metadata_url": "https://acme.okta.com/app/.../sso/saml/metadata",
+//	  "attributes": {"name": "displayName", "custom": {"department": "department"}}, "provision": true,
+//	  "allowed_domains": ["acme.com"]}}
+//
+// The routes of a connection live under sp.BaseURL + /auth/saml/{name}. An empty path gives an
+// empty registry.
+func Load(path string, sp *ServiceProvider) (*Registry, error) {
+	if path == "" {
+		return &Registry{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data, sp)
+}
+
+// Parse reads connections in the format described at Load.
+func Parse(data []byte, sp *ServiceProvider) (*Registry, error) {
+	registry := &Registry{}
+	if err := json.Unmarshal(data, &registry.Connections); err != nil {
+		return nil, err
+	}
+	if len(registry.Connections) > 0 && sp == nil {
+		return nil, errors.New("SAML connections need the service provider's base URL, key and certificate")
+	}
+
+	for name, connection := range registry.Connections {
+		if connection == nil {
+			return nil, fmt.Errorf("connection %q: empty definition", name)
+		}
+		connection.Name = name
+		if connection.DisplayName == "" {
+			connection.DisplayName = name
+		}
+		for i, domain := range connection.AllowedDomains {
+			connection.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		}
+		//* Otherwise any IdP could assert the address of an account at another company and take it
+		//* over, or claim addresses at other companies for new accounts
+		if connection.LinkByEmail && len(connection.AllowedDomains) == 0 {
+			return nil, fmt.Errorf("connection %q: link_by_email requires allowed_domains", name)
+		}
+		if connection.Provision && len(connection.AllowedDomains) == 0 {
+			return nil, fmt.Errorf("connection %q: provision requires allowed_domains", name)
+		}
+
+		routes := sp.BaseURL.JoinPath("auth", "saml", name)
+		connection.sp = &saml.ServiceProvider{
+			EntityID:          connection.EntityID,
+			Key:               sp.Key,
+			Certificate:       sp.Certificate,
+			MetadataURL:       *routes.JoinPath("metadata"),
+			AcsURL:            *routes.JoinPath("acs"),
+			AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+			SignatureMethod:   dsig.RSASHA256SignatureMethod,
+		}
+		if connection.sp.EntityID == "" {
+			connection.sp.EntityID = connection.sp.MetadataURL.String()
+		}
+		if connection.NameIDFormat != "" {
+			connection.sp.AuthnNameIDFormat = saml.NameIDFormat(connection.NameIDFormat)
+		}
+
+		switch {
+		case connection.IDPMetadataFile != "":
+			metadata, err := os.ReadFile(connection.IDPMetadataFile)
+			if err != nil {
+				return nil, fmt.Errorf("connection %q: %w", name, err)
+			}
+			if connection.sp.IDPMetadata, err = ParseIDPMetadata(metadata); err != nil {
+				return nil, fmt.Errorf("connection %q: %w", name, err)
+			}
+		case connection.IDPMetadataURL == "":
+			return nil, fmt.Errorf("connection %q: idp_metadata_url or idp_metadata_file is required", name)
+		}
+	}
+
+	return registry, nil
+}
+
+// Get returns the connection called name.
+func (r *Registry) Get(name string) (*Connection, bool) {
+	connection, ok := r.Connections[name]
+	return connection, ok
+}
+
+// Names returns the names of all connections in order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.Connections))
+	for name := range r.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseIDPMetadata reads IdP metadata, which is either an EntityDescriptor or an
+// EntitiesDescriptor whose first IdP is used.
+func ParseIDPMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	entity := &saml.EntityDescriptor{}
+	err := xml.Unmarshal(data, entity)
+	if err != nil && strings.Contains(err.Error(), "<EntitiesDescriptor>") {
+		entities := &saml.EntitiesDescriptor{}
+		if err := xml.Unmarshal(data, entities); err != nil {
+			return nil, err
+		}
+		for i := range entities.EntityDescriptors {
+			if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+				return &entities.EntityDescriptors[i], nil
+			}
+		}
+		return nil, errors.New("the metadata describes no identity provider")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entity.IDPSSODescriptors) == 0 {
+		return nil, errors.New("the metadata describes no identity provider")
+	}
+
+	return entity, nil
+}
+
+// AllowsEmail reports whether the IdP may assert email, which must be at one of AllowedDomains.
+func (c *Connection) AllowsEmail(email string) bool {
+	if len(c.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	return slices.Contains(c.AllowedDomains, strings.ToLower(email[at+1:]))
+}
+
+// Metadata returns the service provider metadata to register with the IdP.
+func (c *Connection) Metadata() ([]byte, error) {
+	return xml.MarshalIndent(c.sp.Metadata(), "", "  ")
+}
+
+// AuthnRequestURL returns the IdP page that signs the user in, carrying a signed AuthnRequest,
+// and the ID of that request, which the response must answer.
+func (c *Connection) AuthnRequestURL(ctx context.Context) (string, string, error) {
+	sp, err := c.serviceProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	location := sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if location == "" {
+		return "", "", fmt.Errorf("connection %s: the IdP has no HTTP-Redirect sign-in endpoint", c.Name)
+	}
+	request, err := sp.MakeAuthenticationRequest(location, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", err
+	}
+	redirect, err := request.Redirect("", sp)
+	if err != nil {
+		return "", "", err
+	}
+
+	return redirect.String(), request.ID, nil
+}
+
+// ParseResponse validates the SAML response posted to the Assertion Consumer Service, which must
+// answer the AuthnRequest requestID, and returns the asserted user. The IdP's signature, the
+// issuer, the audience, the recipient and the validity period of the assertion are all checked.
+func (c *Connection) ParseResponse(ctx context.Context, r *http.Request, requestID string) (*Identity, error) {
+	sp, err := c.serviceProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	//* The metadata offers only the HTTP-POST binding, so artifacts are never resolved
+	if r.PostForm.Get("SAMLResponse") == "" {
+		return nil, fmt.Errorf("%w: no SAMLResponse posted", ErrInvalidResponse)
+	}
+	r.Form.Del("SAMLart")
+
+	assertion, err := sp.ParseResponse(r, []string{requestID})
+	if err != nil {
+		//* The library hides the reason behind a generic error
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) && invalid.PrivateErr != nil {
+			err = invalid.PrivateErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+
+	return c.identity(assertion)
+}
+
+// identity maps the subject and attributes of an assertion to the user.
+func (c *Connection) identity(assertion *saml.Assertion) (*Identity, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidResponse)
+	}
+	nameID := assertion.Subject.NameID
+	//* A transient NameID changes with every sign-in, so it cannot be linked to an account
+	if nameID.Format == string(saml.TransientNameIDFormat) {
+		return nil, fmt.Errorf("%w: transient NameID", ErrInvalidResponse)
+	}
+
+	values := map[string][]string{}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			for _, value := range attribute.Values {
+				for _, key := range []string{attribute.Name, attribute.FriendlyName} {
+					if key != "" {
+						values[key] = append(values[key], value.Value)
+					}
+				}
+			}
+		}
+	}
+	first := func(names ...string) string {
+		for _, name := range names {
+			if len(values[name]) > 0 {
+				return strings.TrimSpace(values[name][0])
+			}
+		}
+		return ""
+	}
+
+	identity := &Identity{
+		Connection: c.Name,
+		Subject:    nameID.Value,
+		Name:       first(c.Attributes.Name),
+		Username:   first(c.Attributes.Username),
+		Phone:      first(c.Attributes.Phone),
+		Locale:     first(c.Attributes.Locale),
+		Custom:     map[string][]string{},
+	}
+	if c.Attributes.Email != "" {
+		identity.Email = first(c.Attributes.Email)
+	} else {
+		identity.Email = first(defaultEmailAttributes...)
+	}
+	if identity.Email == "" && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+		identity.Email = nameID.Value
+	}
+	for field, attribute := range c.Attributes.Custom {
+		if len(values[attribute]) > 0 {
+			identity.Custom[field] = values[attribute]
+		}
+	}
+
+	return identity, nil
+}
+
+// serviceProvider returns the SAML service provider of the connection, fetching the IdP metadata
+// the first time when it comes from a URL.
+func (c *Connection) serviceProvider(ctx context.Context) (*saml.ServiceProvider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sp.IDPMetadata != nil {
+		return c.sp, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.IDPMetadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client().Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetching the metadata of %s: %w", c.Name, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching the metadata of %s: GET %s: %s", c.Name, c.IDPMetadataURL, response.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := ParseIDPMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("fetching the metadata of %s: %w", c.Name, err)
+	}
+	c.sp.IDPMetadata = metadata
+
+	return c.sp, nil
+}
+
+func (c *Connection) client() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	return defaultClient
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
@@ -0,0 +1,177 @@
+package samlsp
+
+import (
+	"Gin/Basics/samlsp/samlsptest"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+)
+
+const (
+	testRequestID = "id-request-1"
+	testEntityID  = "https://api.example.com/api/v1/auth/saml/acme/metadata"
+	testACSURL    = "https://api.example.com/api/v1/auth/saml/acme/acs"
+)
+
+// newTestConnection returns the connection "acme" to idp, with settings added to its definition.
+func newTestConnection(t *testing.T, idp *samlsptest.IdP, settings string) *Connection {
+	t.Helper()
+
+	key, cert := samlsptest.KeyPair(t, "api.example.com")
+	base, _ := url.Parse("https://api.example.com/api/v1")
+	registry, err := Parse([]byte(fmt.Sprintf(`{"acme": {"idp_metadata_file": %q,
+		"attributes": {"name": "displayName", "custom": {"department": "department"}}%s}}`, idp.MetadataFile(t), settings)),
+		&ServiceProvider{BaseURL: base, Key: key, Certificate: cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection, _ := registry.Get("acme")
+
+	return connection
+}
+
+// validResponse describes a response to testRequestID that the connection accepts.
+func validResponse() samlsptest.Response {
+	return samlsptest.Response{
+		RequestID:    testRequestID,
+		SPEntityID:   testEntityID,
+		ACSURL:       testACSURL,
+		NameID:       "employee-42",
+		NameIDFormat: saml.PersistentNameIDFormat,
+		Attributes: map[string][]string{
+			"mail":        {"ada@acme.com"},
+			"displayName": {"Ada Lovelace"},
+			"department":  {"Engineering", "Research"},
+		},
+	}
+}
+
+func post(samlResponse string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, testACSURL, strings.NewReader(url.Values{"SAMLResponse": {samlResponse}}.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return request
+}
+
+func TestParseResponse(t *testing.T) {
+	idp := samlsptest.NewIdP(t)
+	connection := newTestConnection(t, idp, "")
+
+	identity, err := connection.ParseResponse(context.Background(), post(idp.Post(t, validResponse())), testRequestID)
+	if err != nil {
+		t.Fatalf("ParseResponse: %v", err)
+	}
+	if identity.Connection != "acme" || identity.Subject != "employee-42" || identity.Email != "ada@acme.com" || identity.Name != "Ada Lovelace" {
+		t.Fatalf("identity = %+v", identity)
+	}
+	if department := identity.Custom["department"]; len(department) != 2 || department[0] != "Engineering" {
+		t.Fatalf("department = %v", department)
+	}
+}
+
+func TestParseResponseRejects(t *testing.T) {
+	idp := samlsptest.NewIdP(t)
+	connection := newTestConnection(t, idp, "")
+
+	tamper := func(samlResponse string) string {
+		data, err := base64.StdEncoding.DecodeString(samlResponse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(string(data), "ada@acme.com", "eve@acme.com")))
+	}
+	expire := func(assertion *saml.Assertion) {
+		past := time.Now().Add(-time.Hour)
+		assertion.Conditions.NotOnOrAfter = past
+		assertion.Subject.SubjectConfirmations[0].SubjectConfirmationData.NotOnOrAfter = past
+	}
+
+	for _, tc := range []struct {
+		name      string
+		response  func(*samlsptest.Response)
+		tamper    func(string) string
+		requestID string
+	}{
+		{name: "tampered", tamper: tamper},
+		{name: "unsigned", response: func(r *samlsptest.Response) { r.Unsigned = true }},
+		{name: "wrong audience", response: func(r *samlsptest.Response) { r.SPEntityID = "https://other.example.com/metadata" }},
+		{name: "wrong recipient", response: func(r *samlsptest.Response) {
+			r.Assertion = func(a *saml.Assertion) {
+				a.Subject.SubjectConfirmations[0].SubjectConfirmationData.Recipient = "https://other.example.com/acs"
+			}
+		}},
+		{name: "expired", response: func(r *samlsptest.Response) { r.Assertion = expire }},
+		{name: "answers another request", requestID: "id-request-2"},
+		{name: "answers no request", response: func(r *samlsptest.Response) { r.RequestID = "" }},
+		{name: "transient NameID", response: func(r *samlsptest.Response) { r.NameIDFormat = saml.TransientNameIDFormat }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			response := validResponse()
+			if tc.response != nil {
+				tc.response(&response)
+			}
+			samlResponse := idp.Post(t, response)
+			if tc.tamper != nil {
+				samlResponse = tc.tamper(samlResponse)
+			}
+			requestID := testRequestID
+			if tc.requestID != "" {
+				requestID = tc.requestID
+			}
+
+			_, err := connection.ParseResponse(context.Background(), post(samlResponse), requestID)
+			if !errors.Is(err, ErrInvalidResponse) {
+				t.Fatalf("ParseResponse error = %v, want ErrInvalidResponse", err)
+			}
+		})
+	}
+}
+
+func TestAllowsEmail(t *testing.T) {
+	idp := samlsptest.NewIdP(t)
+	connection := newTestConnection(t, idp, `, "link_by_email": true, "allowed_domains": [" Acme.com", "@acme.co.uk"]`)
+
+	for email, want := range map[string]bool{
+		"ada@acme.com":         true,
+		"Ada@ACME.COM":         true,
+		"ada@acme.co.uk":       true,
+		"ada@evil.com":         false,
+		"ada@sub.acme.com":     false,
+		"ada@acme.com.evil":    false,
+		"acme.com":             false,
+		"ada@acme.com@evil.io": false,
+	} {
+		if got := connection.AllowsEmail(email); got != want {
+			t.Errorf("AllowsEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
+
+func TestParseRequiresAllowedDomains(t *testing.T) {
+	key, cert := samlsptest.KeyPair(t, "api.example.com")
+	base, _ := url.Parse("https://api.example.com/api/v1")
+	metadata := samlsptest.NewIdP(t).MetadataFile(t)
+
+	for _, option := range []string{"link_by_email", "provision"} {
+		_, err := Parse([]byte(fmt.Sprintf(`{"acme": {"idp_metadata_file": %q, %q: true}}`, metadata, option)),
+			&ServiceProvider{BaseURL: base, Key: key, Certificate: cert})
+		if err == nil || !strings.Contains(err.Error(), option+" requires allowed_domains") {
+			t.Errorf("%s: Parse error = %v, want allowed_domains to be required", option, err)
+		}
+
+		_, err = Parse([]byte(fmt.Sprintf(`{"acme": {"idp_metadata_file": %q, %q: true, "allowed_domains": ["acme.com"]}}`, metadata, option)),
+			&ServiceProvider{BaseURL: base, Key: key, Certificate: cert})
+		if err != nil {
+			t.Errorf("%s with allowed_domains: %v", option, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package samlsptest plays a SAML 2.0 identity provider in tests: it publishes metadata with a
+// locally generated signing certificate and issues signed responses to post to an Assertion
+// Consumer Service.
+package samlsptest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// IdP is a mock identity provider. The zero value is not usable; call NewIdP.
+type IdP struct {
+	Provider *saml.IdentityProvider
+}
+
+// NewIdP returns an identity provider with a fresh signing key.
+func NewIdP(t testing.TB) *IdP {
+	t.Helper()
+
+	key, cert := KeyPair(t, "idp.example.com")
+	return &IdP{Provider: &saml.IdentityProvider{
+		Key:             key,
+		Certificate:     cert,
+		MetadataURL:     url.URL{Scheme: "https", Host: "idp.example.com", Path: "/metadata"},
+		SSOURL:          url.URL{Scheme: "https", Host: "idp.example.com", Path: "/sso"},
+		SignatureMethod: dsig.RSASHA256SignatureMethod,
+	}}
+}
+
+// KeyPair generates an RSA key and a self-signed certificate for host.
+func KeyPair(t testing.TB, host string) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, cert
+}
+
+// MetadataFile writes the IdP metadata to a file removed when the test ends and returns its path.
+func (i *IdP) MetadataFile(t testing.TB) string {
+	t.Helper()
+
+	metadata, err := xml.Marshal(i.Provider.Metadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "idp-metadata.xml")
+	if err := os.WriteFile(path, metadata, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// Response describes the SAML response to issue.
+type Response struct {
+	// RequestID is the AuthnRequest answered. Empty leaves InResponseTo out.
+	RequestID string
+	// SPEntityID is the audience and ACSURL the recipient and destination.
+	SPEntityID string
+	ACSURL     string
+
+	NameID       string
+	NameIDFormat saml.NameIDFormat
+	// Attributes are asserted by name.
+	Attributes map[string][]string
+
+	// Assertion, when set, changes the assertion before it is signed.
+	Assertion func(*saml.Assertion)
+	// Unsigned leaves both the response and the assertion unsigned.
+	Unsigned bool
+}
+
+// Post returns the base64-encoded SAMLResponse form value of response.
+func (i *IdP) Post(t testing.TB, response Response) string {
+	t.Helper()
+
+	now := time.Now()
+	request := &saml.IdpAuthnRequest{
+		IDP:                     i.Provider,
+		HTTPRequest:             httptest.NewRequest("POST", response.ACSURL, nil),
+		Request:                 saml.AuthnRequest{ID: response.RequestID, IssueInstant: now},
+		ServiceProviderMetadata: &saml.EntityDescriptor{EntityID: response.SPEntityID},
+		//* Without encryption keys in the descriptor the assertion is sent in the clear
+		SPSSODescriptor: &saml.SPSSODescriptor{},
+		ACSEndpoint:     &saml.IndexedEndpoint{Binding: saml.HTTPPostBinding, Location: response.ACSURL},
+		Now:             now,
+	}
+
+	session := &saml.Session{
+		CreateTime:   now,
+		NameID:       response.NameID,
+		NameIDFormat: string(response.NameIDFormat),
+	}
+	for name, values := range response.Attributes {
+		attribute := saml.Attribute{Name: name, NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic"}
+		for _, value := range values {
+			attribute.Values = append(attribute.Values, saml.AttributeValue{Type: "xs:string", Value: value})
+		}
+		session.CustomAttributes = append(session.CustomAttributes, attribute)
+	}
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(request, session); err != nil {
+		t.Fatal(err)
+	}
+	if response.Assertion != nil {
+		response.Assertion(request.Assertion)
+	}
+
+	var responseEl *etree.Element
+	if response.Unsigned {
+		responseEl = (&saml.Response{
+			Destination:  response.ACSURL,
+			ID:           "id-unsigned",
+			InResponseTo: response.RequestID,
+			IssueInstant: now,
+			Version:      "2.0",
+			Issuer:       &saml.Issuer{Value: i.Provider.MetadataURL.String()},
+			Status:       saml.Status{StatusCode: saml.StatusCode{Value: saml.StatusSuccess}},
+		}).Element()
+		responseEl.AddChild(request.Assertion.Element())
+	} else {
+		if err := request.MakeResponse(); err != nil {
+			t.Fatal(err)
+		}
+		responseEl = request.ResponseEl
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(responseEl)
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(data)
+}